@@ -1,11 +1,15 @@
 package controller
 
 import (
+	"errors"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
 	"ticket-system-backend/model"
+	"ticket-system-backend/service"
 	"ticket-system-backend/util"
 
 	"github.com/gin-gonic/gin"
@@ -14,14 +18,10 @@ import (
 
 type AdminController struct{}
 
-func (ac *AdminController) Register(c *gin.Context) {
+func (ac *AdminController) Login(c *gin.Context) {
 	var req struct {
 		Username string `json:"username" binding:"required"`
 		Password string `json:"password" binding:"required"`
-		RealName string `json:"real_name" binding:"required"`
-		Email    string `json:"email"`
-		Phone    string `json:"phone"`
-		Role     string `json:"role"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -29,53 +29,23 @@ func (ac *AdminController) Register(c *gin.Context) {
 		return
 	}
 
-	existAdmin, _ := model.GetAdminByUsername(req.Username)
-	if existAdmin != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "用户名已存在"})
-		return
-	}
-
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "密码加密失败"})
-		return
-	}
-
-	role := req.Role
-	if role == "" {
-		role = "admin"
-	}
-
-	admin := &model.Admin{
-		Username: req.Username,
-		Password: string(hashedPassword),
-		RealName: req.RealName,
-		Email:    req.Email,
-		Phone:    req.Phone,
-		Role:     role,
-		Status:   1,
-	}
-
-	if err := model.CreateAdmin(admin); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "创建管理员失败"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "创建成功", "data": admin})
-}
-
-func (ac *AdminController) Login(c *gin.Context) {
-	var req struct {
-		Username string `json:"username" binding:"required"`
-		Password string `json:"password" binding:"required"`
+	admin, err := model.GetAdminByUsername(req.Username)
+	adminID, account := 0, ""
+	if err == nil {
+		adminID, account = admin.ID, strconv.Itoa(admin.ID)
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+	if err := util.CheckLoginAllowed(util.LoginScopeAdmin, account, c.ClientIP()); err != nil {
+		recordAdminLoginEvent(c, adminID, model.LoginEventLocked, err)
+		var throttle *util.LoginThrottleError
+		if errors.As(err, &throttle) {
+			respondAdminLoginThrottled(c, throttle)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "登录失败"})
 		return
 	}
 
-	admin, err := model.GetAdminByUsername(req.Username)
 	if err != nil {
 		history := &model.AdminLoginHistory{
 			AdminID:    0,
@@ -86,6 +56,10 @@ func (ac *AdminController) Login(c *gin.Context) {
 		}
 		model.CreateLoginHistory(history)
 
+		if throttle := recordAdminLoginFailure(c, account); throttle != nil && throttle.Locked {
+			respondAdminLoginThrottled(c, throttle)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户名或密码错误"})
 		return
 	}
@@ -100,6 +74,10 @@ func (ac *AdminController) Login(c *gin.Context) {
 		}
 		model.CreateLoginHistory(history)
 
+		if throttle := recordAdminLoginFailure(c, account); throttle != nil && throttle.Locked {
+			respondAdminLoginThrottled(c, throttle)
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户名或密码错误"})
 		return
 	}
@@ -108,21 +86,72 @@ func (ac *AdminController) Login(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "账号已被禁用"})
 		return
 	}
-
-	model.UpdateAdminLoginInfo(admin.ID, c.ClientIP())
+	util.ClearLoginFailures(util.LoginScopeAdmin, account)
 
 	history := &model.AdminLoginHistory{
 		AdminID:   admin.ID,
 		IP:        c.ClientIP(),
 		UserAgent: c.Request.UserAgent(),
 		Status:    1,
+		Event:     model.LoginEventPassword,
 	}
 	model.CreateLoginHistory(history)
 
+	// 启用了两步验证（或系统强制启用）时，密码正确只换取临时令牌，动态码校验通过后才签发管理员令牌
+	authService := service.NewAdminAuthService()
+	if stage := authService.LoginStage(admin); stage != service.AdminLoginStageDone {
+		twoFactorToken, err := authService.IssueTwoFactorToken(admin, stage)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成token失败"})
+			return
+		}
+		message := "请输入两步验证码"
+		if stage == service.AdminLoginStageTwoFactorSetup {
+			message = "系统要求启用两步验证，请先绑定验证器"
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": message,
+			"data": gin.H{
+				"two_factor_required":       stage == service.AdminLoginStageTwoFactorVerify,
+				"two_factor_setup_required": stage == service.AdminLoginStageTwoFactorSetup,
+				"two_factor_token":          twoFactorToken,
+				"expires_in":                int(util.TwoFactorTokenExpiration.Seconds()),
+			},
+		})
+		return
+	}
+
+	data, ok := ac.issueLoginToken(c, admin, "password")
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "登录成功", "data": data})
+}
+
+// recordAdminLoginFailure 累计登录失败次数，返回本次失败触发的限制
+func recordAdminLoginFailure(c *gin.Context, account string) *util.LoginThrottleError {
+	throttle, err := util.RecordLoginFailure(util.LoginScopeAdmin, account, c.ClientIP())
+	if err != nil {
+		log.Printf("记录登录失败次数出错: account=%s err=%v", account, err)
+	}
+	return throttle
+}
+
+// respondAdminLoginThrottled 登录被限制时返回 429，并通过 Retry-After 告知可重试的时间
+func respondAdminLoginThrottled(c *gin.Context, throttle *util.LoginThrottleError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttle.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"code": 429, "message": throttle.Error()})
+}
+
+// issueLoginToken 登录的最后一步：签发管理员令牌并记录登录日志，method 为完成登录的验证方式
+func (ac *AdminController) issueLoginToken(c *gin.Context, admin *model.Admin, method string) (gin.H, bool) {
+	model.UpdateAdminLoginInfo(admin.ID, c.ClientIP())
+
 	token, err := util.GenerateAdminToken(admin.ID, admin.Username, admin.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成token失败"})
-		return
+		return nil, false
 	}
 
 	log := &model.AdminLog{
@@ -130,52 +159,116 @@ func (ac *AdminController) Login(c *gin.Context) {
 		Action:     "login",
 		TargetType: "admin",
 		TargetID:   admin.ID,
-		Detail:     `{"username":"` + admin.Username + `"}`,
+		Detail:     `{"username":"` + admin.Username + `","method":"` + method + `"}`,
 		IP:         c.ClientIP(),
 		UserAgent:  c.Request.UserAgent(),
 	}
 	model.CreateAdminLog(log)
 
-	c.JSON(http.StatusOK, gin.H{
-		"code":    200,
-		"message": "登录成功",
-		"data": gin.H{
-			"token": token,
-			"admin": gin.H{
-				"id":        admin.ID,
-				"username":  admin.Username,
-				"real_name": admin.RealName,
-				"role":      admin.Role,
-				"email":     admin.Email,
-			},
+	return gin.H{
+		"token": token,
+		"admin": gin.H{
+			"id":           admin.ID,
+			"username":     admin.Username,
+			"real_name":    admin.RealName,
+			"role":         admin.Role,
+			"email":        admin.Email,
+			"totp_enabled": admin.TOTPEnabled == 1,
+			"permissions":  adminPermissions(admin.ID),
 		},
-	})
+	}, true
 }
 
 func (ac *AdminController) GetAdminInfo(c *gin.Context) {
 	adminID, _ := c.Get("admin_id")
+	targetID := adminID.(int)
+	// /admins/info 为当前管理员，/admins/:id 为指定管理员
+	if id, err := strconv.Atoi(c.Param("id")); err == nil {
+		targetID = id
+	}
 
-	admin, err := model.GetAdminByID(adminID.(int))
+	admin, err := model.GetAdminByID(targetID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "管理员不存在"})
 		return
 	}
 
+	loginLock, _ := util.GetLoginLockStatus(util.LoginScopeAdmin, strconv.Itoa(admin.ID))
+	loginHistory, _ := model.GetAdminLoginHistory(admin.ID, 10)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"data": gin.H{
-			"id":         admin.ID,
-			"username":   admin.Username,
-			"real_name":  admin.RealName,
-			"email":      admin.Email,
-			"phone":      admin.Phone,
-			"role":       admin.Role,
-			"status":     admin.Status,
-			"created_at": admin.CreatedAt,
+			"id":            admin.ID,
+			"username":      admin.Username,
+			"real_name":     admin.RealName,
+			"email":         admin.Email,
+			"phone":         admin.Phone,
+			"role":          admin.Role,
+			"status":        admin.Status,
+			"created_at":    admin.CreatedAt,
+			"permissions":   adminPermissions(admin.ID),
+			"login_lock":    loginLock,
+			"login_history": loginHistory,
 		},
 	})
 }
 
+// adminPermissions 管理员的有效权限，供前端控制菜单和按钮
+func adminPermissions(adminID int) []string {
+	permissions, err := service.NewAdminRoleService().AdminPermissions(adminID)
+	if err != nil {
+		log.Printf("获取管理员权限失败: admin=%d err=%v", adminID, err)
+		return []string{}
+	}
+	return permissions
+}
+
+func respondAdminChangeError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrRoleNotFound, service.ErrLastSuperAdmin, service.ErrAdminChangeSelf:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case service.ErrSuperAdminRequired, service.ErrRolePermissionExceeded:
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "校验角色失败"})
+	}
+}
+
+// UnlockAdmin 超级管理员解除管理员的登录锁定
+func (ac *AdminController) UnlockAdmin(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	target, err := model.GetAdminByID(targetID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "管理员不存在"})
+		return
+	}
+
+	if err := util.UnlockLogin(util.LoginScopeAdmin, strconv.Itoa(target.ID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "解除锁定失败"})
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "unlock_admin",
+		TargetType: "admin",
+		TargetID:   target.ID,
+		Detail:     `{"username":"` + target.Username + `"}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "已解除登录锁定"})
+}
+
 func (ac *AdminController) GetAdminList(c *gin.Context) {
 	keyword := c.Query("keyword")
 	role := c.Query("role")
@@ -232,7 +325,7 @@ func (ac *AdminController) UpdateAdmin(c *gin.Context) {
 		Email    string `json:"email"`
 		Phone    string `json:"phone"`
 		Role     string `json:"role"`
-		Status   int    `json:"status"`
+		Status   *int   `json:"status"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -246,6 +339,25 @@ func (ac *AdminController) UpdateAdmin(c *gin.Context) {
 		return
 	}
 
+	operator, ok := currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	role, status := admin.Role, admin.Status
+	if req.Role != "" {
+		role = req.Role
+	}
+	if req.Status != nil && (*req.Status == 0 || *req.Status == 1) {
+		status = *req.Status
+	}
+	if role != admin.Role || status != admin.Status {
+		if err := service.NewAdminRoleService().CheckAdminChange(operator, admin, role, status); err != nil {
+			respondAdminChangeError(c, err)
+			return
+		}
+	}
+
 	if req.RealName != "" {
 		admin.RealName = req.RealName
 	}
@@ -255,24 +367,24 @@ func (ac *AdminController) UpdateAdmin(c *gin.Context) {
 	if req.Phone != "" {
 		admin.Phone = req.Phone
 	}
-	if req.Role != "" {
-		admin.Role = req.Role
-	}
-	if req.Status >= 0 {
-		admin.Status = req.Status
-	}
 
 	if err := model.UpdateAdmin(admin); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新失败"})
 		return
 	}
+	// 状态为 0 时 Updates 会忽略，角色和状态单独写入
+	if err := model.UpdateAdminRoleStatus(admin.ID, role, status); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新失败"})
+		return
+	}
+	util.InvalidateAdminPermissions(admin.ID)
 
 	log := &model.AdminLog{
-		AdminID:    admin.ID,
+		AdminID:    operator.ID,
 		Action:     "update_admin",
 		TargetType: "admin",
 		TargetID:   admin.ID,
-		Detail:     `{"updated_id":` + idStr + `}`,
+		Detail:     `{"updated_id":` + idStr + `,"role":"` + role + `","status":` + strconv.Itoa(status) + `}`,
 		IP:         c.ClientIP(),
 		UserAgent:  c.Request.UserAgent(),
 	}
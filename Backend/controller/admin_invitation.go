@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/service"
+	"ticket-system-backend/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminInvitationController struct{}
+
+func adminInvitationData(invitation *model.AdminInvitation) gin.H {
+	return gin.H{
+		"id":          invitation.ID,
+		"email":       invitation.Email,
+		"real_name":   invitation.RealName,
+		"role":        invitation.Role,
+		"status":      invitation.StatusName(),
+		"invited_by":  invitation.InvitedBy,
+		"admin_id":    invitation.AdminID,
+		"expires_at":  invitation.ExpiresAt,
+		"accepted_at": invitation.AcceptedAt,
+		"revoked_by":  invitation.RevokedBy,
+		"revoked_at":  invitation.RevokedAt,
+		"created_at":  invitation.CreatedAt,
+	}
+}
+
+// GetInvitations 邀请列表，status 可为 0 待接受、1 已接受、2 已撤销
+func (ic *AdminInvitationController) GetInvitations(c *gin.Context) {
+	query := model.AdminInvitationQuery{Status: -1, Page: 1, Size: 10}
+	if statusStr := c.Query("status"); statusStr != "" {
+		status, _ := strconv.Atoi(statusStr)
+		query.Status = status
+	}
+	if pageStr := c.Query("page"); pageStr != "" {
+		query.Page, _ = strconv.Atoi(pageStr)
+	}
+	if sizeStr := c.Query("size"); sizeStr != "" {
+		query.Size, _ = strconv.Atoi(sizeStr)
+	}
+
+	invitations, total, err := service.NewAdminInvitationService().List(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取邀请列表失败"})
+		return
+	}
+
+	list := make([]gin.H, 0, len(invitations))
+	for _, invitation := range invitations {
+		list = append(list, adminInvitationData(invitation))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"list":  list,
+			"total": total,
+			"page":  query.Page,
+			"size":  query.Size,
+		},
+	})
+}
+
+// CreateInvitation 邀请管理员，向受邀邮箱发送设置账号的链接
+func (ic *AdminInvitationController) CreateInvitation(c *gin.Context) {
+	var req struct {
+		Email    string `json:"email" binding:"required,email,max=100"`
+		RealName string `json:"real_name" binding:"max=50"`
+		Role     string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	inviter, ok := currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	invitation, err := service.NewAdminInvitationService().Invite(inviter, req.Email, req.RealName, req.Role)
+	if err != nil {
+		respondAdminInvitationError(c, err, "邀请管理员失败")
+		return
+	}
+
+	writeAdminInvitationLog(c, inviter.ID, "invite_admin", invitation, nil)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "邀请已发送", "data": adminInvitationData(invitation)})
+}
+
+// RevokeInvitation 撤销待接受的邀请
+func (ic *AdminInvitationController) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	invitation, err := service.NewAdminInvitationService().Revoke(id, adminID.(int))
+	if err != nil {
+		respondAdminInvitationError(c, err, "撤销邀请失败")
+		return
+	}
+
+	writeAdminInvitationLog(c, adminID.(int), "revoke_admin_invitation", invitation, nil)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "邀请已撤销"})
+}
+
+// GetInvitation 受邀人打开邀请链接时查看邀请信息，无需登录
+func (ic *AdminInvitationController) GetInvitation(c *gin.Context) {
+	invitation, err := service.NewAdminInvitationService().Lookup(c.Query("token"))
+	if err != nil {
+		respondAdminInvitationError(c, err, "获取邀请失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"email":      invitation.Email,
+			"real_name":  invitation.RealName,
+			"role":       invitation.Role,
+			"expires_at": invitation.ExpiresAt,
+		},
+	})
+}
+
+// AcceptInvitation 受邀人设置用户名和密码，创建管理员账号。之后通过管理员登录接口登录
+func (ic *AdminInvitationController) AcceptInvitation(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Username string `json:"username" binding:"required,min=3,max=50"`
+		Password string `json:"password" binding:"required,min=8,max=20"`
+		RealName string `json:"real_name" binding:"max=50"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	if valid, msg := util.ValidatePasswordStrength(req.Password); !valid {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": msg})
+		return
+	}
+
+	admin, invitation, err := service.NewAdminInvitationService().Accept(req.Token, service.AdminInvitationAccept{
+		Username: req.Username,
+		Password: req.Password,
+		RealName: req.RealName,
+	})
+	if err != nil {
+		respondAdminInvitationError(c, err, "接受邀请失败")
+		return
+	}
+
+	writeAdminInvitationLog(c, admin.ID, "accept_admin_invitation", invitation, gin.H{"username": admin.Username, "invited_by": invitation.InvitedBy})
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "账号已创建，请登录",
+		"data": gin.H{
+			"id":        admin.ID,
+			"username":  admin.Username,
+			"real_name": admin.RealName,
+			"email":     admin.Email,
+			"role":      admin.Role,
+		},
+	})
+}
+
+// writeAdminInvitationLog 记录邀请的每一步操作，详情包含受邀邮箱和角色
+func writeAdminInvitationLog(c *gin.Context, adminID int, action string, invitation *model.AdminInvitation, extra gin.H) {
+	detail := gin.H{"email": invitation.Email, "role": invitation.Role}
+	for key, value := range extra {
+		detail[key] = value
+	}
+	data, _ := json.Marshal(detail)
+
+	log := &model.AdminLog{
+		AdminID:    adminID,
+		Action:     action,
+		TargetType: "admin_invitation",
+		TargetID:   invitation.ID,
+		Detail:     string(data),
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+}
+
+func respondAdminInvitationError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrInvitationNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	case util.ErrAdminInviteTokenInvalid, service.ErrInvitationNotPending, service.ErrInvitationPending, service.ErrAdminEmailExists, service.ErrAdminUsernameExists:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case service.ErrRoleNotFound, service.ErrSuperAdminRequired, service.ErrRolePermissionExceeded:
+		respondAdminChangeError(c, err)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": message})
+	}
+}
@@ -1,11 +1,16 @@
 package controller
 
 import (
+	"context"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"ticket-system-backend/model"
+	"ticket-system-backend/service"
+	"ticket-system-backend/util"
 
 	"github.com/gin-gonic/gin"
 )
@@ -74,15 +79,51 @@ func (uc *AdminUserController) GetUserDetail(c *gin.Context) {
 		orders = []*model.Order{}
 	}
 
+	loginLock, _ := util.GetLoginLockStatus(util.LoginScopeUser, strconv.Itoa(user.ID))
+	loginHistory, _ := model.GetUserLoginHistory(user.ID, 10)
+
 	c.JSON(http.StatusOK, gin.H{
 		"code": 200,
 		"data": gin.H{
-			"user":   user,
-			"orders": orders,
+			"user":          user,
+			"orders":        orders,
+			"login_lock":    loginLock,
+			"login_history": loginHistory,
 		},
 	})
 }
 
+// UnlockUser 解除用户因登录失败过多导致的锁定
+func (uc *AdminUserController) UnlockUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	user, err := model.GetUserByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "用户不存在"})
+		return
+	}
+
+	if err := util.UnlockLogin(util.LoginScopeUser, strconv.Itoa(user.ID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "解除锁定失败"})
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "unlock_user",
+		TargetType: "user",
+		TargetID:   id,
+		Detail:     `{"username":"` + user.Username + `"}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "已解除登录锁定"})
+}
+
 func (uc *AdminUserController) UpdateUserStatus(c *gin.Context) {
 	idStr := c.Param("id")
 	id, _ := strconv.Atoi(idStr)
@@ -91,7 +132,7 @@ func (uc *AdminUserController) UpdateUserStatus(c *gin.Context) {
 		Status int `json:"status"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Status != 1 && req.Status != 2) {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
 		return
 	}
@@ -101,6 +142,10 @@ func (uc *AdminUserController) UpdateUserStatus(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "用户不存在"})
 		return
 	}
+	if user.Status == model.UserStatusDeleted {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "已注销的账号不能修改状态"})
+		return
+	}
 
 	user.Status = req.Status
 	if err := model.UpdateUser(user); err != nil {
@@ -108,6 +153,14 @@ func (uc *AdminUserController) UpdateUserStatus(c *gin.Context) {
 		return
 	}
 
+	// 禁用账号后立即注销其全部登录会话
+	if user.Status == 2 {
+		if err := service.NewAuthService().RevokeUserSessions(id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "注销用户会话失败"})
+			return
+		}
+	}
+
 	adminID, _ := c.Get("admin_id")
 	log := &model.AdminLog{
 		AdminID:    adminID.(int),
@@ -133,7 +186,11 @@ func (uc *AdminUserController) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	hasActiveOrders := model.HasActiveOrders(id)
+	hasActiveOrders, err := model.HasActiveOrders(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除失败"})
+		return
+	}
 	if hasActiveOrders {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "该用户存在未完成的订单，无法删除"})
 		return
@@ -143,6 +200,7 @@ func (uc *AdminUserController) DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除失败"})
 		return
 	}
+	service.NewAuthService().RevokeUserSessions(id)
 
 	adminID, _ := c.Get("admin_id")
 	log := &model.AdminLog{
@@ -262,10 +320,16 @@ func (oc *AdminOrderController) ProcessRefund(c *gin.Context) {
 		return
 	}
 
-	if err := model.RefundOrder(id); err != nil {
+	ok, err := service.CloseOrder(order, 1, 2)
+	if err != nil {
+		log.Printf("退款订单失败: order=%d, err=%v", order.ID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "退款处理失败"})
 		return
 	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "只能退款已支付的订单"})
+		return
+	}
 
 	adminID, _ := c.Get("admin_id")
 	log := &model.AdminLog{
@@ -273,13 +337,16 @@ func (oc *AdminOrderController) ProcessRefund(c *gin.Context) {
 		Action:     "order_refund",
 		TargetType: "order",
 		TargetID:   id,
-		Detail:     `{"order_no":"` + order.OrderNo + `","reason":"` + req.Reason + `"}`,
+		Detail:     `{"order_no":"` + order.OrderNo + `","amount":` + order.Amount.String() + `,"reason":"` + req.Reason + `"}`,
 		IP:         c.ClientIP(),
 		UserAgent:  c.Request.UserAgent(),
 	}
 	model.CreateAdminLog(log)
 
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "退款处理成功"})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "退款处理成功", "data": gin.H{
+		"refund_amount": order.Amount,
+		"currency":      order.Currency,
+	}})
 }
 
 func (oc *AdminOrderController) ExportOrders(c *gin.Context) {
@@ -324,6 +391,30 @@ func (oc *AdminOrderController) ExportOrders(c *gin.Context) {
 	})
 }
 
+func (oc *AdminOrderController) DownloadTickets(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	order, err := model.GetOrderByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "订单不存在"})
+		return
+	}
+
+	data, err := service.NewTicketService().RenderOrderTicketsPDF(order)
+	if err != nil {
+		if err == service.ErrNoPrintableTickets {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成电子票失败"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="tickets_`+order.OrderNo+`.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", data)
+}
+
 type AdminPerformanceController struct{}
 
 func (pc *AdminPerformanceController) GetPerformanceList(c *gin.Context) {
@@ -407,15 +498,16 @@ func (pc *AdminPerformanceController) GetPerformanceDetail(c *gin.Context) {
 
 func (pc *AdminPerformanceController) CreatePerformance(c *gin.Context) {
 	var req struct {
-		Title       string `json:"title" binding:"required"`
-		CategoryID  int    `json:"category_id" binding:"required"`
-		CoverImage  string `json:"cover_image"`
-		Description string `json:"description"`
-		Performer   string `json:"performer" binding:"required"`
-		Venue       string `json:"venue" binding:"required"`
-		StartTime   string `json:"start_time" binding:"required"`
-		EndTime     string `json:"end_time" binding:"required"`
-		Status      int    `json:"status"`
+		Title           string `json:"title" binding:"required"`
+		CategoryID      int    `json:"category_id" binding:"required"`
+		CoverImage      string `json:"cover_image"`
+		Description     string `json:"description"`
+		Performer       string `json:"performer" binding:"required"`
+		Venue           string `json:"venue" binding:"required"`
+		StartTime       string `json:"start_time" binding:"required"`
+		EndTime         string `json:"end_time" binding:"required"`
+		Status          int    `json:"status"`
+		RequireRealName int    `json:"require_real_name"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -432,15 +524,16 @@ func (pc *AdminPerformanceController) CreatePerformance(c *gin.Context) {
 	}
 
 	performance := &model.Performance{
-		Title:       req.Title,
-		CategoryID:  req.CategoryID,
-		CoverImage:  req.CoverImage,
-		Description: req.Description,
-		Performer:   req.Performer,
-		Venue:       req.Venue,
-		StartTime:   startTime,
-		EndTime:     endTime,
-		Status:      status,
+		Title:           req.Title,
+		CategoryID:      req.CategoryID,
+		CoverImage:      req.CoverImage,
+		Description:     req.Description,
+		Performer:       req.Performer,
+		Venue:           req.Venue,
+		StartTime:       startTime,
+		EndTime:         endTime,
+		Status:          status,
+		RequireRealName: req.RequireRealName,
 	}
 
 	if err := model.CreatePerformance(performance); err != nil {
@@ -468,15 +561,16 @@ func (pc *AdminPerformanceController) UpdatePerformance(c *gin.Context) {
 	id, _ := strconv.Atoi(idStr)
 
 	var req struct {
-		Title       string `json:"title"`
-		CategoryID  int    `json:"category_id"`
-		CoverImage  string `json:"cover_image"`
-		Description string `json:"description"`
-		Performer   string `json:"performer"`
-		Venue       string `json:"venue"`
-		StartTime   string `json:"start_time"`
-		EndTime     string `json:"end_time"`
-		Status      int    `json:"status"`
+		Title           string `json:"title"`
+		CategoryID      int    `json:"category_id"`
+		CoverImage      string `json:"cover_image"`
+		Description     string `json:"description"`
+		Performer       string `json:"performer"`
+		Venue           string `json:"venue"`
+		StartTime       string `json:"start_time"`
+		EndTime         string `json:"end_time"`
+		Status          int    `json:"status"`
+		RequireRealName *int   `json:"require_real_name"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -525,6 +619,13 @@ func (pc *AdminPerformanceController) UpdatePerformance(c *gin.Context) {
 		return
 	}
 
+	if req.RequireRealName != nil {
+		if err := model.SetPerformanceRealName(id, *req.RequireRealName); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新失败"})
+			return
+		}
+	}
+
 	adminID, _ := c.Get("admin_id")
 	log := &model.AdminLog{
 		AdminID:    adminID.(int),
@@ -604,13 +705,17 @@ func (ttc *AdminTicketTypeController) GetTicketTypeList(c *gin.Context) {
 
 func (ttc *AdminTicketTypeController) CreateTicketType(c *gin.Context) {
 	var req struct {
-		PerformanceID int     `json:"performance_id" binding:"required"`
-		Name          string  `json:"name" binding:"required"`
-		Price         float64 `json:"price" binding:"required"`
-		Stock         int     `json:"stock" binding:"required"`
-		SaleStartTime string  `json:"sale_start_time" binding:"required"`
-		SaleEndTime   string  `json:"sale_end_time" binding:"required"`
-		Status        int     `json:"status"`
+		PerformanceID   int        `json:"performance_id" binding:"required"`
+		Name            string     `json:"name" binding:"required"`
+		Price           util.Money `json:"price" binding:"required"`
+		Stock           int        `json:"stock" binding:"required"`
+		SaleStartTime   string     `json:"sale_start_time" binding:"required"`
+		SaleEndTime     string     `json:"sale_end_time" binding:"required"`
+		Status          int        `json:"status"`
+		SaleMode        int        `json:"sale_mode"`
+		Hidden          int        `json:"hidden"`
+		BallotStartTime string     `json:"ballot_start_time"`
+		BallotEndTime   string     `json:"ballot_end_time"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -629,6 +734,19 @@ func (ttc *AdminTicketTypeController) CreateTicketType(c *gin.Context) {
 		SaleStartTime: saleStartTime,
 		SaleEndTime:   saleEndTime,
 		Status:        req.Status,
+		SaleMode:      req.SaleMode,
+		Hidden:        req.Hidden,
+	}
+
+	if req.SaleMode == model.SaleModeBallot {
+		ballotStartTime, err1 := time.Parse("2006-01-02 15:04:05", req.BallotStartTime)
+		ballotEndTime, err2 := time.Parse("2006-01-02 15:04:05", req.BallotEndTime)
+		if err1 != nil || err2 != nil || !ballotEndTime.After(ballotStartTime) {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "抽签登记时间错误"})
+			return
+		}
+		ticketType.BallotStartTime = &ballotStartTime
+		ticketType.BallotEndTime = &ballotEndTime
 	}
 
 	if err := model.CreateTicketType(ticketType); err != nil {
@@ -656,12 +774,16 @@ func (ttc *AdminTicketTypeController) UpdateTicketType(c *gin.Context) {
 	id, _ := strconv.Atoi(idStr)
 
 	var req struct {
-		Name          string  `json:"name"`
-		Price         float64 `json:"price"`
-		Stock         int     `json:"stock"`
-		SaleStartTime string  `json:"sale_start_time"`
-		SaleEndTime   string  `json:"sale_end_time"`
-		Status        int     `json:"status"`
+		Name            string     `json:"name"`
+		Price           util.Money `json:"price"`
+		Stock           int        `json:"stock"`
+		SaleStartTime   string     `json:"sale_start_time"`
+		SaleEndTime     string     `json:"sale_end_time"`
+		Status          int        `json:"status"`
+		SaleMode        *int       `json:"sale_mode"`
+		Hidden          *int       `json:"hidden"`
+		BallotStartTime string     `json:"ballot_start_time"`
+		BallotEndTime   string     `json:"ballot_end_time"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -695,6 +817,20 @@ func (ttc *AdminTicketTypeController) UpdateTicketType(c *gin.Context) {
 	if req.Status >= 0 {
 		ticketType.Status = req.Status
 	}
+	if req.SaleMode != nil {
+		ticketType.SaleMode = *req.SaleMode
+	}
+	if req.Hidden != nil {
+		ticketType.Hidden = *req.Hidden
+	}
+	if req.BallotStartTime != "" {
+		ballotStartTime, _ := time.Parse("2006-01-02 15:04:05", req.BallotStartTime)
+		ticketType.BallotStartTime = &ballotStartTime
+	}
+	if req.BallotEndTime != "" {
+		ballotEndTime, _ := time.Parse("2006-01-02 15:04:05", req.BallotEndTime)
+		ticketType.BallotEndTime = &ballotEndTime
+	}
 
 	if err := model.UpdateTicketType(ticketType); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新失败"})
@@ -797,61 +933,127 @@ func (ttc *AdminTicketTypeController) UpdateTicketStock(c *gin.Context) {
 	model.CreateAdminLog(log)
 
 	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更新库存成功", "data": gin.H{
-		"id":         ticketType.ID,
+		"id":        ticketType.ID,
 		"name":      ticketType.Name,
 		"old_stock": oldStock,
 		"new_stock": req.Stock,
 	}})
 }
 
-type AdminCategoryController struct{}
+func (ttc *AdminTicketTypeController) GetBallot(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
 
-func (cc *AdminCategoryController) GetCategoryList(c *gin.Context) {
-	categories, err := model.GetAllCategories()
+	summary, err := service.NewBallotService().GetSummary(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取分类列表失败"})
+		respondAdminBallotError(c, err, "获取抽签信息失败")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"code": 200,
-		"data": categories,
-	})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": summary})
 }
 
-func (cc *AdminCategoryController) CreateCategory(c *gin.Context) {
+func (ttc *AdminTicketTypeController) DrawBallot(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
 	var req struct {
-		Name     string `json:"name" binding:"required"`
-		ParentID int    `json:"parent_id"`
-		Sort     int    `json:"sort"`
+		Seed string `json:"seed"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+	c.ShouldBindJSON(&req)
+
+	draw, err := service.NewBallotService().Draw(id, req.Seed)
+	if err != nil {
+		respondAdminBallotError(c, err, "抽签失败")
 		return
 	}
 
-	category := &model.Category{
-		Name:     req.Name,
-		ParentID: req.ParentID,
-		Sort:     req.Sort,
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "ballot_draw",
+		TargetType: "ticket_type",
+		TargetID:   id,
+		Detail:     `{"round":` + strconv.Itoa(draw.Round) + `,"seed":"` + draw.Seed + `","winners":` + strconv.Itoa(draw.WinnerCount) + `}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
 	}
+	model.CreateAdminLog(log)
 
-	if err := model.CreateCategory(category); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "创建失败"})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "抽签完成", "data": draw})
+}
+
+func (ttc *AdminTicketTypeController) PublishBallot(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	var req struct {
+		PaymentHours int `json:"payment_hours"`
+	}
+
+	c.ShouldBindJSON(&req)
+
+	result, err := service.NewBallotService().Publish(context.Background(), id, req.PaymentHours)
+	if err != nil {
+		respondAdminBallotError(c, err, "公布抽签结果失败")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "创建成功", "data": category})
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "ballot_publish",
+		TargetType: "ticket_type",
+		TargetID:   id,
+		Detail:     `{"round":` + strconv.Itoa(result.Round) + `,"orders":` + strconv.Itoa(result.Orders) + `,"failed":` + strconv.Itoa(result.Failed) + `}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "抽签结果已公布", "data": result})
 }
 
-func (cc *AdminCategoryController) UpdateCategory(c *gin.Context) {
+func (ttc *AdminTicketTypeController) GetAccessCodes(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if size < 1 {
+		size = 20
+	}
+
+	codes, total, err := model.GetAccessCodes(model.AccessCodeQuery{
+		TicketTypeID: id,
+		BatchNo:      c.Query("batch_no"),
+		Page:         page,
+		Size:         size,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取兑换码列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"list":  codes,
+			"total": total,
+			"page":  page,
+			"size":  size,
+		},
+	})
+}
+
+func (ttc *AdminTicketTypeController) GenerateAccessCodes(c *gin.Context) {
 	idStr := c.Param("id")
 	id, _ := strconv.Atoi(idStr)
 
 	var req struct {
-		Name string `json:"name"`
-		Sort int    `json:"sort"`
+		Count      int    `json:"count" binding:"required"`
+		MaxUses    int    `json:"max_uses"`
+		ExpireTime string `json:"expire_time"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -859,104 +1061,1001 @@ func (cc *AdminCategoryController) UpdateCategory(c *gin.Context) {
 		return
 	}
 
-	category, err := model.GetCategoryByID(id)
+	var expireTime *time.Time
+	if req.ExpireTime != "" {
+		t, err := time.Parse("2006-01-02 15:04:05", req.ExpireTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "过期时间格式错误"})
+			return
+		}
+		expireTime = &t
+	}
+
+	adminID, _ := c.Get("admin_id")
+	batchNo, codes, err := service.NewAccessCodeService().Generate(adminID.(int), id, req.Count, req.MaxUses, expireTime)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "分类不存在"})
+		switch err {
+		case service.ErrTicketTypeNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "票种不存在"})
+		case service.ErrTicketTypeNotHidden, service.ErrAccessCodeCount:
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成兑换码失败"})
+		}
 		return
 	}
 
-	if req.Name != "" {
-		category.Name = req.Name
-	}
-	if req.Sort >= 0 {
-		category.Sort = req.Sort
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "generate_access_codes",
+		TargetType: "ticket_type",
+		TargetID:   id,
+		Detail:     `{"batch_no":"` + batchNo + `","count":` + strconv.Itoa(len(codes)) + `}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
 	}
+	model.CreateAdminLog(log)
 
-	if err := model.UpdateCategory(category); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新失败"})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "生成成功", "data": gin.H{
+		"batch_no": batchNo,
+		"codes":    codes,
+	}})
+}
+
+func (ttc *AdminTicketTypeController) ExportAccessCodes(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+	batchNo := c.Query("batch_no")
+
+	data, err := service.NewAccessCodeService().ExportCSV(id, batchNo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "导出失败"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更新成功"})
+	filename := "access_codes_" + idStr
+	if batchNo != "" {
+		filename += "_" + batchNo
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
 }
 
-func (cc *AdminCategoryController) DeleteCategory(c *gin.Context) {
+type priceRuleRequest struct {
+	Name          string     `json:"name" binding:"required"`
+	Price         util.Money `json:"price"`
+	StartTime     string     `json:"start_time"`
+	EndTime       string     `json:"end_time"`
+	SoldThreshold int        `json:"sold_threshold"`
+	Priority      int        `json:"priority"`
+	Status        *int       `json:"status"`
+}
+
+func (r *priceRuleRequest) input() (service.PriceRuleInput, bool) {
+	input := service.PriceRuleInput{
+		Name:          r.Name,
+		Price:         r.Price,
+		SoldThreshold: r.SoldThreshold,
+		Priority:      r.Priority,
+		Status:        1,
+	}
+	if r.Status != nil {
+		input.Status = *r.Status
+	}
+	if r.StartTime != "" {
+		t, err := time.Parse("2006-01-02 15:04:05", r.StartTime)
+		if err != nil {
+			return input, false
+		}
+		input.StartTime = &t
+	}
+	if r.EndTime != "" {
+		t, err := time.Parse("2006-01-02 15:04:05", r.EndTime)
+		if err != nil {
+			return input, false
+		}
+		input.EndTime = &t
+	}
+	return input, true
+}
+
+func (ttc *AdminTicketTypeController) GetPriceRules(c *gin.Context) {
 	idStr := c.Param("id")
 	id, _ := strconv.Atoi(idStr)
 
-	category, err := model.GetCategoryByID(id)
+	rules, err := service.NewPricingService().GetRules(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "分类不存在"})
+		respondAdminPriceRuleError(c, err, "获取价格规则失败")
 		return
 	}
 
-	hasPerformances := model.HasCategoryPerformances(id)
-	if hasPerformances {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "该分类下存在演出，无法删除"})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": rules})
+}
+
+func (ttc *AdminTicketTypeController) CreatePriceRule(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	var req priceRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
 		return
 	}
 
-	if err := model.DeleteCategory(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除失败"})
+	input, ok := req.input()
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "时间格式错误"})
+		return
+	}
+
+	rule, err := service.NewPricingService().CreateRule(id, input)
+	if err != nil {
+		respondAdminPriceRuleError(c, err, "创建失败")
 		return
 	}
 
 	adminID, _ := c.Get("admin_id")
 	log := &model.AdminLog{
 		AdminID:    adminID.(int),
-		Action:     "delete_category",
-		TargetType: "category",
+		Action:     "create_price_rule",
+		TargetType: "ticket_type",
 		TargetID:   id,
-		Detail:     `{"name":"` + category.Name + `"}`,
+		Detail:     `{"rule_id":` + strconv.Itoa(rule.ID) + `,"price":` + rule.Price.String() + `}`,
 		IP:         c.ClientIP(),
 		UserAgent:  c.Request.UserAgent(),
 	}
 	model.CreateAdminLog(log)
 
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "删除成功"})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "创建成功", "data": rule})
 }
 
-type AdminSystemController struct{}
+type AdminPriceRuleController struct{}
 
-func (sc *AdminSystemController) GetConfig(c *gin.Context) {
-	configs, err := model.GetAllSystemConfigs()
+func (prc *AdminPriceRuleController) UpdatePriceRule(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	var req priceRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	input, ok := req.input()
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "时间格式错误"})
+		return
+	}
+
+	rule, err := service.NewPricingService().UpdateRule(id, input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取配置失败"})
+		respondAdminPriceRuleError(c, err, "更新失败")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"code": 200,
-		"data": configs,
-	})
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "update_price_rule",
+		TargetType: "price_rule",
+		TargetID:   id,
+		Detail:     `{"price":` + rule.Price.String() + `,"status":` + strconv.Itoa(rule.Status) + `}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更新成功", "data": rule})
 }
 
-func (sc *AdminSystemController) UpdateConfig(c *gin.Context) {
-	var req struct {
-		Key   string `json:"key" binding:"required"`
-		Value string `json:"value" binding:"required"`
-	}
+func (prc *AdminPriceRuleController) DeletePriceRule(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+	rule, err := model.GetPriceRuleByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "价格规则不存在"})
 		return
 	}
 
-	if err := model.UpdateSystemConfig(req.Key, req.Value); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新失败"})
+	if err := model.DeletePriceRule(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除失败"})
 		return
 	}
 
 	adminID, _ := c.Get("admin_id")
 	log := &model.AdminLog{
 		AdminID:    adminID.(int),
-		Action:     "update_system_config",
-		TargetType: "system_config",
-		Detail:     `{"key":"` + req.Key + `","value":"` + req.Value + `"}`,
+		Action:     "delete_price_rule",
+		TargetType: "price_rule",
+		TargetID:   id,
+		Detail:     `{"ticket_type_id":` + strconv.Itoa(rule.TicketTypeID) + `,"name":"` + rule.Name + `"}`,
 		IP:         c.ClientIP(),
 		UserAgent:  c.Request.UserAgent(),
 	}
 	model.CreateAdminLog(log)
 
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更新成功"})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "删除成功"})
+}
+
+func respondAdminPriceRuleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrTicketTypeNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "票种不存在"})
+	case service.ErrPriceRuleNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "价格规则不存在"})
+	case service.ErrPriceRuleParams:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": message})
+	}
+}
+
+type AdminAccessCodeController struct{}
+
+func (acc *AdminAccessCodeController) DisableAccessCode(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	accessCode, err := model.GetAccessCodeByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "兑换码不存在"})
+		return
+	}
+
+	if err := model.DisableAccessCode(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "停用失败"})
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "disable_access_code",
+		TargetType: "access_code",
+		TargetID:   id,
+		Detail:     `{"code":"` + accessCode.Code + `"}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "停用成功"})
+}
+
+func respondAdminBallotError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrTicketTypeNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "票种不存在"})
+	case service.ErrNotBallotTicketType, service.ErrBallotNotEnded, service.ErrBallotDrawPending,
+		service.ErrBallotPaymentOpen, service.ErrBallotNoDraw, service.ErrBallotNoEntrants, service.ErrBallotNoStock:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": message})
+	}
+}
+
+type AdminCouponController struct{}
+
+type couponRequest struct {
+	Name         string     `json:"name" binding:"required"`
+	Type         int        `json:"type" binding:"required"`
+	Value        util.Money `json:"value" binding:"required"`
+	MaxDiscount  util.Money `json:"max_discount"`
+	MinSpend     util.Money `json:"min_spend"`
+	StartTime    string     `json:"start_time" binding:"required"`
+	EndTime      string     `json:"end_time" binding:"required"`
+	TotalLimit   int        `json:"total_limit"`
+	PerUserLimit int        `json:"per_user_limit"`
+	ScopeType    int        `json:"scope_type"`
+	ScopeIDs     []int      `json:"scope_ids"`
+	Status       *int       `json:"status"`
+	Code         string     `json:"code"`
+}
+
+func (r *couponRequest) input() (service.CouponInput, bool) {
+	startTime, err := time.Parse("2006-01-02 15:04:05", r.StartTime)
+	if err != nil {
+		return service.CouponInput{}, false
+	}
+	endTime, err := time.Parse("2006-01-02 15:04:05", r.EndTime)
+	if err != nil {
+		return service.CouponInput{}, false
+	}
+	status := 1
+	if r.Status != nil {
+		status = *r.Status
+	}
+	return service.CouponInput{
+		Name:         r.Name,
+		Type:         r.Type,
+		Value:        r.Value,
+		MaxDiscount:  r.MaxDiscount,
+		MinSpend:     r.MinSpend,
+		StartTime:    startTime,
+		EndTime:      endTime,
+		TotalLimit:   r.TotalLimit,
+		PerUserLimit: r.PerUserLimit,
+		ScopeType:    r.ScopeType,
+		ScopeIDs:     r.ScopeIDs,
+		Status:       status,
+		Code:         r.Code,
+	}, true
+}
+
+func (cc *AdminCouponController) GetCouponList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "-1"))
+
+	coupons, total, err := model.GetCoupons(model.CouponQuery{
+		Keyword: c.Query("keyword"),
+		Status:  status,
+		Page:    page,
+		Size:    size,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取优惠券列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"list":  coupons,
+			"total": total,
+			"page":  page,
+			"size":  size,
+		},
+	})
+}
+
+func (cc *AdminCouponController) GetCouponDetail(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	coupon, err := model.GetCouponByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "优惠券不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": coupon})
+}
+
+func (cc *AdminCouponController) CreateCoupon(c *gin.Context) {
+	var req couponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	input, ok := req.input()
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "时间格式错误"})
+		return
+	}
+
+	coupon, err := service.NewCouponService().Create(input)
+	if err != nil {
+		respondAdminCouponError(c, err, "创建失败")
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "create_coupon",
+		TargetType: "coupon",
+		TargetID:   coupon.ID,
+		Detail:     `{"name":"` + coupon.Name + `"}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "创建成功", "data": coupon})
+}
+
+func (cc *AdminCouponController) UpdateCoupon(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	var req couponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	input, ok := req.input()
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "时间格式错误"})
+		return
+	}
+
+	coupon, err := service.NewCouponService().Update(id, input)
+	if err != nil {
+		respondAdminCouponError(c, err, "更新失败")
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "update_coupon",
+		TargetType: "coupon",
+		TargetID:   id,
+		Detail:     `{"name":"` + coupon.Name + `","status":` + strconv.Itoa(coupon.Status) + `}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更新成功", "data": coupon})
+}
+
+func (cc *AdminCouponController) DeleteCoupon(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	if err := service.NewCouponService().Delete(id); err != nil {
+		respondAdminCouponError(c, err, "删除失败")
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "delete_coupon",
+		TargetType: "coupon",
+		TargetID:   id,
+		Detail:     `{}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "删除成功"})
+}
+
+func (cc *AdminCouponController) GetCouponCodes(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if size < 1 {
+		size = 20
+	}
+
+	codes, total, err := model.GetCouponCodes(id, page, size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取优惠码列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"list":  codes,
+			"total": total,
+			"page":  page,
+			"size":  size,
+		},
+	})
+}
+
+func (cc *AdminCouponController) GenerateCouponCodes(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	var req struct {
+		Count   int `json:"count" binding:"required"`
+		MaxUses int `json:"max_uses"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	codes, err := service.NewCouponService().GenerateCodes(id, req.Count, req.MaxUses)
+	if err != nil {
+		respondAdminCouponError(c, err, "生成优惠码失败")
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "generate_coupon_codes",
+		TargetType: "coupon",
+		TargetID:   id,
+		Detail:     `{"count":` + strconv.Itoa(len(codes)) + `}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "生成成功", "data": codes})
+}
+
+func respondAdminCouponError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrCouponNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "优惠券不存在"})
+	case service.ErrCouponParams, service.ErrCouponInUse, service.ErrCouponCodeCount, service.ErrCouponCodeExists:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": message})
+	}
+}
+
+type AdminFeePolicyController struct{}
+
+type feePolicyRequest struct {
+	Name           string     `json:"name" binding:"required"`
+	ScopeType      int        `json:"scope_type"`
+	ScopeID        int        `json:"scope_id"`
+	ServiceFee     util.Money `json:"service_fee"`
+	ServiceFeeRate util.Rate  `json:"service_fee_rate"`
+	DeliveryFee    util.Money `json:"delivery_fee"`
+	TaxRate        util.Rate  `json:"tax_rate"`
+	TaxInclusive   int        `json:"tax_inclusive"`
+	Status         *int       `json:"status"`
+}
+
+func (r *feePolicyRequest) input() service.FeePolicyInput {
+	input := service.FeePolicyInput{
+		Name:           r.Name,
+		ScopeType:      r.ScopeType,
+		ScopeID:        r.ScopeID,
+		ServiceFee:     r.ServiceFee,
+		ServiceFeeRate: r.ServiceFeeRate,
+		DeliveryFee:    r.DeliveryFee,
+		TaxRate:        r.TaxRate,
+		TaxInclusive:   r.TaxInclusive,
+		Status:         1,
+	}
+	if r.Status != nil {
+		input.Status = *r.Status
+	}
+	return input
+}
+
+func (fc *AdminFeePolicyController) GetFeePolicyList(c *gin.Context) {
+	policies, err := model.GetFeePolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取费用策略失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": policies})
+}
+
+func (fc *AdminFeePolicyController) CreateFeePolicy(c *gin.Context) {
+	var req feePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	policy, err := service.NewFeeService().Create(req.input())
+	if err != nil {
+		respondAdminFeePolicyError(c, err, "创建失败")
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "create_fee_policy",
+		TargetType: "fee_policy",
+		TargetID:   policy.ID,
+		Detail:     `{"scope_type":` + strconv.Itoa(policy.ScopeType) + `,"scope_id":` + strconv.Itoa(policy.ScopeID) + `}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "创建成功", "data": policy})
+}
+
+func (fc *AdminFeePolicyController) UpdateFeePolicy(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	var req feePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	policy, err := service.NewFeeService().Update(id, req.input())
+	if err != nil {
+		respondAdminFeePolicyError(c, err, "更新失败")
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "update_fee_policy",
+		TargetType: "fee_policy",
+		TargetID:   id,
+		Detail:     `{"service_fee":` + policy.ServiceFee.String() + `,"tax_rate":` + util.Money(policy.TaxRate).String() + `,"status":` + strconv.Itoa(policy.Status) + `}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更新成功", "data": policy})
+}
+
+func (fc *AdminFeePolicyController) DeleteFeePolicy(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	policy, err := model.GetFeePolicyByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "费用策略不存在"})
+		return
+	}
+
+	if err := model.DeleteFeePolicy(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除失败"})
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "delete_fee_policy",
+		TargetType: "fee_policy",
+		TargetID:   id,
+		Detail:     `{"name":"` + policy.Name + `"}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "删除成功"})
+}
+
+func respondAdminFeePolicyError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrFeePolicyNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "费用策略不存在"})
+	case service.ErrFeePolicyParams, service.ErrFeePolicyExists:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": message})
+	}
+}
+
+type AdminInvoiceController struct{}
+
+func (ic *AdminInvoiceController) GetInvoiceList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "10"))
+	status, _ := strconv.Atoi(c.DefaultQuery("status", "0"))
+
+	query := model.InvoiceQuery{
+		Keyword: c.Query("keyword"),
+		Status:  status,
+		Page:    page,
+		Size:    size,
+	}
+
+	if startDate := c.Query("start_date"); startDate != "" {
+		if t, err := time.Parse("2006-01-02", startDate); err == nil {
+			query.StartDate = t
+		}
+	}
+
+	if endDate := c.Query("end_date"); endDate != "" {
+		if t, err := time.Parse("2006-01-02", endDate); err == nil {
+			query.EndDate = t.Add(24*time.Hour - time.Second)
+		}
+	}
+
+	invoices, total, err := model.GetInvoices(query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取发票列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"list":  invoices,
+			"total": total,
+			"page":  page,
+			"size":  size,
+		},
+	})
+}
+
+func (ic *AdminInvoiceController) DownloadInvoice(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	invoice, err := model.GetInvoiceByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "发票不存在"})
+		return
+	}
+
+	data, err := service.NewInvoiceService().RenderPDF(invoice)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成发票失败"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+invoice.InvoiceNo+`.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", data)
+}
+
+type AdminCategoryController struct{}
+
+func (cc *AdminCategoryController) GetCategoryList(c *gin.Context) {
+	categories, err := model.GetAllCategories()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取分类列表失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": categories,
+	})
+}
+
+func (cc *AdminCategoryController) CreateCategory(c *gin.Context) {
+	var req struct {
+		Name     string `json:"name" binding:"required"`
+		ParentID int    `json:"parent_id"`
+		Sort     int    `json:"sort"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	category := &model.Category{
+		Name:     req.Name,
+		ParentID: req.ParentID,
+		Sort:     req.Sort,
+	}
+
+	if err := model.CreateCategory(category); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "创建失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "创建成功", "data": category})
+}
+
+func (cc *AdminCategoryController) UpdateCategory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	var req struct {
+		Name string `json:"name"`
+		Sort int    `json:"sort"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	category, err := model.GetCategoryByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "分类不存在"})
+		return
+	}
+
+	if req.Name != "" {
+		category.Name = req.Name
+	}
+	if req.Sort >= 0 {
+		category.Sort = req.Sort
+	}
+
+	if err := model.UpdateCategory(category); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更新成功"})
+}
+
+func (cc *AdminCategoryController) DeleteCategory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, _ := strconv.Atoi(idStr)
+
+	category, err := model.GetCategoryByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "分类不存在"})
+		return
+	}
+
+	hasPerformances := model.HasCategoryPerformances(id)
+	if hasPerformances {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "该分类下存在演出，无法删除"})
+		return
+	}
+
+	if err := model.DeleteCategory(id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除失败"})
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "delete_category",
+		TargetType: "category",
+		TargetID:   id,
+		Detail:     `{"name":"` + category.Name + `"}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "删除成功"})
+}
+
+type AdminSystemController struct{}
+
+func (sc *AdminSystemController) GetConfig(c *gin.Context) {
+	configs, err := model.GetAllSystemConfigs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取配置失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": configs,
+	})
+}
+
+func (sc *AdminSystemController) UpdateConfig(c *gin.Context) {
+	var req struct {
+		Key   string `json:"key" binding:"required"`
+		Value string `json:"value" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	// 强制两步验证只能由超级管理员通过专用接口修改
+	if req.Key == model.SystemConfigAdminRequire2FA {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "无权限修改此配置"})
+		return
+	}
+
+	if err := model.UpdateSystemConfig(req.Key, req.Value); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新失败"})
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "update_system_config",
+		TargetType: "system_config",
+		Detail:     `{"key":"` + req.Key + `","value":"` + req.Value + `"}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更新成功"})
+}
+
+type AdminTicketController struct{}
+
+func (tc *AdminTicketController) GetTicket(c *gin.Context) {
+	ticket, err := model.GetTicketByNo(c.Param("ticketNo"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "电子票不存在"})
+		return
+	}
+
+	performance, _ := model.GetPerformanceByID(ticket.PerformanceID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"ticket":      ticket,
+			"performance": performance,
+		},
+	})
+}
+
+func (tc *AdminTicketController) CheckIn(c *gin.Context) {
+	var req struct {
+		TicketNo      string `json:"ticket_no" binding:"required"`
+		PerformanceID int    `json:"performance_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	// 扫描二维码得到的是带签名的票码，手工录入时为票号
+	ticketNo := req.TicketNo
+	if strings.Contains(ticketNo, ".") {
+		var ok bool
+		if ticketNo, ok = util.VerifyTicketCode(ticketNo); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "票码签名无效"})
+			return
+		}
+	}
+
+	ticket, err := model.GetTicketByNo(ticketNo)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "电子票不存在"})
+		return
+	}
+
+	if req.PerformanceID > 0 && ticket.PerformanceID != req.PerformanceID {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "该票不属于本场演出"})
+		return
+	}
+
+	switch ticket.Status {
+	case model.TicketStatusPending:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "订单尚未支付"})
+		return
+	case model.TicketStatusCheckedIn:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "该票已检票", "data": gin.H{
+			"attendee_name": ticket.AttendeeName,
+			"checked_in_at": ticket.CheckedInAt,
+		}})
+		return
+	case model.TicketStatusVoid:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "该票已作废"})
+		return
+	}
+
+	ok, err := model.CheckInTicket(ticket.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "检票失败"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "该票已检票"})
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "ticket_check_in",
+		TargetType: "ticket",
+		TargetID:   ticket.ID,
+		Detail:     `{"ticket_no":"` + ticket.TicketNo + `"}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	ticketTypeName := ""
+	if ticket.TicketType != nil {
+		ticketTypeName = ticket.TicketType.Name
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "检票成功", "data": gin.H{
+		"ticket_no":     ticket.TicketNo,
+		"ticket_type":   ticketTypeName,
+		"attendee_name": ticket.AttendeeName,
+		"id_number":     ticket.IDNumberMasked,
+	}})
 }
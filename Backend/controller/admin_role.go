@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminRoleController struct{}
+
+type adminRoleRequest struct {
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name" binding:"required,max=50"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+func (r *adminRoleRequest) input() service.AdminRoleInput {
+	return service.AdminRoleInput{
+		Name:        r.Name,
+		DisplayName: r.DisplayName,
+		Description: r.Description,
+		Permissions: r.Permissions,
+	}
+}
+
+// GetPermissions 权限目录
+func (rc *AdminRoleController) GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": model.AdminPermissions})
+}
+
+// GetRoles 角色列表，含各角色的权限和管理员人数
+func (rc *AdminRoleController) GetRoles(c *gin.Context) {
+	roles, err := service.NewAdminRoleService().ListRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取角色失败"})
+		return
+	}
+	counts, _ := model.CountAdminsByRole()
+
+	list := make([]gin.H, 0, len(roles))
+	for _, role := range roles {
+		item := adminRoleData(role)
+		item["admin_count"] = counts[role.Name]
+		list = append(list, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": list})
+}
+
+func (rc *AdminRoleController) CreateRole(c *gin.Context) {
+	var req adminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	role, err := service.NewAdminRoleService().CreateRole(req.input())
+	if err != nil {
+		respondAdminRoleError(c, err, "创建角色失败")
+		return
+	}
+
+	writeAdminRoleLog(c, "create_role", role)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "创建成功", "data": adminRoleData(role)})
+}
+
+func (rc *AdminRoleController) UpdateRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	var req adminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	role, err := service.NewAdminRoleService().UpdateRole(id, req.input())
+	if err != nil {
+		respondAdminRoleError(c, err, "更新角色失败")
+		return
+	}
+
+	writeAdminRoleLog(c, "update_role", role)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更新成功", "data": adminRoleData(role)})
+}
+
+func (rc *AdminRoleController) DeleteRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	role, err := service.NewAdminRoleService().DeleteRole(id)
+	if err != nil {
+		respondAdminRoleError(c, err, "删除角色失败")
+		return
+	}
+
+	writeAdminRoleLog(c, "delete_role", role)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "删除成功"})
+}
+
+func adminRoleData(role *model.AdminRole) gin.H {
+	return gin.H{
+		"id":           role.ID,
+		"name":         role.Name,
+		"display_name": role.DisplayName,
+		"description":  role.Description,
+		"permissions":  service.NewAdminRoleService().RolePermissions(role),
+		"built_in":     role.BuiltIn == 1,
+		"created_at":   role.CreatedAt,
+		"updated_at":   role.UpdatedAt,
+	}
+}
+
+// writeAdminRoleLog 记录角色变更，详情中包含变更后的完整权限列表
+func writeAdminRoleLog(c *gin.Context, action string, role *model.AdminRole) {
+	permissions, _ := json.Marshal(role.PermissionList())
+	name, _ := json.Marshal(role.Name)
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     action,
+		TargetType: "admin_role",
+		TargetID:   role.ID,
+		Detail:     `{"name":` + string(name) + `,"permissions":` + string(permissions) + `}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+}
+
+func respondAdminRoleError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrRoleNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	case service.ErrRoleExists, service.ErrRoleNameInvalid, service.ErrRoleBuiltIn, service.ErrRoleSuperAdminImmutable,
+		service.ErrRoleInUse, service.ErrPermissionUnknown, service.ErrPermissionSuperOnly:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": message})
+	}
+}
@@ -0,0 +1,361 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/service"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// VerifyTwoFactor 登录第二步：提交密码登录得到的临时令牌和动态码（或恢复码），换取管理员令牌
+func (ac *AdminController) VerifyTwoFactor(c *gin.Context) {
+	var req struct {
+		TwoFactorToken string `json:"two_factor_token" binding:"required"`
+		Code           string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	authService := service.NewAdminAuthService()
+	admin, err := authService.AdminFromTwoFactorToken(req.TwoFactorToken, service.AdminLoginStageTwoFactorVerify)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "登录已过期，请重新登录"})
+		return
+	}
+
+	usedRecoveryCode, err := authService.VerifyCode(admin, req.Code)
+	event := model.LoginEventTwoFactorVerify
+	if usedRecoveryCode {
+		event = model.LoginEventRecoveryCode
+	}
+	recordAdminLoginEvent(c, admin.ID, event, err)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	method := "totp"
+	if usedRecoveryCode {
+		method = "recovery_code"
+	}
+	data, ok := ac.issueLoginToken(c, admin, method)
+	if !ok {
+		return
+	}
+	if usedRecoveryCode {
+		remaining, _ := model.CountAdminRecoveryCodes(admin.ID)
+		data["recovery_codes_remaining"] = remaining
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "登录成功", "data": data})
+}
+
+// BeginTwoFactorSetup 系统强制启用两步验证时，尚未绑定的管理员在登录过程中获取验证器密钥
+func (ac *AdminController) BeginTwoFactorSetup(c *gin.Context) {
+	var req struct {
+		TwoFactorToken string `json:"two_factor_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	authService := service.NewAdminAuthService()
+	admin, err := authService.AdminFromTwoFactorToken(req.TwoFactorToken, service.AdminLoginStageTwoFactorSetup)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "登录已过期，请重新登录"})
+		return
+	}
+
+	setup, err := authService.BeginSetup(admin)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": setup})
+}
+
+// ConfirmTwoFactorSetup 登录过程中确认绑定，成功后直接签发管理员令牌并返回恢复码
+func (ac *AdminController) ConfirmTwoFactorSetup(c *gin.Context) {
+	var req struct {
+		TwoFactorToken string `json:"two_factor_token" binding:"required"`
+		Code           string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	authService := service.NewAdminAuthService()
+	admin, err := authService.AdminFromTwoFactorToken(req.TwoFactorToken, service.AdminLoginStageTwoFactorSetup)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "登录已过期，请重新登录"})
+		return
+	}
+
+	codes, err := authService.ConfirmSetup(admin, req.Code)
+	recordAdminLoginEvent(c, admin.ID, model.LoginEventTwoFactorEnable, err)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	data, ok := ac.issueLoginToken(c, admin, "totp")
+	if !ok {
+		return
+	}
+	data["recovery_codes"] = codes
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "两步验证已启用，请妥善保存恢复码", "data": data})
+}
+
+type AdminTwoFactorController struct{}
+
+// GetStatus 当前管理员的两步验证状态
+func (tc *AdminTwoFactorController) GetStatus(c *gin.Context) {
+	admin, ok := currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	remaining := 0
+	if admin.TOTPEnabled == 1 {
+		remaining, _ = model.CountAdminRecoveryCodes(admin.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 200,
+		"data": gin.H{
+			"enabled":                  admin.TOTPEnabled == 1,
+			"enabled_at":               admin.TOTPEnabledAt,
+			"enforced":                 service.NewAdminAuthService().TwoFactorEnforced(),
+			"recovery_codes_remaining": remaining,
+		},
+	})
+}
+
+// Setup 获取验证器密钥和二维码，调用 Confirm 提交动态码后才启用
+func (tc *AdminTwoFactorController) Setup(c *gin.Context) {
+	admin, ok := currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	setup, err := service.NewAdminAuthService().BeginSetup(admin)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "data": setup})
+}
+
+// Confirm 提交验证器上的动态码，确认启用两步验证
+func (tc *AdminTwoFactorController) Confirm(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	admin, ok := currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	codes, err := service.NewAdminAuthService().ConfirmSetup(admin, req.Code)
+	recordAdminLoginEvent(c, admin.ID, model.LoginEventTwoFactorEnable, err)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "两步验证已启用，请妥善保存恢复码",
+		"data":    gin.H{"recovery_codes": codes},
+	})
+}
+
+// Disable 关闭两步验证，需要当前密码和动态码（或恢复码）
+func (tc *AdminTwoFactorController) Disable(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	admin, ok := currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(req.Password)); err != nil {
+		recordAdminLoginEvent(c, admin.ID, model.LoginEventTwoFactorDisable, errors.New("密码错误"))
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "密码错误"})
+		return
+	}
+
+	err := service.NewAdminAuthService().Disable(admin, req.Code)
+	recordAdminLoginEvent(c, admin.ID, model.LoginEventTwoFactorDisable, err)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "两步验证已关闭"})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func (tc *AdminTwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	admin, ok := currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	codes, err := service.NewAdminAuthService().RegenerateRecoveryCodes(admin, req.Code)
+	recordAdminLoginEvent(c, admin.ID, model.LoginEventRecoveryRenew, err)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "恢复码已重新生成",
+		"data":    gin.H{"recovery_codes": codes},
+	})
+}
+
+// UpdatePolicy 超级管理员开启或关闭“所有管理员必须启用两步验证”
+func (tc *AdminTwoFactorController) UpdatePolicy(c *gin.Context) {
+	var req struct {
+		Enforced *bool `json:"enforced" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	if err := service.NewAdminAuthService().SetTwoFactorEnforced(*req.Enforced); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新失败"})
+		return
+	}
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "update_2fa_policy",
+		TargetType: "system_config",
+		Detail:     `{"enforced":` + strconv.FormatBool(*req.Enforced) + `}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更新成功"})
+}
+
+// ResetAdmin 超级管理员为丢失验证器的管理员解除两步验证绑定
+func (tc *AdminTwoFactorController) ResetAdmin(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
+		return
+	}
+
+	target, err := model.GetAdminByID(targetID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "管理员不存在"})
+		return
+	}
+
+	if err := service.NewAdminAuthService().Reset(target.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "重置失败"})
+		return
+	}
+	recordAdminLoginEvent(c, target.ID, model.LoginEventTwoFactorReset, nil)
+
+	adminID, _ := c.Get("admin_id")
+	log := &model.AdminLog{
+		AdminID:    adminID.(int),
+		Action:     "reset_admin_2fa",
+		TargetType: "admin",
+		TargetID:   target.ID,
+		Detail:     `{"username":"` + target.Username + `"}`,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+	}
+	model.CreateAdminLog(log)
+
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "已重置该管理员的两步验证"})
+}
+
+func currentAdmin(c *gin.Context) (*model.Admin, bool) {
+	adminID, _ := c.Get("admin_id")
+	admin, err := model.GetAdminByID(adminID.(int))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "管理员不存在"})
+		return nil, false
+	}
+	return admin, true
+}
+
+// recordAdminLoginEvent 将登录及两步验证相关事件写入登录历史，err 为空表示成功
+func recordAdminLoginEvent(c *gin.Context, adminID int, event string, err error) {
+	history := &model.AdminLoginHistory{
+		AdminID:   adminID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Status:    1,
+		Event:     event,
+	}
+	if err != nil {
+		history.Status = 0
+		reason := []rune(err.Error())
+		if len(reason) > 100 {
+			reason = reason[:100]
+		}
+		history.FailReason = string(reason)
+	}
+	model.CreateLoginHistory(history)
+}
+
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrTwoFactorCodeInvalid, service.ErrTwoFactorEnabled, service.ErrTwoFactorNotEnabled, service.ErrTwoFactorNotSetup:
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	case service.ErrTwoFactorLocked:
+		c.JSON(http.StatusTooManyRequests, gin.H{"code": 429, "message": err.Error()})
+	case service.ErrTwoFactorEnforced:
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "操作失败"})
+	}
+}
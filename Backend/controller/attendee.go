@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"ticket-system-backend/service"
+	"ticket-system-backend/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AttendeeController struct{}
+
+func (ac *AttendeeController) GetAttendees(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	attendees, err := service.NewAttendeeService().GetAttendees(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取观演人列表失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(attendees))
+}
+
+func (ac *AttendeeController) AddAttendee(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	var request struct {
+		Name     string `json:"name" binding:"required"`
+		IdNumber string `json:"idNumber" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	attendee, err := service.NewAttendeeService().AddAttendee(userID.(int), request.Name, request.IdNumber)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidAttendeeName, service.ErrInvalidIDNumber, service.ErrAttendeeLimitExceeded:
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeAttendeeInvalid, err.Error()))
+		case service.ErrAttendeeExists:
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeAttendeeExist, ""))
+		default:
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "添加观演人失败"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(attendee))
+}
+
+func (ac *AttendeeController) DeleteAttendee(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "无效的观演人ID"))
+		return
+	}
+
+	if err := service.NewAttendeeService().DeleteAttendee(userID.(int), id); err != nil {
+		if err == service.ErrAttendeeNotFound {
+			c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeAttendeeNotExist, ""))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "删除观演人失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
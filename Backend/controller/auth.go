@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"net/http"
+
+	"ticket-system-backend/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthController struct{}
+
+// JWKS 公开令牌签名公钥，其他服务据此验证用户令牌（受众 ticket-system-user）
+func (ac *AuthController) JWKS(c *gin.Context) {
+	jwks, err := util.JWKS()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取签名公钥失败"))
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwks)
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"ticket-system-backend/service"
+	"ticket-system-backend/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BallotController struct{}
+
+func (bc *BallotController) GetEntries(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	entries, err := service.NewBallotService().GetEntries(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取抽签登记失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(entries))
+}
+
+func (bc *BallotController) Enter(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	ticketTypeID, err := strconv.Atoi(c.Param("ticketTypeId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "无效的票种ID"))
+		return
+	}
+
+	var request struct {
+		Quantity    int   `json:"quantity" binding:"required,min=1"`
+		AttendeeIds []int `json:"attendeeIds"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	entry, err := service.NewBallotService().Enter(userID.(int), ticketTypeID, request.Quantity, request.AttendeeIds)
+	if err != nil {
+		respondBallotError(c, err, "抽签登记失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(entry))
+}
+
+func (bc *BallotController) Withdraw(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	ticketTypeID, err := strconv.Atoi(c.Param("ticketTypeId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "无效的票种ID"))
+		return
+	}
+
+	if err := service.NewBallotService().Withdraw(userID.(int), ticketTypeID); err != nil {
+		respondBallotError(c, err, "撤销抽签登记失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
+
+func respondBallotError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrTicketTypeNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeTicketNotExist, ""))
+	case service.ErrPerformanceNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodePerformanceNotExist, ""))
+	case service.ErrNotBallotTicketType:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeNotBallot, ""))
+	case service.ErrBallotClosed:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBallotClosed, ""))
+	case service.ErrBallotEntryExists:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBallotEntryExist, ""))
+	case service.ErrBallotEntryNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeBallotEntryNotExist, ""))
+	case service.ErrTicketLimitExceeded:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeTicketLimitExceeded, "超过最大购买数量"))
+	case service.ErrRealNameRequired, service.ErrAttendeeCountMismatch, service.ErrAttendeeDuplicate:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeAttendeeInvalid, err.Error()))
+	case service.ErrAttendeeNotFound:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeAttendeeNotExist, ""))
+	default:
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, message))
+	}
+}
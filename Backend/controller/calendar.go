@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ticket-system-backend/service"
+	"ticket-system-backend/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+const calendarContentType = "text/calendar; charset=utf-8"
+
+type CalendarController struct{}
+
+// GetUserCalendar 下载用户尚未结束的演出日历
+func (cc *CalendarController) GetUserCalendar(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	data, err := service.NewCalendarService().UserCalendar(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "生成日历失败"))
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="calendar.ics"`)
+	c.Data(http.StatusOK, calendarContentType, data)
+}
+
+// GetOrderCalendar 下载单个订单的日历文件
+func (cc *CalendarController) GetOrderCalendar(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "无效的订单ID"))
+		return
+	}
+
+	data, order, err := service.NewCalendarService().OrderCalendar(userID.(int), id)
+	if err != nil {
+		switch err {
+		case service.ErrOrderNotFound:
+			c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeOrderNotExist, ""))
+		case service.ErrCalendarOrderNotPaid:
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeOrderStatusError, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "生成日历失败"))
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+order.OrderNo+`.ics"`)
+	c.Data(http.StatusOK, calendarContentType, data)
+}
+
+// GetCalendarFeed 查询日历订阅状态，订阅链接只在生成时返回
+func (cc *CalendarController) GetCalendarFeed(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	feed := service.NewCalendarService().GetFeed(userID.(int))
+	if feed == nil {
+		c.JSON(http.StatusOK, util.SuccessResponse(map[string]interface{}{"enabled": false}))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(map[string]interface{}{
+		"enabled":      true,
+		"created_at":   feed.CreatedAt,
+		"last_used_at": feed.LastUsedAt,
+	}))
+}
+
+// ResetCalendarFeed 开启或重新生成日历订阅链接，旧链接失效
+func (cc *CalendarController) ResetCalendarFeed(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	token, feed, err := service.NewCalendarService().ResetFeed(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "生成订阅链接失败"))
+		return
+	}
+
+	url := requestBaseURL(c) + "/api/calendar/" + token + ".ics"
+	c.JSON(http.StatusOK, util.SuccessResponse(map[string]interface{}{
+		"enabled":    true,
+		"url":        url,
+		"webcal_url": "webcal://" + url[strings.Index(url, "://")+3:],
+		"created_at": feed.CreatedAt,
+	}))
+}
+
+// RevokeCalendarFeed 关闭日历订阅
+func (cc *CalendarController) RevokeCalendarFeed(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	if err := service.NewCalendarService().RevokeFeed(userID.(int)); err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "关闭订阅失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
+
+// SubscribeCalendar 日历应用通过私有链接拉取日历，无需登录
+func (cc *CalendarController) SubscribeCalendar(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	data, err := service.NewCalendarService().FeedCalendar(token)
+	if err != nil {
+		if err == service.ErrCalendarFeedNotFound {
+			c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeNotFound, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "生成日历失败"))
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, calendarContentType, data)
+}
+
+// requestBaseURL 根据请求（含反向代理头）还原对外访问的地址
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	host := c.Request.Host
+	if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ticket-system-backend/service"
+	"ticket-system-backend/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CartController struct{}
+
+func (cc *CartController) GetCart(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	cart, err := service.NewCartService().GetCart(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取购物车失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(cart))
+}
+
+func (cc *CartController) AddItem(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	var request struct {
+		TicketTypeId int `json:"ticketTypeId" binding:"required"`
+		Quantity     int `json:"quantity" binding:"required,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	item, err := service.NewCartService().AddItem(userID.(int), request.TicketTypeId, request.Quantity)
+	if err != nil {
+		respondCartError(c, err, "加入购物车失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(item))
+}
+
+func (cc *CartController) UpdateItem(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "无效的购物车条目ID"))
+		return
+	}
+
+	var request struct {
+		Quantity int `json:"quantity" binding:"required,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	item, err := service.NewCartService().UpdateItem(userID.(int), id, request.Quantity)
+	if err != nil {
+		respondCartError(c, err, "更新购物车失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(item))
+}
+
+func (cc *CartController) RemoveItem(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "无效的购物车条目ID"))
+		return
+	}
+
+	if err := service.NewCartService().RemoveItem(userID.(int), id); err != nil {
+		respondCartError(c, err, "删除购物车条目失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
+
+func (cc *CartController) Checkout(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	// itemIds 为空时结算整个购物车；实名演出需在 attendees 中按票种指定观演人
+	var request struct {
+		ItemIds   []int `json:"itemIds"`
+		Attendees []struct {
+			TicketTypeId int   `json:"ticketTypeId"`
+			AttendeeIds  []int `json:"attendeeIds"`
+		} `json:"attendees"`
+		CouponCode string `json:"couponCode"`
+	}
+
+	// 不带请求体时结算整个购物车
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	attendees := make(map[int][]int, len(request.Attendees))
+	for _, a := range request.Attendees {
+		attendees[a.TicketTypeId] = append(attendees[a.TicketTypeId], a.AttendeeIds...)
+	}
+
+	order, err := service.NewCartService().Checkout(context.Background(), userID.(int), request.ItemIds, attendees, request.CouponCode)
+	if err != nil {
+		respondPlaceOrderError(c, err)
+		return
+	}
+
+	response := struct {
+		OrderId    int        `json:"order_id"`
+		OrderNo    string     `json:"order_no"`
+		Quantity   int        `json:"quantity"`
+		Amount     util.Money `json:"amount"`
+		ExpireTime string     `json:"expire_time"`
+	}{order.ID, order.OrderNo, order.Quantity, order.Amount, order.ExpireTime.Format(time.RFC3339)}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(response))
+}
+
+func respondCartError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrCartItemNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeCartItemNotExist, ""))
+	case service.ErrTicketTypeNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeTicketNotExist, ""))
+	case service.ErrTicketLimitExceeded:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeTicketLimitExceeded, "超过最大购买数量"))
+	case service.ErrBallotTicketType:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBallotOnly, ""))
+	default:
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, message))
+	}
+}
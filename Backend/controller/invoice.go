@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"ticket-system-backend/service"
+	"ticket-system-backend/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvoiceController struct{}
+
+func (ic *InvoiceController) RequestInvoice(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "无效的订单ID"))
+		return
+	}
+
+	var request struct {
+		Title string `json:"title" binding:"required"`
+		TaxID string `json:"taxId"`
+		Email string `json:"email" binding:"omitempty,email"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	invoice, err := service.NewInvoiceService().Request(userID.(int), id, service.InvoiceInput{
+		Title: request.Title,
+		TaxID: request.TaxID,
+		Email: request.Email,
+	})
+	if err != nil {
+		respondInvoiceError(c, err, "开具发票失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(invoice))
+}
+
+func (ic *InvoiceController) GetInvoice(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "无效的订单ID"))
+		return
+	}
+
+	invoice, err := service.NewInvoiceService().GetOrderInvoice(userID.(int), id)
+	if err != nil {
+		respondInvoiceError(c, err, "获取发票失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(invoice))
+}
+
+func (ic *InvoiceController) DownloadInvoice(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "无效的订单ID"))
+		return
+	}
+
+	invoiceService := service.NewInvoiceService()
+	invoice, err := invoiceService.GetOrderInvoice(userID.(int), id)
+	if err != nil {
+		respondInvoiceError(c, err, "获取发票失败")
+		return
+	}
+
+	data, err := invoiceService.RenderPDF(invoice)
+	if err != nil {
+		respondInvoiceError(c, err, "生成发票失败")
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+invoice.InvoiceNo+`.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", data)
+}
+
+func respondInvoiceError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrOrderNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeOrderNotExist, ""))
+	case service.ErrInvoiceNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeInvoiceNotExist, ""))
+	case service.ErrInvoiceExists:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeInvoiceExist, ""))
+	case service.ErrInvoiceOrderNotPaid:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeInvoiceUnavailable, ""))
+	case service.ErrInvoiceParams:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, message))
+	}
+}
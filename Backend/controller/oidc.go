@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"ticket-system-backend/service"
+	"ticket-system-backend/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCController struct{}
+
+// GetProviders 已配置的第三方登录方式
+func (oc *OIDCController) GetProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, util.SuccessResponse(service.NewOIDCService().Providers()))
+}
+
+// Authorize 发起第三方登录，前端跳转到返回的授权地址
+func (oc *OIDCController) Authorize(c *gin.Context) {
+	authURL, err := service.NewOIDCService().AuthorizationURL(c.Param("provider"), 0)
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(gin.H{"authorization_url": authURL}))
+}
+
+// AuthorizeLink 已登录用户发起绑定第三方账号，授权完成后同样提交到回调接口
+func (oc *OIDCController) AuthorizeLink(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	authURL, err := service.NewOIDCService().AuthorizationURL(c.Param("provider"), userID.(int))
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(gin.H{"authorization_url": authURL}))
+}
+
+// Callback 前端回调页面提交服务商返回的 code 和 state，完成登录或绑定
+func (oc *OIDCController) Callback(c *gin.Context) {
+	var request struct {
+		Code       string `json:"code" binding:"required"`
+		State      string `json:"state" binding:"required"`
+		DeviceName string `json:"device_name" binding:"max=50"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	result, err := service.NewOIDCService().HandleCallback(c.Param("provider"), request.Code, request.State, sessionInfo(c, request.DeviceName))
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	if result.Linked {
+		c.JSON(http.StatusOK, util.SuccessResponse(gin.H{"linked": true, "identity": result.Identity}))
+		return
+	}
+
+	user := result.User
+	c.JSON(http.StatusOK, util.SuccessResponse(gin.H{
+		"id":            user.ID,
+		"username":      user.Username,
+		"phone":         user.Phone,
+		"email":         user.Email,
+		"avatar":        user.Avatar,
+		"token":         result.Tokens.Token,
+		"refresh_token": result.Tokens.RefreshToken,
+		"expires_in":    result.Tokens.ExpiresIn,
+		"new_user":      result.NewUser,
+	}))
+}
+
+// GetIdentities 当前用户绑定的第三方账号
+func (oc *OIDCController) GetIdentities(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	identities, err := service.NewOIDCService().ListIdentities(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取绑定账号失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(identities))
+}
+
+// UnlinkIdentity 解绑第三方账号
+func (oc *OIDCController) UnlinkIdentity(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	identityID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	if err := service.NewOIDCService().Unlink(userID.(int), identityID); err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
+
+func respondOIDCError(c *gin.Context, err error) {
+	switch err {
+	case util.ErrOIDCProviderNotFound, service.ErrIdentityNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeNotFound, err.Error()))
+	case util.ErrOIDCStateInvalid, service.ErrOIDCLoginFailed:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeOIDCLoginFailed, err.Error()))
+	case service.ErrIdentityLinkedToOther, service.ErrIdentityProviderLinked, service.ErrLastLoginMethod:
+		c.JSON(http.StatusConflict, util.ErrorResponse(util.StatusCodeIdentityConflict, err.Error()))
+	case service.ErrUserDisabled:
+		c.JSON(http.StatusForbidden, util.ErrorResponse(util.StatusCodeForbidden, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "第三方登录失败"))
+	}
+}
@@ -1,11 +1,13 @@
 package controller
 
 import (
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"ticket-system-backend/model"
+	"ticket-system-backend/service"
 	"ticket-system-backend/util"
 
 	"github.com/gin-gonic/gin"
@@ -158,12 +160,16 @@ func (oc *OrderController) CancelOrder(c *gin.Context) {
 		return
 	}
 
-	if err := model.UpdateOrderStatus(order.ID, 2); err != nil {
+	ok, err := service.CloseOrder(order, 0, 2)
+	if err != nil {
+		log.Printf("取消订单失败: order=%d, err=%v", order.ID, err)
 		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "取消订单失败"))
 		return
 	}
-
-	model.IncreaseStock(order.TicketTypeID, order.Quantity)
+	if !ok {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeOrderStatusError, "订单状态错误，无法取消"))
+		return
+	}
 
 	c.JSON(http.StatusOK, util.SuccessResponse(nil))
 }
@@ -208,6 +214,10 @@ func (oc *OrderController) PayOrder(c *gin.Context) {
 	}
 
 	if err := model.PayOrder(order.ID); err != nil {
+		if err == model.ErrOrderNotPending {
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeOrderStatusError, "订单状态错误，无法支付"))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "支付订单失败"))
 		return
 	}
@@ -218,31 +228,42 @@ func (oc *OrderController) PayOrder(c *gin.Context) {
 		return
 	}
 
-	ticketType, err := model.GetTicketTypeByID(order.TicketTypeID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取票种信息失败"))
-		return
-	}
-	if ticketType == nil {
-		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeTicketNotExist, ""))
-		return
-	}
-	_ = ticketType
-
 	type TicketInfo struct {
-		TicketNo  string    `json:"ticket_no"`
-		Status    int       `json:"status"`
-		CreatedAt time.Time `json:"created_at"`
+		TicketNo     string    `json:"ticket_no"`
+		TicketTypeID int       `json:"ticket_type_id"`
+		AttendeeName string    `json:"attendee_name,omitempty"`
+		IDNumber     string    `json:"id_number,omitempty"`
+		Status       int       `json:"status"`
+		CreatedAt    time.Time `json:"created_at"`
 	}
 	tickets := []TicketInfo{}
 	if updatedOrder.PaymentTime != nil {
-		for i := 0; i < order.Quantity; i++ {
+		issued, err := model.GetOrderTickets(order.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取电子票失败"))
+			return
+		}
+		for _, ticket := range issued {
 			tickets = append(tickets, TicketInfo{
-				TicketNo:  "TICKET" + strconv.Itoa(order.ID) + strconv.Itoa(i+1),
-				Status:    0,
-				CreatedAt: *updatedOrder.PaymentTime,
+				TicketNo:     ticket.TicketNo,
+				TicketTypeID: ticket.TicketTypeID,
+				AttendeeName: ticket.AttendeeName,
+				IDNumber:     ticket.IDNumberMasked,
+				Status:       ticket.Status,
+				CreatedAt:    *updatedOrder.PaymentTime,
 			})
 		}
+		// 早期订单没有电子票记录，沿用按序号生成的票号
+		if len(issued) == 0 {
+			for i := 0; i < order.Quantity; i++ {
+				tickets = append(tickets, TicketInfo{
+					TicketNo:     "TICKET" + strconv.Itoa(order.ID) + strconv.Itoa(i+1),
+					TicketTypeID: order.TicketTypeID,
+					Status:       0,
+					CreatedAt:    *updatedOrder.PaymentTime,
+				})
+			}
+		}
 	}
 
 	response := struct {
@@ -287,10 +308,100 @@ func (oc *OrderController) RefundOrder(c *gin.Context) {
 		return
 	}
 
-	if err := model.UpdateOrderStatus(order.ID, 3); err != nil {
+	ok, err := service.CloseOrder(order, 1, 3)
+	if err != nil {
+		log.Printf("退款订单失败: order=%d, err=%v", order.ID, err)
 		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "退款申请失败"))
 		return
 	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeOrderStatusError, "订单状态错误，无法申请退款"))
+		return
+	}
 
-	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+	c.JSON(http.StatusOK, util.SuccessResponse(map[string]interface{}{
+		"refund_amount": order.Amount,
+		"currency":      order.Currency,
+	}))
+}
+
+// DownloadTickets 下载订单的纸质票 PDF
+func (oc *OrderController) DownloadTickets(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "无效的订单ID"))
+		return
+	}
+
+	order, err := model.GetOrderByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeOrderNotExist, ""))
+		return
+	}
+
+	if order.UserID != userID.(int) {
+		c.JSON(http.StatusForbidden, util.ErrorResponse(util.StatusCodeForbidden, ""))
+		return
+	}
+
+	data, err := service.NewTicketService().RenderOrderTicketsPDF(order)
+	if err != nil {
+		if err == service.ErrNoPrintableTickets {
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeOrderStatusError, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "生成电子票失败"))
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="tickets_`+order.OrderNo+`.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", data)
+}
+
+// respondPlaceOrderError 将下单失败的原因转换为接口响应
+func respondPlaceOrderError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrTicketLimitExceeded:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeTicketLimitExceeded, "超过最大购买数量"))
+	case service.ErrContactNotVerified:
+		c.JSON(http.StatusForbidden, util.ErrorResponse(util.StatusCodeContactNotVerified, err.Error()))
+	case service.ErrTicketTypeNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeTicketNotExist, ""))
+	case service.ErrPerformanceNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodePerformanceNotExist, ""))
+	case service.ErrStockInsufficient:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeTicketStockInsufficient, "库存不足"))
+	case service.ErrLockAcquireFailed:
+		c.JSON(http.StatusServiceUnavailable, util.ErrorResponse(util.StatusCodeTicketSeckillFailed, "系统繁忙，请稍后重试"))
+	case service.ErrSeckillFailed:
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeTicketSeckillFailed, "扣减库存失败"))
+	case service.ErrOrderMixPerformances:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeOrderMixPerformances, ""))
+	case service.ErrOrderEmpty, service.ErrCartEmpty:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeCartEmpty, ""))
+	case service.ErrRealNameRequired, service.ErrAttendeeCountMismatch, service.ErrAttendeeDuplicate:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeAttendeeInvalid, err.Error()))
+	case service.ErrAttendeeNotFound:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeAttendeeNotExist, ""))
+	case service.ErrAttendeeTicketed:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeAttendeeTicketed, ""))
+	case service.ErrBallotTicketType:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBallotOnly, ""))
+	case service.ErrAccessCodeExhausted:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeAccessCodeInvalid, err.Error()))
+	case service.ErrCouponInvalid, service.ErrCouponExpired:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeCouponInvalid, err.Error()))
+	case service.ErrCouponNotApplicable, service.ErrCouponMinSpend:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeCouponNotApplicable, err.Error()))
+	case service.ErrCouponUserLimit, service.ErrCouponExhausted:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeCouponLimitExceeded, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "创建订单失败"))
+	}
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"ticket-system-backend/model"
+	"ticket-system-backend/service"
 	"ticket-system-backend/util"
 
 	"github.com/gin-gonic/gin"
@@ -74,7 +75,8 @@ func (pc *PerformanceController) GetPerformanceByID(c *gin.Context) {
 		return
 	}
 
-	ticketTypes, err := model.GetTicketTypesByPerformanceID(id)
+	// 隐藏票种不在公开详情中展示
+	ticketTypes, err := service.NewAccessCodeService().GetVisibleTicketTypes(id, 0)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取票种信息失败"))
 		return
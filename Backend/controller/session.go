@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"net/http"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/service"
+	"ticket-system-backend/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionController struct{}
+
+// GetSessions 列出当前用户的登录设备
+func (sc *SessionController) GetSessions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	sessions, err := service.NewAuthService().ListSessions(userID.(int), c.GetString("sessionID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取登录设备失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(sessions))
+}
+
+// RevokeSession 注销指定的登录设备，注销当前设备等同于退出登录
+func (sc *SessionController) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	if err := service.NewAuthService().RevokeSession(userID.(int), c.Param("id")); err != nil {
+		if err == service.ErrSessionNotFound {
+			c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeNotFound, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "注销登录设备失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
+
+// RevokeOtherSessions 注销除当前设备以外的全部登录设备
+func (sc *SessionController) RevokeOtherSessions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	if err := service.NewAuthService().RevokeOtherSessions(userID.(int), c.GetString("sessionID")); err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "注销登录设备失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
+
+// sessionInfo 从请求中提取登录设备信息，未指定设备名称时根据 User-Agent 生成
+func sessionInfo(c *gin.Context, deviceName string) util.SessionInfo {
+	return util.SessionInfo{
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		DeviceName: deviceName,
+	}
+}
+
+// GetLoginHistory 当前用户最近 20 次登录记录（含失败的尝试）
+func (sc *SessionController) GetLoginHistory(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	histories, err := model.GetUserLoginHistory(userID.(int), 20)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取登录记录失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(histories))
+}
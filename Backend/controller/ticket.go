@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"ticket-system-backend/model"
+	"ticket-system-backend/service"
 	"ticket-system-backend/util"
 
 	"github.com/gin-gonic/gin"
@@ -32,7 +33,10 @@ func (tc *TicketController) GetTicketTypesByPerformanceID(c *gin.Context) {
 		return
 	}
 
-	ticketTypes, err := model.GetTicketTypesByPerformanceID(performanceID)
+	userID, _ := c.Get("userID")
+	currentUserID, _ := userID.(int)
+
+	ticketTypes, err := service.NewAccessCodeService().GetVisibleTicketTypes(performanceID, currentUserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取票种信息失败"))
 		return
@@ -96,8 +100,10 @@ func (tc *TicketController) SeckillTicket(c *gin.Context) {
 	}
 
 	var request struct {
-		TicketTypeId int `json:"ticketTypeId" binding:"required"`
-		Quantity     int `json:"quantity" binding:"required,min=1,max=5"`
+		TicketTypeId int    `json:"ticketTypeId" binding:"required"`
+		Quantity     int    `json:"quantity" binding:"required,min=1,max=5"`
+		AttendeeIds  []int  `json:"attendeeIds"`
+		CouponCode   string `json:"couponCode"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -105,97 +111,51 @@ func (tc *TicketController) SeckillTicket(c *gin.Context) {
 		return
 	}
 
-	cfg := util.GetConfig()
-	if request.Quantity > cfg.Seckill.MaxQuantityPerUser {
-		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeTicketLimitExceeded, "超过最大购买数量"))
-		return
-	}
-
-	ticketType, err := model.GetTicketTypeByID(request.TicketTypeId)
+	order, err := service.NewTicketService().Seckill(context.Background(), userID.(int), request.TicketTypeId, request.Quantity, request.AttendeeIds, request.CouponCode)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取票种信息失败"))
+		respondPlaceOrderError(c, err)
 		return
 	}
 
-	if ticketType == nil {
-		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeTicketNotExist, ""))
-		return
-	}
+	response := struct {
+		OrderId    int        `json:"order_id"`
+		OrderNo    string     `json:"order_no"`
+		Amount     util.Money `json:"amount"`
+		ExpireTime string     `json:"expire_time"`
+	}{order.ID, order.OrderNo, order.Amount, order.ExpireTime.Format(time.RFC3339)}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(response))
+}
 
-	if ticketType.Stock < request.Quantity {
-		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeTicketStockInsufficient, "库存不足"))
+func GenerateOrderNo() string {
+	return time.Now().Format("20060102150405") + strconv.FormatInt(time.Now().UnixNano()%1000000, 10)
+}
+
+func (tc *TicketController) RedeemAccessCode(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
 		return
 	}
 
-	performance, err := model.GetPerformanceByID(ticketType.PerformanceID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取演出信息失败"))
-		return
+	var request struct {
+		Code string `json:"code" binding:"required"`
 	}
 
-	if performance == nil {
-		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodePerformanceNotExist, ""))
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
 		return
 	}
 
-	ctx := context.Background()
-	lock, err := util.AcquireSeckillLock(ctx, request.TicketTypeId, 10*time.Second)
+	ticketType, err := service.NewAccessCodeService().Redeem(userID.(int), request.Code)
 	if err != nil {
-		if err == util.ErrLockAcquireFailed {
-			c.JSON(http.StatusServiceUnavailable, util.ErrorResponse(util.StatusCodeTicketSeckillFailed, "系统繁忙，请稍后重试"))
+		if err == service.ErrAccessCodeInvalid {
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeAccessCodeInvalid, ""))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取分布式锁失败"))
-		return
-	}
-	defer util.ReleaseSeckillLock(lock, ctx)
-
-	ticketType, err = model.GetTicketTypeByID(request.TicketTypeId)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取票种信息失败"))
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "兑换失败"))
 		return
 	}
 
-	if ticketType.Stock < request.Quantity {
-		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeTicketStockInsufficient, "库存不足"))
-		return
-	}
-
-	if err := model.DecreaseStock(request.TicketTypeId, request.Quantity); err != nil {
-		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeTicketSeckillFailed, "扣减库存失败"))
-		return
-	}
-
-	order := &model.Order{
-		OrderNo:       GenerateOrderNo(),
-		UserID:        userID.(int),
-		PerformanceID: ticketType.PerformanceID,
-		TicketTypeID:  request.TicketTypeId,
-		Quantity:      request.Quantity,
-		Amount:        ticketType.Price * float64(request.Quantity),
-		Status:        0,
-		ExpireTime:    time.Now().Add(time.Duration(cfg.Seckill.OrderExpireMinutes) * time.Minute),
-		PaymentTime:   nil,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
-
-	if err := model.CreateOrder(order); err != nil {
-		model.IncreaseStock(request.TicketTypeId, request.Quantity)
-		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "创建订单失败"))
-		return
-	}
-
-	response := struct {
-		OrderId    int     `json:"order_id"`
-		OrderNo    string  `json:"order_no"`
-		Amount     float64 `json:"amount"`
-		ExpireTime string  `json:"expire_time"`
-	}{order.ID, order.OrderNo, order.Amount, order.ExpireTime.Format(time.RFC3339)}
-
-	c.JSON(http.StatusOK, util.SuccessResponse(response))
-}
-
-func GenerateOrderNo() string {
-	return time.Now().Format("20060102150405") + strconv.FormatInt(time.Now().UnixNano()%1000000, 10)
+	c.JSON(http.StatusOK, util.SuccessResponse(ticketType))
 }
@@ -3,13 +3,16 @@ package controller
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"net/mail"
 	"os"
 	"path/filepath"
 	"strconv"
 	"time"
 
 	"ticket-system-backend/model"
+	"ticket-system-backend/service"
 	"ticket-system-backend/util"
 
 	"github.com/gin-gonic/gin"
@@ -36,6 +39,11 @@ func (uc *UserController) Register(c *gin.Context) {
 		return
 	}
 
+	if msg := validateContact(request.Phone, request.Email); msg != "" {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeUserInfoError, msg))
+		return
+	}
+
 	existingUser, _ := model.GetUserByUsername(request.Username)
 	if existingUser != nil {
 		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeUserExist, ""))
@@ -87,8 +95,9 @@ func (uc *UserController) Register(c *gin.Context) {
 
 func (uc *UserController) Login(c *gin.Context) {
 	var request struct {
-		Username string `json:"username" binding:"required"`
-		Password string `json:"password" binding:"required"`
+		Username   string `json:"username" binding:"required"`
+		Password   string `json:"password" binding:"required"`
+		DeviceName string `json:"device_name" binding:"max=50"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -96,35 +105,222 @@ func (uc *UserController) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := model.GetUserByUsername(request.Username)
+	user, tokens, err := service.NewAuthService().LoginWithPassword(request.Username, request.Password, sessionInfo(c, request.DeviceName))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUserPasswordError, ""))
+		var throttle *util.LoginThrottleError
+		switch {
+		case errors.As(err, &throttle):
+			respondLoginThrottled(c, throttle)
+		case err == service.ErrUserNotFound || err == service.ErrInvalidPassword:
+			c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUserPasswordError, ""))
+		case err == service.ErrUserDisabled:
+			c.JSON(http.StatusForbidden, util.ErrorResponse(util.StatusCodeForbidden, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "生成登录凭证失败"))
+		}
+		return
+	}
+
+	response := struct {
+		ID           int    `json:"id"`
+		Username     string `json:"username"`
+		Phone        string `json:"phone"`
+		Email        string `json:"email"`
+		Avatar       string `json:"avatar"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}{user.ID, user.Username, user.Phone, user.Email, user.Avatar, tokens.Token, tokens.RefreshToken, tokens.ExpiresIn}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(response))
+}
+
+// respondLoginThrottled 登录被限制时返回 429，并通过 Retry-After 告知可重试的时间
+func respondLoginThrottled(c *gin.Context, throttle *util.LoginThrottleError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttle.RetryAfter.Seconds()))))
+	code := util.StatusCodeTooManyRequests
+	if throttle.Locked {
+		code = util.StatusCodeAccountLocked
+	}
+	c.JSON(http.StatusTooManyRequests, util.ErrorResponse(code, throttle.Error()))
+}
+
+// SendLoginCode 向手机号或邮箱发送登录验证码
+func (uc *UserController) SendLoginCode(c *gin.Context) {
+	var request struct {
+		Target string `json:"target" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
 		return
 	}
 
-	if !util.CheckPassword(request.Password, user.Password) {
-		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUserPasswordError, ""))
+	if err := service.NewAuthService().SendLoginCode(request.Target, c.ClientIP()); err != nil {
+		switch err {
+		case service.ErrVerifyTargetInvalid:
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, err.Error()))
+		case util.ErrVerifyCodeTooFrequent:
+			c.JSON(http.StatusTooManyRequests, util.ErrorResponse(util.StatusCodeVerifyCodeTooFrequent, ""))
+		default:
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, service.ErrVerifyCodeSendFailed.Error()))
+		}
 		return
 	}
 
-	token, err := util.GenerateToken(user.ID, user.Username)
+	c.JSON(http.StatusOK, util.SuccessResponse(map[string]interface{}{
+		"expires_in": int(util.VerifyCodeExpiration.Seconds()),
+	}))
+}
+
+// CodeLogin 验证码登录，未注册的手机号自动注册
+func (uc *UserController) CodeLogin(c *gin.Context) {
+	var request struct {
+		Target     string `json:"target" binding:"required"`
+		Code       string `json:"code" binding:"required,len=6"`
+		DeviceName string `json:"device_name" binding:"max=50"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	user, tokens, created, err := service.NewAuthService().LoginWithCode(request.Target, request.Code, sessionInfo(c, request.DeviceName))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "生成登录凭证失败"))
+		switch err {
+		case service.ErrVerifyTargetInvalid:
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, err.Error()))
+		case util.ErrVerifyCodeInvalid, util.ErrVerifyCodeLocked:
+			c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeVerifyCodeInvalid, err.Error()))
+		case service.ErrUserDisabled:
+			c.JSON(http.StatusForbidden, util.ErrorResponse(util.StatusCodeForbidden, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "登录失败"))
+		}
 		return
 	}
 
 	response := struct {
-		ID       int    `json:"id"`
-		Username string `json:"username"`
-		Phone    string `json:"phone"`
-		Email    string `json:"email"`
-		Avatar   string `json:"avatar"`
-		Token    string `json:"token"`
-	}{user.ID, user.Username, user.Phone, user.Email, user.Avatar, token}
+		ID           int    `json:"id"`
+		Username     string `json:"username"`
+		Phone        string `json:"phone"`
+		Email        string `json:"email"`
+		Avatar       string `json:"avatar"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		NewUser      bool   `json:"new_user"`
+	}{user.ID, user.Username, user.Phone, user.Email, user.Avatar, tokens.Token, tokens.RefreshToken, tokens.ExpiresIn, created}
 
 	c.JSON(http.StatusOK, util.SuccessResponse(response))
 }
 
+// ForgotPassword 申请找回密码，无论账号是否存在都返回成功
+func (uc *UserController) ForgotPassword(c *gin.Context) {
+	var request struct {
+		Target string `json:"target" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	if err := service.NewAuthService().RequestPasswordReset(request.Target); err != nil {
+		switch err {
+		case service.ErrVerifyTargetInvalid:
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, err.Error()))
+		case service.ErrResetTooFrequent:
+			c.JSON(http.StatusTooManyRequests, util.ErrorResponse(util.StatusCodeTooManyRequests, ""))
+		default:
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "申请重置密码失败"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
+
+// ResetPassword 使用重置令牌设置新密码
+func (uc *UserController) ResetPassword(c *gin.Context) {
+	var request struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"newPassword" binding:"required,min=8,max=20"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	if valid, msg := util.ValidatePasswordStrength(request.NewPassword); !valid {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, msg))
+		return
+	}
+
+	if err := service.NewAuthService().ResetPassword(request.Token, request.NewPassword); err != nil {
+		if err == util.ErrResetTokenInvalid {
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeResetTokenInvalid, ""))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeUserPasswordChangeError, ""))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
+
+// RefreshToken 用刷新令牌换取新的访问令牌，旧刷新令牌随即失效
+func (uc *UserController) RefreshToken(c *gin.Context) {
+	var request struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	tokens, err := service.NewAuthService().Refresh(request.RefreshToken, c.ClientIP())
+	if err != nil {
+		switch err {
+		case util.ErrRefreshTokenInvalid, util.ErrRefreshTokenReused, service.ErrUserNotFound:
+			c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, err.Error()))
+		case service.ErrUserDisabled:
+			c.JSON(http.StatusForbidden, util.ErrorResponse(util.StatusCodeForbidden, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "刷新登录凭证失败"))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(tokens))
+}
+
+// Logout 退出登录，注销当前会话
+func (uc *UserController) Logout(c *gin.Context) {
+	var request struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	if err := service.NewAuthService().Logout(request.RefreshToken); err != nil {
+		if err == util.ErrRefreshTokenInvalid {
+			c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "退出登录失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
+
 func (uc *UserController) GetUserInfo(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -143,15 +339,17 @@ func (uc *UserController) GetUserInfo(c *gin.Context) {
 	}
 
 	response := struct {
-		ID        int       `json:"id"`
-		Username  string    `json:"username"`
-		Phone     string    `json:"phone"`
-		Email     string    `json:"email"`
-		Avatar    string    `json:"avatar"`
-		Status    int       `json:"status"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
-	}{user.ID, user.Username, user.Phone, user.Email, user.Avatar, user.Status, user.CreatedAt, user.UpdatedAt}
+		ID            int       `json:"id"`
+		Username      string    `json:"username"`
+		Phone         string    `json:"phone"`
+		Email         string    `json:"email"`
+		Avatar        string    `json:"avatar"`
+		Status        int       `json:"status"`
+		PhoneVerified bool      `json:"phone_verified"`
+		EmailVerified bool      `json:"email_verified"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
+	}{user.ID, user.Username, user.Phone, user.Email, user.Avatar, user.Status, user.PhoneVerified == 1, user.EmailVerified == 1, user.CreatedAt, user.UpdatedAt}
 
 	c.JSON(http.StatusOK, util.SuccessResponse(response))
 }
@@ -174,18 +372,27 @@ func (uc *UserController) UpdateUserInfo(c *gin.Context) {
 		return
 	}
 
+	if msg := validateContact(request.Phone, request.Email); msg != "" {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeUserInfoError, msg))
+		return
+	}
+
 	user, err := model.GetUserByID(userID.(int))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取用户信息失败"))
 		return
 	}
 
+	// 空值不会被更新，只有填写了新的联系方式才需要重新验证
+	phoneChanged := request.Phone != "" && request.Phone != user.Phone
+	emailChanged := request.Email != "" && request.Email != user.Email
+
 	user.Phone = request.Phone
 	user.Email = request.Email
 	user.Avatar = request.Avatar
 	user.UpdatedAt = time.Now()
 
-	if err := model.UpdateUser(user); err != nil {
+	if err := model.UpdateUserProfile(user, phoneChanged, emailChanged); err != nil {
 		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "更新用户信息失败"))
 		return
 	}
@@ -193,6 +400,89 @@ func (uc *UserController) UpdateUserInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, util.SuccessResponse(nil))
 }
 
+// SendContactCode 向当前填写的手机号或邮箱发送验证码
+func (uc *UserController) SendContactCode(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	var request struct {
+		Type string `json:"type" binding:"required,oneof=phone email"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	if err := service.NewUserService().SendContactCode(userID.(int), request.Type, c.ClientIP()); err != nil {
+		respondContactError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(map[string]interface{}{
+		"expires_in": int(util.VerifyCodeExpiration.Seconds()),
+	}))
+}
+
+// VerifyContact 提交验证码，验证手机号或邮箱
+func (uc *UserController) VerifyContact(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	var request struct {
+		Type string `json:"type" binding:"required,oneof=phone email"`
+		Code string `json:"code" binding:"required,len=6"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	if err := service.NewUserService().VerifyContact(userID.(int), request.Type, request.Code); err != nil {
+		respondContactError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
+
+func respondContactError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrContactTypeInvalid, service.ErrContactEmpty, service.ErrContactVerified, service.ErrVerifyTargetInvalid:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeUserInfoError, err.Error()))
+	case util.ErrVerifyCodeTooFrequent:
+		c.JSON(http.StatusTooManyRequests, util.ErrorResponse(util.StatusCodeVerifyCodeTooFrequent, ""))
+	case util.ErrVerifyCodeInvalid, util.ErrVerifyCodeLocked:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeVerifyCodeInvalid, err.Error()))
+	case service.ErrUserNotFound:
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, "用户不存在，请重新登录"))
+	case service.ErrVerifyCodeSendFailed:
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, err.Error()))
+	default:
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "验证失败"))
+	}
+}
+
+// validateContact 校验手机号和邮箱格式，为空表示未填写，返回错误提示
+func validateContact(phone, email string) string {
+	if phone != "" && !util.ValidatePhone(phone) {
+		return "手机号格式不正确"
+	}
+	if email != "" {
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			return "邮箱格式不正确"
+		}
+	}
+	return ""
+}
+
 func (uc *UserController) ChangePassword(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -240,7 +530,19 @@ func (uc *UserController) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+	// 修改密码后注销所有旧会话，并为当前设备签发新的登录凭证
+	authService := service.NewAuthService()
+	if err := authService.RevokeUserSessions(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "注销旧会话失败"))
+		return
+	}
+	tokens, err := authService.IssueTokens(user, sessionInfo(c, ""))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "生成登录凭证失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(tokens))
 }
 
 func (uc *UserController) GetPrivacySettings(c *gin.Context) {
@@ -335,6 +637,7 @@ func (uc *UserController) UpdatePrivacySettings(c *gin.Context) {
 	c.JSON(http.StatusOK, util.SuccessResponse(nil))
 }
 
+// ExportUserData 申请导出个人数据，文件由后台异步生成，生成后可在导出记录中下载
 func (uc *UserController) ExportUserData(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -342,13 +645,84 @@ func (uc *UserController) ExportUserData(c *gin.Context) {
 		return
 	}
 
-	expireTime := time.Now().Add(24 * time.Hour)
-	response := struct {
-		DownloadUrl string `json:"downloadUrl"`
-		ExpiresAt   string `json:"expiresAt"`
-	}{"/api/download/user-data/" + strconv.Itoa(userID.(int)), expireTime.Format(time.RFC3339)}
+	export, retryAfter, err := service.NewDataExportService().RequestExport(userID.(int))
+	if err != nil {
+		switch err {
+		case service.ErrDataExportTooFrequent:
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, util.ErrorResponse(util.StatusCodeTooManyRequests, err.Error()))
+		case service.ErrDataExportInProgress:
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeUserDataExportError, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeUserDataExportError, ""))
+		}
+		return
+	}
 
-	c.JSON(http.StatusOK, util.SuccessResponse(response))
+	c.JSON(http.StatusOK, util.SuccessResponse(userDataExportData(export)))
+}
+
+// GetUserDataExports 最近的导出记录，已生成的记录附带下载地址
+func (uc *UserController) GetUserDataExports(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	exports, err := service.NewDataExportService().List(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取导出记录失败"))
+		return
+	}
+
+	list := make([]gin.H, 0, len(exports))
+	for _, export := range exports {
+		list = append(list, userDataExportData(export))
+	}
+	c.JSON(http.StatusOK, util.SuccessResponse(list))
+}
+
+// DownloadUserData 下载导出文件，下载链接仅对导出者本人有效
+func (uc *UserController) DownloadUserData(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	exportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, ""))
+		return
+	}
+
+	export, err := service.NewDataExportService().Download(userID.(int), exportID, c.Query("token"))
+	if err != nil {
+		switch err {
+		case util.ErrDataExportTokenInvalid:
+			c.JSON(http.StatusForbidden, util.ErrorResponse(util.StatusCodeUserDataExportError, err.Error()))
+		case service.ErrDataExportNotFound, service.ErrDataExportNotReady:
+			c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeUserDataExportError, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeUserDataExportError, ""))
+		}
+		return
+	}
+
+	c.FileAttachment(export.FilePath, fmt.Sprintf("user_data_%s.zip", export.CreatedAt.Format("20060102150405")))
+}
+
+func userDataExportData(export *model.UserDataExport) gin.H {
+	return gin.H{
+		"id":           export.ID,
+		"status":       export.StatusName(),
+		"file_size":    export.FileSize,
+		"created_at":   export.CreatedAt,
+		"completed_at": export.CompletedAt,
+		"expires_at":   export.ExpiresAt,
+		"download_url": service.NewDataExportService().DownloadURL(export),
+	}
 }
 
 func (uc *UserController) UploadAvatar(c *gin.Context) {
@@ -411,6 +785,7 @@ func (uc *UserController) UploadAvatar(c *gin.Context) {
 	}))
 }
 
+// DeleteUserData 校验密码后申请注销账号，冷静期满后账号被匿名化
 func (uc *UserController) DeleteUserData(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -438,5 +813,53 @@ func (uc *UserController) DeleteUserData(c *gin.Context) {
 		return
 	}
 
+	deletion, err := service.NewAccountDeletionService().RequestDeletion(user)
+	if err != nil {
+		switch err {
+		case service.ErrDeletionActiveOrders, service.ErrDeletionPending:
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeUserDataDeleteError, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeUserDataDeleteError, ""))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(deletion))
+}
+
+// GetDeletionRequest 冷静期中的注销申请，没有申请时 data 为 null
+func (uc *UserController) GetDeletionRequest(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	deletion, err := service.NewAccountDeletionService().Pending(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取注销申请失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(deletion))
+}
+
+// CancelDeletionRequest 冷静期内撤销注销申请
+func (uc *UserController) CancelDeletionRequest(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	if err := service.NewAccountDeletionService().Cancel(userID.(int)); err != nil {
+		if err == service.ErrDeletionNotFound {
+			c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeUserDataDeleteError, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "撤销注销申请失败"))
+		return
+	}
+
 	c.JSON(http.StatusOK, util.SuccessResponse(nil))
 }
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"ticket-system-backend/service"
+	"ticket-system-backend/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WaitlistController struct{}
+
+func (wc *WaitlistController) GetEntries(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	entries, err := service.NewWaitlistService().GetEntries(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, "获取候补列表失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(entries))
+}
+
+func (wc *WaitlistController) Join(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	var request struct {
+		TicketTypeId int   `json:"ticketTypeId" binding:"required"`
+		Quantity     int   `json:"quantity" binding:"required,min=1"`
+		AttendeeIds  []int `json:"attendeeIds"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "请求参数错误"))
+		return
+	}
+
+	entry, err := service.NewWaitlistService().Join(userID.(int), request.TicketTypeId, request.Quantity, request.AttendeeIds)
+	if err != nil {
+		respondWaitlistError(c, err, "加入候补失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(entry))
+}
+
+func (wc *WaitlistController) Leave(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, ""))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBadRequest, "无效的候补ID"))
+		return
+	}
+
+	if err := service.NewWaitlistService().Leave(userID.(int), id); err != nil {
+		respondWaitlistError(c, err, "退出候补失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, util.SuccessResponse(nil))
+}
+
+func respondWaitlistError(c *gin.Context, err error, message string) {
+	switch err {
+	case service.ErrTicketTypeNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeTicketNotExist, ""))
+	case service.ErrPerformanceNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodePerformanceNotExist, ""))
+	case service.ErrBallotTicketType:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeBallotOnly, ""))
+	case service.ErrTicketLimitExceeded:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeTicketLimitExceeded, "超过最大购买数量"))
+	case service.ErrWaitlistNotSoldOut:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeWaitlistNotSoldOut, ""))
+	case service.ErrWaitlistEntryExists:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeWaitlistEntryExist, ""))
+	case service.ErrWaitlistEntryNotFound:
+		c.JSON(http.StatusNotFound, util.ErrorResponse(util.StatusCodeWaitlistEntryNotExist, ""))
+	case service.ErrWaitlistNotWaiting:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeWaitlistNotWaiting, ""))
+	case service.ErrRealNameRequired, service.ErrAttendeeCountMismatch, service.ErrAttendeeDuplicate:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeAttendeeInvalid, err.Error()))
+	case service.ErrAttendeeNotFound:
+		c.JSON(http.StatusBadRequest, util.ErrorResponse(util.StatusCodeAttendeeNotExist, ""))
+	default:
+		c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, message))
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"ticket-system-backend/router"
+	"ticket-system-backend/service"
 	"ticket-system-backend/util"
 )
 
@@ -19,12 +20,22 @@ func main() {
 		log.Fatalf("初始化配置失败: %v", err)
 	}
 
+	if err := util.InitJWTKeys(); err != nil {
+		log.Fatalf("加载JWT签名密钥失败: %v", err)
+	}
+
 	util.InitDB()
 	defer util.CloseDB()
 
 	util.InitRedis()
 	defer util.CloseRedis()
 
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	go service.StartOrderWorker(workerCtx)
+	go service.StartDataExportWorker(workerCtx)
+	go service.StartAccountDeletionWorker(workerCtx)
+
 	r := router.SetupRouter()
 
 	router.ServeStaticFiles(r)
@@ -53,6 +64,7 @@ func main() {
 	<-quit
 
 	fmt.Println("正在关闭服务器...")
+	stopWorker()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
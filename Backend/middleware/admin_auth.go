@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"ticket-system-backend/service"
 	"ticket-system-backend/util"
 
 	"github.com/gin-gonic/gin"
@@ -41,26 +42,25 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-func AdminRoleMiddleware(roles ...string) gin.HandlerFunc {
+// RequirePermission 要求当前管理员拥有指定权限，须在 AdminAuthMiddleware 之后使用。
+// 权限按管理员当前的角色实时判断，修改角色或禁用管理员后无需重新登录即生效
+func RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("admin_role")
+		adminID, exists := c.Get("admin_id")
 		if !exists {
 			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "无权限访问"})
 			c.Abort()
 			return
 		}
 
-		adminRole := role.(string)
-		hasPermission := false
-
-		for _, r := range roles {
-			if adminRole == r || adminRole == "super_admin" {
-				hasPermission = true
-				break
-			}
+		allowed, err := service.NewAdminRoleService().HasPermission(adminID.(int), permission)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "权限校验失败"})
+			c.Abort()
+			return
 		}
 
-		if !hasPermission {
+		if !allowed {
 			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "无权限访问此功能"})
 			c.Abort()
 			return
@@ -35,9 +35,23 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 会话已注销（退出登录、修改密码、账号被禁用）的令牌立即失效
+		active, err := util.SessionActive(claims.SessionID, claims.UserID, c.ClientIP())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, util.ErrorResponse(util.StatusCodeInternalError, ""))
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, util.ErrorResponse(util.StatusCodeUnauthorized, "登录已失效，请重新登录"))
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存储在上下文中
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("sessionID", claims.SessionID)
 
 		c.Next()
 	}
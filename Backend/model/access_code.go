@@ -0,0 +1,220 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// AccessCode 隐藏票种的兑换码，MaxUses 为可下单次数
+type AccessCode struct {
+	ID           int        `gorm:"primary_key;auto_increment" json:"id"`
+	TicketTypeID int        `gorm:"not null;index" json:"ticket_type_id"`
+	Code         string     `gorm:"size:32;not null;unique_index" json:"code"`
+	BatchNo      string     `gorm:"size:32;not null" json:"batch_no"`
+	MaxUses      int        `gorm:"not null" json:"max_uses"`
+	UsedCount    int        `gorm:"not null;default:0" json:"used_count"`
+	Status       int        `gorm:"type:tinyint;not null;default:1" json:"status"` // 0:已停用, 1:有效
+	ExpireTime   *time.Time `json:"expire_time,omitempty"`
+	CreatedBy    int        `gorm:"not null" json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (AccessCode) TableName() string {
+	return "access_code"
+}
+
+// Usable 兑换码是否有效且仍有剩余次数
+func (a *AccessCode) Usable(now time.Time) bool {
+	if a.Status != 1 || a.UsedCount >= a.MaxUses {
+		return false
+	}
+	return a.ExpireTime == nil || now.Before(*a.ExpireTime)
+}
+
+// AccessCodeUnlock 用户通过兑换码解锁的隐藏票种
+type AccessCodeUnlock struct {
+	ID           int       `gorm:"primary_key;auto_increment" json:"id"`
+	AccessCodeID int       `gorm:"not null" json:"access_code_id"`
+	UserID       int       `gorm:"not null" json:"user_id"`
+	TicketTypeID int       `gorm:"not null" json:"ticket_type_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (AccessCodeUnlock) TableName() string {
+	return "access_code_unlock"
+}
+
+// OrderAccessCode 订单使用兑换码的记录，订单关闭后释放
+type OrderAccessCode struct {
+	ID           int       `gorm:"primary_key;auto_increment" json:"id"`
+	OrderID      int       `gorm:"not null;index" json:"order_id"`
+	AccessCodeID int       `gorm:"not null" json:"access_code_id"`
+	UserID       int       `gorm:"not null" json:"user_id"`
+	TicketTypeID int       `gorm:"not null" json:"ticket_type_id"`
+	Quantity     int       `gorm:"not null" json:"quantity"`
+	Released     int       `gorm:"type:tinyint;not null;default:0" json:"released"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (OrderAccessCode) TableName() string {
+	return "order_access_code"
+}
+
+// AccessCodeQuery 兑换码查询条件
+type AccessCodeQuery struct {
+	TicketTypeID int
+	BatchNo      string
+	Page         int
+	Size         int
+}
+
+// GetAccessCodeByCode 根据兑换码获取
+func GetAccessCodeByCode(code string) (*AccessCode, error) {
+	var accessCode AccessCode
+	err := util.DB.Where("code = ?", code).First(&accessCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &accessCode, nil
+}
+
+// GetAccessCodes 分页获取票种的兑换码，Size 为 0 时不分页
+func GetAccessCodes(query AccessCodeQuery) ([]*AccessCode, int, error) {
+	var codes []*AccessCode
+	var total int
+
+	tx := util.DB.Model(&AccessCode{}).Where("ticket_type_id = ?", query.TicketTypeID)
+	if query.BatchNo != "" {
+		tx = tx.Where("batch_no = ?", query.BatchNo)
+	}
+	tx.Count(&total)
+
+	if query.Size > 0 {
+		page := query.Page
+		if page < 1 {
+			page = 1
+		}
+		tx = tx.Offset((page - 1) * query.Size).Limit(query.Size)
+	}
+	err := tx.Order("id asc").Find(&codes).Error
+	return codes, total, err
+}
+
+// CreateAccessCode 创建兑换码
+func CreateAccessCode(accessCode *AccessCode) error {
+	return util.DB.Create(accessCode).Error
+}
+
+// DisableAccessCode 停用兑换码
+func DisableAccessCode(id int) error {
+	return util.DB.Model(&AccessCode{}).Where("id = ?", id).Update("status", 0).Error
+}
+
+// GetAccessCodeByID 根据ID获取兑换码
+func GetAccessCodeByID(id int) (*AccessCode, error) {
+	var accessCode AccessCode
+	err := util.DB.Where("id = ?", id).First(&accessCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &accessCode, nil
+}
+
+// HasAccessCodeUnlock 用户是否已用该兑换码解锁
+func HasAccessCodeUnlock(accessCodeID, userID int) bool {
+	var count int
+	util.DB.Model(&AccessCodeUnlock{}).Where("access_code_id = ? AND user_id = ?", accessCodeID, userID).Count(&count)
+	return count > 0
+}
+
+// LockAccessCodeTx 在事务中锁定兑换码行，同一兑换码的解锁依次进行
+func LockAccessCodeTx(tx *gorm.DB, id int) (*AccessCode, error) {
+	var accessCode AccessCode
+	err := tx.Set("gorm:query_option", "FOR UPDATE").Where("id = ?", id).First(&accessCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &accessCode, nil
+}
+
+// HasAccessCodeUnlockTx 在事务中判断用户是否已用该兑换码解锁
+func HasAccessCodeUnlockTx(tx *gorm.DB, accessCodeID, userID int) (bool, error) {
+	var count int
+	err := tx.Model(&AccessCodeUnlock{}).Where("access_code_id = ? AND user_id = ?", accessCodeID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// CountAccessCodeUnlocksTx 在事务中统计兑换码已解锁的用户数，调用前须已锁定兑换码行
+func CountAccessCodeUnlocksTx(tx *gorm.DB, accessCodeID int) (int, error) {
+	var count int
+	err := tx.Model(&AccessCodeUnlock{}).Where("access_code_id = ?", accessCodeID).Count(&count).Error
+	return count, err
+}
+
+// CreateAccessCodeUnlockTx 在事务中记录解锁
+func CreateAccessCodeUnlockTx(tx *gorm.DB, unlock *AccessCodeUnlock) error {
+	return tx.Create(unlock).Error
+}
+
+// GetUnlockedTicketTypeIDs 获取用户已解锁的隐藏票种ID
+func GetUnlockedTicketTypeIDs(userID int) ([]int, error) {
+	var ids []int
+	err := util.DB.Model(&AccessCodeUnlock{}).Where("user_id = ?", userID).Pluck("DISTINCT ticket_type_id", &ids).Error
+	return ids, err
+}
+
+// HasUnlockedTicketType 用户是否已解锁该票种
+func HasUnlockedTicketType(userID, ticketTypeID int) bool {
+	var count int
+	util.DB.Model(&AccessCodeUnlock{}).Where("user_id = ? AND ticket_type_id = ?", userID, ticketTypeID).Count(&count)
+	return count > 0
+}
+
+// GetUserAccessCodesTx 在事务中获取用户解锁该票种所用的兑换码
+func GetUserAccessCodesTx(tx *gorm.DB, userID, ticketTypeID int) ([]*AccessCode, error) {
+	var codes []*AccessCode
+	err := tx.Table("access_code").Select("access_code.*").
+		Joins("JOIN access_code_unlock u ON u.access_code_id = access_code.id").
+		Where("u.user_id = ? AND u.ticket_type_id = ?", userID, ticketTypeID).
+		Order("access_code.id asc").Find(&codes).Error
+	return codes, err
+}
+
+// UseAccessCodeTx 在事务中占用一次兑换码，次数用尽、停用或过期时返回 false
+func UseAccessCodeTx(tx *gorm.DB, accessCodeID int) (bool, error) {
+	result := tx.Exec("UPDATE access_code SET used_count = used_count + 1 "+
+		"WHERE id = ? AND status = 1 AND used_count < max_uses AND (expire_time IS NULL OR expire_time > NOW())", accessCodeID)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// CreateOrderAccessCodeTx 在事务中记录订单使用的兑换码
+func CreateOrderAccessCodeTx(tx *gorm.DB, usage *OrderAccessCode) error {
+	return tx.Create(usage).Error
+}
+
+// ReleaseOrderAccessCodesTx 在关单事务中归还兑换码次数
+func ReleaseOrderAccessCodesTx(tx *gorm.DB, orderID int) error {
+	var usages []*OrderAccessCode
+	if err := tx.Where("order_id = ?", orderID).Find(&usages).Error; err != nil {
+		return err
+	}
+	for _, usage := range usages {
+		result := tx.Model(&OrderAccessCode{}).Where("id = ? AND released = 0", usage.ID).Update("released", 1)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if err := tx.Exec("UPDATE access_code SET used_count = used_count - 1 WHERE id = ? AND used_count > 0", usage.AccessCodeID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
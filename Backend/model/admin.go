@@ -7,18 +7,21 @@ import (
 )
 
 type Admin struct {
-	ID          int        `gorm:"primary_key;auto_increment" json:"id"`
-	Username    string     `gorm:"size:50;not null;unique" json:"username"`
-	Password    string     `gorm:"size:255;not null" json:"-"`
-	RealName    string     `gorm:"size:50;not null" json:"real_name"`
-	Email       string     `gorm:"size:100" json:"email"`
-	Phone       string     `gorm:"size:20" json:"phone"`
-	Role        string     `gorm:"size:20;not null;default:'admin'" json:"role"`
-	Status      int        `gorm:"default:1" json:"status"`
-	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
-	LastLoginIP string     `gorm:"size:50" json:"last_login_ip,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID            int        `gorm:"primary_key;auto_increment" json:"id"`
+	Username      string     `gorm:"size:50;not null;unique" json:"username"`
+	Password      string     `gorm:"size:255;not null" json:"-"`
+	RealName      string     `gorm:"size:50;not null" json:"real_name"`
+	Email         string     `gorm:"size:100" json:"email"`
+	Phone         string     `gorm:"size:20" json:"phone"`
+	Role          string     `gorm:"size:20;not null;default:'admin'" json:"role"`
+	Status        int        `gorm:"default:1" json:"status"`
+	LastLoginAt   *time.Time `json:"last_login_at,omitempty"`
+	LastLoginIP   string     `gorm:"size:50" json:"last_login_ip,omitempty"`
+	TOTPSecret    string     `gorm:"column:totp_secret;size:255" json:"-"`
+	TOTPEnabled   int        `gorm:"column:totp_enabled;default:0" json:"totp_enabled"`
+	TOTPEnabledAt *time.Time `gorm:"column:totp_enabled_at" json:"totp_enabled_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 func (Admin) TableName() string {
@@ -47,6 +50,7 @@ type AdminLoginHistory struct {
 	IP         string    `gorm:"size:50" json:"ip"`
 	UserAgent  string    `gorm:"size:255" json:"user_agent"`
 	Status     int       `gorm:"default:1" json:"status"`
+	Event      string    `gorm:"size:30;default:'password'" json:"event"`
 	FailReason string    `gorm:"size:100" json:"fail_reason,omitempty"`
 	CreatedAt  time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 }
@@ -117,6 +121,15 @@ func GetAdminByUsername(username string) (*Admin, error) {
 	return &admin, nil
 }
 
+func GetAdminByEmail(email string) (*Admin, error) {
+	var admin Admin
+	err := util.DB.Where("email = ?", email).First(&admin).Error
+	if err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
+
 func CreateAdmin(admin *Admin) error {
 	return util.DB.Create(admin).Error
 }
@@ -125,6 +138,14 @@ func UpdateAdmin(admin *Admin) error {
 	return util.DB.Model(admin).Updates(admin).Error
 }
 
+// UpdateAdminRoleStatus 修改管理员的角色和状态
+func UpdateAdminRoleStatus(adminID int, role string, status int) error {
+	return util.DB.Model(&Admin{}).Where("id = ?", adminID).Updates(map[string]interface{}{
+		"role":   role,
+		"status": status,
+	}).Error
+}
+
 func UpdateAdminLoginInfo(adminID int, ip string) error {
 	now := time.Now()
 	return util.DB.Model(&Admin{}).Where("id = ?", adminID).Updates(map[string]interface{}{
@@ -195,14 +216,21 @@ func CreateLoginHistory(history *AdminLoginHistory) error {
 	return util.DB.Create(history).Error
 }
 
+// GetAdminLoginHistory 获取管理员最近的登录记录
+func GetAdminLoginHistory(adminID, limit int) ([]*AdminLoginHistory, error) {
+	var histories []*AdminLoginHistory
+	err := util.DB.Where("admin_id = ?", adminID).Order("id desc").Limit(limit).Find(&histories).Error
+	return histories, err
+}
+
 type DashboardStats struct {
-	TodayOrders        int64   `json:"today_orders"`
-	TodayRevenue       float64 `json:"today_revenue"`
-	PendingOrders      int64   `json:"pending_orders"`
-	ActivePerformances int64   `json:"active_performances"`
-	TotalUsers         int64   `json:"total_users"`
-	WeekOrders         int64   `json:"week_orders"`
-	WeekRevenue        float64 `json:"week_revenue"`
+	TodayOrders        int64      `json:"today_orders"`
+	TodayRevenue       util.Money `json:"today_revenue"`
+	PendingOrders      int64      `json:"pending_orders"`
+	ActivePerformances int64      `json:"active_performances"`
+	TotalUsers         int64      `json:"total_users"`
+	WeekOrders         int64      `json:"week_orders"`
+	WeekRevenue        util.Money `json:"week_revenue"`
 }
 
 func GetDashboardStats() (*DashboardStats, error) {
@@ -224,10 +252,10 @@ func GetDashboardStats() (*DashboardStats, error) {
 }
 
 type SalesData struct {
-	Date    string  `json:"date"`
-	Orders  int     `json:"orders"`
-	Revenue float64 `json:"revenue"`
-	Tickets int     `json:"tickets"`
+	Date    string     `json:"date"`
+	Orders  int        `json:"orders"`
+	Revenue util.Money `json:"revenue"`
+	Tickets int        `json:"tickets"`
 }
 
 func GetSalesData(days int) ([]*SalesData, error) {
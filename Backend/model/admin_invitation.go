@@ -0,0 +1,148 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 管理员邀请状态，待接受的邀请超过有效期后视为已过期
+const (
+	AdminInvitationPending  = 0
+	AdminInvitationAccepted = 1
+	AdminInvitationRevoked  = 2
+)
+
+// AdminInvitation 超级管理员发出的管理员邀请，受邀人通过邮件中的链接设置账号和密码
+type AdminInvitation struct {
+	ID         int        `gorm:"primary_key;auto_increment" json:"id"`
+	Email      string     `gorm:"size:100;not null;index" json:"email"`
+	RealName   string     `gorm:"size:50" json:"real_name"`
+	Role       string     `gorm:"size:20;not null" json:"role"`
+	Nonce      string     `gorm:"size:64;not null" json:"-"`
+	Status     int        `gorm:"default:0" json:"status"`
+	InvitedBy  int        `gorm:"not null" json:"invited_by"`
+	AdminID    int        `gorm:"default:0" json:"admin_id,omitempty"` // 接受邀请后创建的管理员
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	RevokedBy  int        `gorm:"default:0" json:"revoked_by,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (AdminInvitation) TableName() string {
+	return "admin_invitation"
+}
+
+// Expired 待接受的邀请是否已超过有效期
+func (i *AdminInvitation) Expired() bool {
+	return i.Status == AdminInvitationPending && time.Now().After(i.ExpiresAt)
+}
+
+// StatusName 邀请状态的文字表示：pending/accepted/revoked/expired
+func (i *AdminInvitation) StatusName() string {
+	switch {
+	case i.Status == AdminInvitationAccepted:
+		return "accepted"
+	case i.Status == AdminInvitationRevoked:
+		return "revoked"
+	case i.Expired():
+		return "expired"
+	default:
+		return "pending"
+	}
+}
+
+type AdminInvitationQuery struct {
+	Status int // -1 为全部
+	Page   int
+	Size   int
+}
+
+func GetAdminInvitations(query AdminInvitationQuery) ([]*AdminInvitation, int, error) {
+	var invitations []*AdminInvitation
+	var total int
+
+	tx := util.DB.Model(&AdminInvitation{})
+	if query.Status >= 0 {
+		tx = tx.Where("status = ?", query.Status)
+	}
+	tx.Count(&total)
+
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+	size := query.Size
+	if size < 1 {
+		size = 10
+	}
+	offset := (page - 1) * size
+
+	err := tx.Offset(offset).Limit(size).Order("id desc").Find(&invitations).Error
+	return invitations, total, err
+}
+
+func GetAdminInvitationByID(id int) (*AdminInvitation, error) {
+	var invitation AdminInvitation
+	err := util.DB.Where("id = ?", id).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// HasPendingAdminInvitation 该邮箱是否有未过期的待接受邀请
+func HasPendingAdminInvitation(email string) (bool, error) {
+	var total int
+	err := util.DB.Model(&AdminInvitation{}).
+		Where("email = ? AND status = ? AND expires_at > ?", email, AdminInvitationPending, time.Now()).
+		Count(&total).Error
+	return total > 0, err
+}
+
+func CreateAdminInvitation(invitation *AdminInvitation) error {
+	return util.DB.Create(invitation).Error
+}
+
+// RevokeAdminInvitation 撤销待接受的邀请，返回是否撤销成功
+func RevokeAdminInvitation(id, revokedBy int) (bool, error) {
+	result := util.DB.Model(&AdminInvitation{}).
+		Where("id = ? AND status = ?", id, AdminInvitationPending).
+		Updates(map[string]interface{}{
+			"status":     AdminInvitationRevoked,
+			"revoked_by": revokedBy,
+			"revoked_at": time.Now(),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// AcceptAdminInvitation 在同一事务中创建管理员并将邀请标记为已接受。
+// 邀请已被接受、撤销、过期或随机数不一致时返回 gorm.ErrRecordNotFound
+func AcceptAdminInvitation(invitation *AdminInvitation, admin *Admin) error {
+	tx := util.DB.Begin()
+	if err := tx.Create(admin).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	result := tx.Model(&AdminInvitation{}).
+		Where("id = ? AND nonce = ? AND status = ? AND expires_at > ?", invitation.ID, invitation.Nonce, AdminInvitationPending, time.Now()).
+		Updates(map[string]interface{}{
+			"status":      AdminInvitationAccepted,
+			"admin_id":    admin.ID,
+			"accepted_at": time.Now(),
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+	return tx.Commit().Error
+}
@@ -0,0 +1,185 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"ticket-system-backend/util"
+)
+
+// RoleSuperAdmin 超级管理员，固定拥有全部权限，不受角色表中的权限配置影响
+const RoleSuperAdmin = "super_admin"
+
+// 管理端权限
+const (
+	PermissionDashboardRead    = "dashboard.read"
+	PermissionAdminRead        = "admin.read"
+	PermissionAdminWrite       = "admin.write"
+	PermissionAdminSecurity    = "admin.security"
+	PermissionAdminInvite      = "admin.invite"
+	PermissionRoleManage       = "role.manage"
+	PermissionUserRead         = "user.read"
+	PermissionUserWrite        = "user.write"
+	PermissionUserDelete       = "user.delete"
+	PermissionOrderRead        = "order.read"
+	PermissionOrderRefund      = "order.refund"
+	PermissionOrderExport      = "order.export"
+	PermissionPerformanceRead  = "performance.read"
+	PermissionPerformanceWrite = "performance.write"
+	PermissionCategoryWrite    = "category.write"
+	PermissionTicketTypeRead   = "ticket_type.read"
+	PermissionTicketTypeWrite  = "ticket_type.write"
+	PermissionTicketCheckIn    = "ticket.check_in"
+	PermissionCouponRead       = "coupon.read"
+	PermissionCouponWrite      = "coupon.write"
+	PermissionFeePolicyRead    = "fee_policy.read"
+	PermissionFeePolicyWrite   = "fee_policy.write"
+	PermissionInvoiceRead      = "invoice.read"
+	PermissionConfigRead       = "config.read"
+	PermissionConfigWrite      = "config.write"
+	PermissionLogRead          = "log.read"
+)
+
+// AdminPermission 权限目录中的一项。SuperAdminOnly 的权限只属于超级管理员，不能分配给其他角色
+type AdminPermission struct {
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	Group          string `json:"group"`
+	SuperAdminOnly bool   `json:"super_admin_only"`
+}
+
+// AdminPermissions 权限目录，新增管理接口时在此登记对应的权限
+var AdminPermissions = []AdminPermission{
+	{Code: PermissionDashboardRead, Name: "查看数据看板", Group: "看板"},
+	{Code: PermissionAdminRead, Name: "查看管理员", Group: "管理员"},
+	{Code: PermissionAdminWrite, Name: "编辑管理员资料、状态和角色", Group: "管理员"},
+	{Code: PermissionAdminSecurity, Name: "重置两步验证、解除管理员锁定、设置两步验证策略", Group: "管理员", SuperAdminOnly: true},
+	{Code: PermissionAdminInvite, Name: "邀请管理员、撤销邀请", Group: "管理员", SuperAdminOnly: true},
+	{Code: PermissionRoleManage, Name: "管理角色和权限", Group: "管理员", SuperAdminOnly: true},
+	{Code: PermissionUserRead, Name: "查看用户", Group: "用户"},
+	{Code: PermissionUserWrite, Name: "启用/禁用用户、解除登录锁定", Group: "用户"},
+	{Code: PermissionUserDelete, Name: "删除用户", Group: "用户"},
+	{Code: PermissionOrderRead, Name: "查看订单", Group: "订单"},
+	{Code: PermissionOrderRefund, Name: "订单退款", Group: "订单"},
+	{Code: PermissionOrderExport, Name: "导出订单", Group: "订单"},
+	{Code: PermissionPerformanceRead, Name: "查看演出和分类", Group: "演出"},
+	{Code: PermissionPerformanceWrite, Name: "创建、编辑、删除演出", Group: "演出"},
+	{Code: PermissionCategoryWrite, Name: "管理演出分类", Group: "演出"},
+	{Code: PermissionTicketTypeRead, Name: "查看票种、抽签、兑换码和调价规则", Group: "票务"},
+	{Code: PermissionTicketTypeWrite, Name: "管理票种、库存、抽签、兑换码和调价规则", Group: "票务"},
+	{Code: PermissionTicketCheckIn, Name: "查询电子票、验票入场", Group: "票务"},
+	{Code: PermissionCouponRead, Name: "查看优惠券", Group: "营销"},
+	{Code: PermissionCouponWrite, Name: "管理优惠券和券码", Group: "营销"},
+	{Code: PermissionFeePolicyRead, Name: "查看服务费和税费策略", Group: "财务"},
+	{Code: PermissionFeePolicyWrite, Name: "管理服务费和税费策略", Group: "财务"},
+	{Code: PermissionInvoiceRead, Name: "查看和下载发票", Group: "财务"},
+	{Code: PermissionConfigRead, Name: "查看系统配置", Group: "系统"},
+	{Code: PermissionConfigWrite, Name: "修改系统配置", Group: "系统"},
+	{Code: PermissionLogRead, Name: "查看操作日志", Group: "系统"},
+}
+
+// GetAdminPermission 按编码查找权限目录中的权限
+func GetAdminPermission(code string) (AdminPermission, bool) {
+	for _, permission := range AdminPermissions {
+		if permission.Code == code {
+			return permission, true
+		}
+	}
+	return AdminPermission{}, false
+}
+
+// AdminRole 管理员角色，权限以逗号分隔保存
+type AdminRole struct {
+	ID          int       `gorm:"primary_key;auto_increment" json:"id"`
+	Name        string    `gorm:"size:20;not null;unique" json:"name"`
+	DisplayName string    `gorm:"size:50;not null" json:"display_name"`
+	Description string    `gorm:"size:255" json:"description"`
+	Permissions string    `gorm:"type:text" json:"-"`
+	BuiltIn     int       `gorm:"default:0" json:"built_in"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (AdminRole) TableName() string {
+	return "admin_role"
+}
+
+// PermissionList 角色拥有的权限编码
+func (r *AdminRole) PermissionList() []string {
+	if r.Permissions == "" {
+		return []string{}
+	}
+	return strings.Split(r.Permissions, ",")
+}
+
+func GetAdminRoles() ([]*AdminRole, error) {
+	var roles []*AdminRole
+	err := util.DB.Order("built_in desc, id asc").Find(&roles).Error
+	return roles, err
+}
+
+func GetAdminRoleByID(id int) (*AdminRole, error) {
+	var role AdminRole
+	err := util.DB.Where("id = ?", id).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func GetAdminRoleByName(name string) (*AdminRole, error) {
+	var role AdminRole
+	err := util.DB.Where("name = ?", name).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func CreateAdminRole(role *AdminRole) error {
+	return util.DB.Create(role).Error
+}
+
+// UpdateAdminRole 修改角色的名称、说明和权限，权限为空时同样写入
+func UpdateAdminRole(role *AdminRole) error {
+	return util.DB.Model(&AdminRole{}).Where("id = ?", role.ID).Updates(map[string]interface{}{
+		"display_name": role.DisplayName,
+		"description":  role.Description,
+		"permissions":  role.Permissions,
+	}).Error
+}
+
+func DeleteAdminRole(id int) error {
+	return util.DB.Where("id = ?", id).Delete(&AdminRole{}).Error
+}
+
+// CountAdminsByRole 各角色的管理员人数
+func CountAdminsByRole() (map[string]int, error) {
+	var rows []struct {
+		Role  string
+		Total int
+	}
+	err := util.DB.Model(&Admin{}).Select("role, COUNT(*) AS total").Group("role").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Role] = row.Total
+	}
+	return counts, nil
+}
+
+// GetAdminIDsByRole 属于该角色的管理员 ID
+func GetAdminIDsByRole(role string) ([]int, error) {
+	var ids []int
+	err := util.DB.Model(&Admin{}).Where("role = ?", role).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// CountActiveSuperAdmins 启用状态的超级管理员人数
+func CountActiveSuperAdmins() (int, error) {
+	var total int
+	err := util.DB.Model(&Admin{}).Where("role = ? AND status = 1", RoleSuperAdmin).Count(&total).Error
+	return total, err
+}
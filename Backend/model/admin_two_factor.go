@@ -0,0 +1,115 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 管理员登录历史中的事件类型
+const (
+	LoginEventPassword         = "password"
+	LoginEventTwoFactorVerify  = "2fa_verify"
+	LoginEventRecoveryCode     = "2fa_recovery_code"
+	LoginEventTwoFactorEnable  = "2fa_enable"
+	LoginEventTwoFactorDisable = "2fa_disable"
+	LoginEventTwoFactorReset   = "2fa_reset"
+	LoginEventRecoveryRenew    = "2fa_recovery_renew"
+)
+
+// SystemConfigAdminRequire2FA 为 true 时所有管理员必须启用两步验证才能登录
+const SystemConfigAdminRequire2FA = "admin_require_2fa"
+
+// AdminRecoveryCode 两步验证恢复码，只保存哈希，每个恢复码只能使用一次
+type AdminRecoveryCode struct {
+	ID        int        `gorm:"primary_key;auto_increment" json:"id"`
+	AdminID   int        `gorm:"not null" json:"admin_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (AdminRecoveryCode) TableName() string {
+	return "admin_recovery_code"
+}
+
+// SetAdminTOTPSecret 保存待确认的验证器密钥（已加密），确认前两步验证不生效
+func SetAdminTOTPSecret(adminID int, encryptedSecret string) error {
+	return util.DB.Model(&Admin{}).Where("id = ? AND totp_enabled = 0", adminID).
+		Update("totp_secret", encryptedSecret).Error
+}
+
+// EnableAdminTOTP 启用两步验证并写入新的恢复码
+func EnableAdminTOTP(adminID int, codeHashes []string) error {
+	tx := util.DB.Begin()
+	if err := tx.Model(&Admin{}).Where("id = ?", adminID).Updates(map[string]interface{}{
+		"totp_enabled":    1,
+		"totp_enabled_at": time.Now(),
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := replaceAdminRecoveryCodes(tx, adminID, codeHashes); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// DisableAdminTOTP 关闭两步验证，清除密钥和恢复码
+func DisableAdminTOTP(adminID int) error {
+	tx := util.DB.Begin()
+	if err := tx.Model(&Admin{}).Where("id = ?", adminID).Updates(map[string]interface{}{
+		"totp_secret":     "",
+		"totp_enabled":    0,
+		"totp_enabled_at": nil,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("admin_id = ?", adminID).Delete(&AdminRecoveryCode{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// ReplaceAdminRecoveryCodes 重新生成恢复码，旧的恢复码全部作废
+func ReplaceAdminRecoveryCodes(adminID int, codeHashes []string) error {
+	tx := util.DB.Begin()
+	if err := replaceAdminRecoveryCodes(tx, adminID, codeHashes); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func replaceAdminRecoveryCodes(tx *gorm.DB, adminID int, codeHashes []string) error {
+	if err := tx.Where("admin_id = ?", adminID).Delete(&AdminRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, hash := range codeHashes {
+		if err := tx.Create(&AdminRecoveryCode{AdminID: adminID, CodeHash: hash, CreatedAt: now}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseAdminRecoveryCode 核销恢复码，并发请求中只有一个能成功
+func UseAdminRecoveryCode(adminID int, codeHash string) (bool, error) {
+	result := util.DB.Model(&AdminRecoveryCode{}).
+		Where("admin_id = ? AND code_hash = ? AND used_at IS NULL", adminID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// CountAdminRecoveryCodes 统计未使用的恢复码数量
+func CountAdminRecoveryCodes(adminID int) (int, error) {
+	var count int
+	err := util.DB.Model(&AdminRecoveryCode{}).Where("admin_id = ? AND used_at IS NULL", adminID).Count(&count).Error
+	return count, err
+}
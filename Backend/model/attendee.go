@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+)
+
+// Attendee 实名观演人，证件号加密存储
+type Attendee struct {
+	ID             int       `gorm:"primary_key;auto_increment" json:"id"`
+	UserID         int       `gorm:"not null" json:"user_id"`
+	Name           string    `gorm:"size:50;not null" json:"name"`
+	IDNumberEnc    string    `gorm:"column:id_number_enc;size:255;not null" json:"-"`
+	IDNumberHash   string    `gorm:"column:id_number_hash;size:64;not null" json:"-"`
+	IDNumberMasked string    `gorm:"column:id_number_masked;size:30;not null" json:"id_number"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (Attendee) TableName() string {
+	return "attendee"
+}
+
+// GetUserAttendees 获取用户的观演人列表
+func GetUserAttendees(userID int) ([]*Attendee, error) {
+	var attendees []*Attendee
+	err := util.DB.Where("user_id = ?", userID).Order("id asc").Find(&attendees).Error
+	return attendees, err
+}
+
+// GetAttendeesByIDs 批量获取观演人
+func GetAttendeesByIDs(ids []int) ([]*Attendee, error) {
+	var attendees []*Attendee
+	err := util.DB.Where("id IN (?)", ids).Find(&attendees).Error
+	return attendees, err
+}
+
+// GetAttendeeByID 根据ID获取观演人
+func GetAttendeeByID(id int) (*Attendee, error) {
+	var attendee Attendee
+	err := util.DB.Where("id = ?", id).First(&attendee).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attendee, nil
+}
+
+// HasUserAttendee 检查用户是否已添加该证件号的观演人
+func HasUserAttendee(userID int, idNumberHash string) bool {
+	var count int
+	util.DB.Model(&Attendee{}).Where("user_id = ? AND id_number_hash = ?", userID, idNumberHash).Count(&count)
+	return count > 0
+}
+
+// CreateAttendee 创建观演人
+func CreateAttendee(attendee *Attendee) error {
+	return util.DB.Create(attendee).Error
+}
+
+// DeleteAttendee 删除观演人
+func DeleteAttendee(id int) error {
+	return util.DB.Delete(&Attendee{}, "id = ?", id).Error
+}
@@ -0,0 +1,219 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 票种销售方式
+const (
+	SaleModeSeckill = 0 // 先到先得
+	SaleModeBallot  = 1 // 抽签
+)
+
+// 抽签登记状态
+const (
+	BallotEntryPending = 0 // 待开奖
+	BallotEntryWon     = 1 // 已中签
+	BallotEntryLost    = 2 // 未中签
+	BallotEntryLapsed  = 3 // 中签后逾期未支付
+)
+
+// 抽签批次状态
+const (
+	BallotDrawDrawn     = 0 // 已抽签，未公布
+	BallotDrawPublished = 1 // 已公布
+)
+
+// BallotEntry 抽签登记，每个用户每个票种一条
+type BallotEntry struct {
+	ID           int       `gorm:"primary_key;auto_increment" json:"id"`
+	TicketTypeID int       `gorm:"not null" json:"ticket_type_id"`
+	UserID       int       `gorm:"not null" json:"user_id"`
+	Quantity     int       `gorm:"not null" json:"quantity"`
+	AttendeeIDs  string    `gorm:"column:attendee_ids;size:255" json:"-"` // 逗号分隔的观演人ID
+	Status       int       `gorm:"type:tinyint;not null" json:"status"`
+	DrawRound    int       `gorm:"not null;default:0" json:"-"` // 中签轮次，公布前不对用户展示
+	OrderID      int       `gorm:"not null;default:0" json:"order_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	TicketType *TicketType `gorm:"foreignkey:TicketTypeID" json:"ticket_type,omitempty"`
+}
+
+func (BallotEntry) TableName() string {
+	return "ballot_entry"
+}
+
+// BallotDraw 抽签批次，记录种子以便复现抽签结果
+type BallotDraw struct {
+	ID              int        `gorm:"primary_key;auto_increment" json:"id"`
+	TicketTypeID    int        `gorm:"not null" json:"ticket_type_id"`
+	Round           int        `gorm:"not null" json:"round"`
+	Seed            string     `gorm:"size:64;not null" json:"seed"`
+	Stock           int        `gorm:"not null" json:"stock"`
+	EntryCount      int        `gorm:"not null" json:"entry_count"`
+	WinnerCount     int        `gorm:"not null" json:"winner_count"`
+	WinnerQuantity  int        `gorm:"not null" json:"winner_quantity"`
+	Status          int        `gorm:"type:tinyint;not null" json:"status"`
+	PaymentDeadline *time.Time `json:"payment_deadline,omitempty"`
+	PublishedAt     *time.Time `json:"published_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+func (BallotDraw) TableName() string {
+	return "ballot_draw"
+}
+
+// GetBallotEntry 获取用户在某票种的抽签登记
+func GetBallotEntry(ticketTypeID, userID int) (*BallotEntry, error) {
+	var entry BallotEntry
+	err := util.DB.Where("ticket_type_id = ? AND user_id = ?", ticketTypeID, userID).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetUserBallotEntries 获取用户的抽签登记列表
+func GetUserBallotEntries(userID int) ([]*BallotEntry, error) {
+	var entries []*BallotEntry
+	err := util.DB.Where("user_id = ?", userID).Preload("TicketType").Order("id desc").Find(&entries).Error
+	return entries, err
+}
+
+// GetBallotEntriesByStatus 获取票种下指定状态的登记，按ID排序保证抽签输入稳定
+func GetBallotEntriesByStatus(ticketTypeID, status int) ([]*BallotEntry, error) {
+	var entries []*BallotEntry
+	err := util.DB.Where("ticket_type_id = ? AND status = ?", ticketTypeID, status).Order("id asc").Find(&entries).Error
+	return entries, err
+}
+
+// GetBallotWinners 获取某轮中签但尚未下单的登记
+func GetBallotWinners(ticketTypeID, round int) ([]*BallotEntry, error) {
+	var entries []*BallotEntry
+	err := util.DB.Where("ticket_type_id = ? AND draw_round = ? AND status = ?", ticketTypeID, round, BallotEntryPending).
+		Order("id asc").Find(&entries).Error
+	return entries, err
+}
+
+// CountBallotEntries 按状态统计票种的抽签登记数量
+func CountBallotEntries(ticketTypeID int) (map[int]int, error) {
+	var rows []struct {
+		Status int
+		Count  int
+	}
+	err := util.DB.Model(&BallotEntry{}).Select("status, COUNT(*) AS count").
+		Where("ticket_type_id = ?", ticketTypeID).Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// CreateBallotEntry 创建抽签登记
+func CreateBallotEntry(entry *BallotEntry) error {
+	return util.DB.Create(entry).Error
+}
+
+// DeleteBallotEntry 撤销待开奖的抽签登记
+func DeleteBallotEntry(id int) error {
+	return util.DB.Where("id = ? AND status = ?", id, BallotEntryPending).Delete(&BallotEntry{}).Error
+}
+
+// ResetBallotEntriesTx 在事务中将票种下指定状态的登记重置为待开奖，供下一轮抽签使用
+func ResetBallotEntriesTx(tx *gorm.DB, ticketTypeID, status int) error {
+	return tx.Model(&BallotEntry{}).Where("ticket_type_id = ? AND status = ?", ticketTypeID, status).Updates(map[string]interface{}{
+		"status":     BallotEntryPending,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// MarkBallotWinnersTx 在事务中标记某轮中签的登记
+func MarkBallotWinnersTx(tx *gorm.DB, ids []int, round int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Model(&BallotEntry{}).Where("id IN (?)", ids).Updates(map[string]interface{}{
+		"draw_round": round,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// UpdateBallotEntryResult 更新登记结果
+func UpdateBallotEntryResult(id, status, orderID int) error {
+	return util.DB.Model(&BallotEntry{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     status,
+		"order_id":   orderID,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// MarkBallotEntryWonTx 在下单事务中将待公布的中签登记标记为已中签并关联订单，
+// 返回是否由本次调用完成标记，防止重复公布时为同一登记重复下单
+func MarkBallotEntryWonTx(tx *gorm.DB, id, orderID int) (bool, error) {
+	result := tx.Model(&BallotEntry{}).Where("id = ? AND status = ?", id, BallotEntryPending).Updates(map[string]interface{}{
+		"status":     BallotEntryWon,
+		"order_id":   orderID,
+		"updated_at": time.Now(),
+	})
+	return result.RowsAffected == 1, result.Error
+}
+
+// MarkBallotLosers 公布结果时将本轮未中签的登记标记为未中签
+func MarkBallotLosers(ticketTypeID int) error {
+	return util.DB.Model(&BallotEntry{}).Where("ticket_type_id = ? AND status = ?", ticketTypeID, BallotEntryPending).Updates(map[string]interface{}{
+		"status":     BallotEntryLost,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// GetUnpaidBallotWinners 获取中签后订单未支付的登记
+func GetUnpaidBallotWinners(ticketTypeID int) ([]*BallotEntry, error) {
+	var entries []*BallotEntry
+	err := util.DB.Table("ballot_entry").Select("ballot_entry.*").
+		Joins("JOIN `order` o ON o.id = ballot_entry.order_id").
+		Where("ballot_entry.ticket_type_id = ? AND ballot_entry.status = ? AND o.status <> 1", ticketTypeID, BallotEntryWon).
+		Find(&entries).Error
+	return entries, err
+}
+
+// GetBallotDraws 获取票种的抽签批次
+func GetBallotDraws(ticketTypeID int) ([]*BallotDraw, error) {
+	var draws []*BallotDraw
+	err := util.DB.Where("ticket_type_id = ?", ticketTypeID).Order("round asc").Find(&draws).Error
+	return draws, err
+}
+
+// GetLatestBallotDraw 获取票种最近一轮抽签
+func GetLatestBallotDraw(ticketTypeID int) (*BallotDraw, error) {
+	var draw BallotDraw
+	err := util.DB.Where("ticket_type_id = ?", ticketTypeID).Order("round desc").First(&draw).Error
+	if err != nil {
+		return nil, err
+	}
+	return &draw, nil
+}
+
+// CreateBallotDrawTx 在事务中创建抽签批次，(ticket_type_id, round) 唯一，防止重复抽签
+func CreateBallotDrawTx(tx *gorm.DB, draw *BallotDraw) error {
+	return tx.Create(draw).Error
+}
+
+// PublishBallotDraw 将抽签批次标记为已公布，返回是否由本次调用完成标记
+func PublishBallotDraw(id int, deadline time.Time) (bool, error) {
+	now := time.Now()
+	result := util.DB.Model(&BallotDraw{}).Where("id = ? AND status = ?", id, BallotDrawDrawn).Updates(map[string]interface{}{
+		"status":           BallotDrawPublished,
+		"payment_deadline": &deadline,
+		"published_at":     &now,
+	})
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+)
+
+// CalendarFeed 用户的私有日历订阅，只保存令牌的哈希，重新生成即吊销旧链接
+type CalendarFeed struct {
+	ID         int        `gorm:"primary_key;auto_increment" json:"id"`
+	UserID     int        `gorm:"not null;unique_index" json:"user_id"`
+	TokenHash  string     `gorm:"size:64;not null;unique_index" json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (CalendarFeed) TableName() string {
+	return "calendar_feed"
+}
+
+// GetCalendarFeedByUserID 获取用户的日历订阅
+func GetCalendarFeedByUserID(userID int) (*CalendarFeed, error) {
+	var feed CalendarFeed
+	err := util.DB.Where("user_id = ?", userID).First(&feed).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// GetCalendarFeedByTokenHash 根据令牌哈希获取日历订阅
+func GetCalendarFeedByTokenHash(tokenHash string) (*CalendarFeed, error) {
+	var feed CalendarFeed
+	err := util.DB.Where("token_hash = ?", tokenHash).First(&feed).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// SaveCalendarFeed 创建或替换用户的日历订阅令牌
+func SaveCalendarFeed(userID int, tokenHash string) (*CalendarFeed, error) {
+	feed := &CalendarFeed{UserID: userID, TokenHash: tokenHash, CreatedAt: time.Now()}
+	tx := util.DB.Begin()
+	if err := tx.Where("user_id = ?", userID).Delete(&CalendarFeed{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Create(feed).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return feed, tx.Commit().Error
+}
+
+// DeleteCalendarFeed 吊销用户的日历订阅
+func DeleteCalendarFeed(userID int) error {
+	return util.DB.Where("user_id = ?", userID).Delete(&CalendarFeed{}).Error
+}
+
+// TouchCalendarFeed 记录订阅最近一次被拉取的时间
+func TouchCalendarFeed(id int) error {
+	return util.DB.Model(&CalendarFeed{}).Where("id = ?", id).Update("last_used_at", time.Now()).Error
+}
+
+// GetUserUpcomingPaidOrders 获取用户尚未结束的演出的已支付订单
+func GetUserUpcomingPaidOrders(userID int, now time.Time) ([]*Order, error) {
+	var orders []*Order
+	err := util.DB.Joins("JOIN performance ON performance.id = `order`.performance_id").
+		Where("`order`.user_id = ? AND `order`.status = 1 AND performance.end_time > ?", userID, now).
+		Preload("Performance").Preload("Items.TicketType").Preload("TicketType").
+		Order("performance.start_time asc").Find(&orders).Error
+	return orders, err
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// CartItem 购物车条目，同一用户同一票种只保留一条
+type CartItem struct {
+	ID           int       `gorm:"primary_key;auto_increment" json:"id"`
+	UserID       int       `gorm:"not null" json:"user_id"`
+	TicketTypeID int       `gorm:"not null" json:"ticket_type_id"`
+	Quantity     int       `gorm:"not null" json:"quantity"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	TicketType *TicketType `gorm:"foreignkey:TicketTypeID" json:"ticket_type,omitempty"`
+}
+
+func (CartItem) TableName() string {
+	return "cart_item"
+}
+
+// GetCartItems 获取用户购物车
+func GetCartItems(userID int) ([]*CartItem, error) {
+	var items []*CartItem
+	err := util.DB.Where("user_id = ?", userID).Preload("TicketType").Order("id asc").Find(&items).Error
+	return items, err
+}
+
+// GetCartItemByID 根据ID获取购物车条目
+func GetCartItemByID(id int) (*CartItem, error) {
+	var item CartItem
+	err := util.DB.Where("id = ?", id).Preload("TicketType").First(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetCartItemByTicketType 获取用户购物车中某票种的条目
+func GetCartItemByTicketType(userID, ticketTypeID int) (*CartItem, error) {
+	var item CartItem
+	err := util.DB.Where("user_id = ? AND ticket_type_id = ?", userID, ticketTypeID).First(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// CreateCartItem 添加购物车条目
+func CreateCartItem(item *CartItem) error {
+	return util.DB.Create(item).Error
+}
+
+// UpdateCartItemQuantity 修改购物车条目数量
+func UpdateCartItemQuantity(id, quantity int) error {
+	return util.DB.Model(&CartItem{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"quantity":   quantity,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// DeleteCartItem 删除购物车条目
+func DeleteCartItem(id int) error {
+	return util.DB.Delete(&CartItem{}, "id = ?", id).Error
+}
+
+// DeleteCartItemsTx 在下单事务中移除已结算的购物车条目
+func DeleteCartItemsTx(tx *gorm.DB, userID int, ids []int) error {
+	return tx.Where("user_id = ? AND id IN (?)", userID, ids).Delete(&CartItem{}).Error
+}
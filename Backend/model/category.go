@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
 )
 
 type Category = PerformanceCategory
@@ -73,6 +75,24 @@ func GetAllSystemConfigs() ([]*SystemConfig, error) {
 	return configs, err
 }
 
+// GetSystemConfigValue 获取单项系统配置的值，不存在时返回空字符串
+func GetSystemConfigValue(key string) (string, error) {
+	var config SystemConfig
+	err := util.DB.Where("`key` = ?", key).First(&config).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return "", nil
+	}
+	return config.Value, err
+}
+
+// SetSystemConfig 写入系统配置，不存在时创建
+func SetSystemConfig(key, value, description string) error {
+	return util.DB.Where(SystemConfig{Key: key}).
+		Assign(SystemConfig{Value: value}).
+		Attrs(SystemConfig{Description: description}).
+		FirstOrCreate(&SystemConfig{}).Error
+}
+
 // UpdateSystemConfig 更新系统配置
 func UpdateSystemConfig(key, value string) error {
 	return util.DB.Model(&SystemConfig{}).Where("`key` = ?", key).Update("value", value).Error
@@ -0,0 +1,258 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 优惠券类型
+const (
+	CouponTypeFixed   = 1 // 满减/立减固定金额
+	CouponTypePercent = 2 // 按比例折扣
+)
+
+// 优惠券适用范围
+const (
+	CouponScopeAll         = 0 // 全场
+	CouponScopeCategory    = 1 // 指定分类
+	CouponScopePerformance = 2 // 指定演出
+	CouponScopeTicketType  = 3 // 指定票种
+)
+
+// Coupon 优惠券
+type Coupon struct {
+	ID           int        `gorm:"primary_key;auto_increment" json:"id"`
+	Name         string     `gorm:"size:100;not null" json:"name"`
+	Type         int        `gorm:"type:tinyint;not null" json:"type"`
+	Value        util.Money `gorm:"type:bigint;not null" json:"value"`        // 固定金额；折扣券为百分比，同样按两位小数定点存储（即万分比）
+	MaxDiscount  util.Money `gorm:"type:bigint;not null" json:"max_discount"` // 折扣券的优惠上限，0 为不限
+	MinSpend     util.Money `gorm:"type:bigint;not null" json:"min_spend"`
+	StartTime    time.Time  `json:"start_time"`
+	EndTime      time.Time  `json:"end_time"`
+	TotalLimit   int        `gorm:"not null" json:"total_limit"`    // 0 为不限
+	PerUserLimit int        `gorm:"not null" json:"per_user_limit"` // 0 为不限
+	UsedCount    int        `gorm:"not null;default:0" json:"used_count"`
+	ScopeType    int        `gorm:"type:tinyint;not null" json:"scope_type"`
+	ScopeIDs     string     `gorm:"column:scope_ids;size:500" json:"scope_ids"`    // 逗号分隔的分类/演出/票种ID
+	Status       int        `gorm:"type:tinyint;not null;default:1" json:"status"` // 0:停用, 1:启用
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (Coupon) TableName() string {
+	return "coupon"
+}
+
+// CouponCode 优惠码，一张优惠券可以有一个公开码或批量生成的一次性码
+type CouponCode struct {
+	ID        int       `gorm:"primary_key;auto_increment" json:"id"`
+	CouponID  int       `gorm:"not null;index" json:"coupon_id"`
+	Code      string    `gorm:"size:32;not null;unique_index" json:"code"`
+	MaxUses   int       `gorm:"not null" json:"max_uses"` // 0 为不限，仅受优惠券总量限制
+	UsedCount int       `gorm:"not null;default:0" json:"used_count"`
+	Status    int       `gorm:"type:tinyint;not null;default:1" json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (CouponCode) TableName() string {
+	return "coupon_code"
+}
+
+// CouponUsage 优惠券使用记录，订单关闭后释放
+type CouponUsage struct {
+	ID           int        `gorm:"primary_key;auto_increment" json:"id"`
+	CouponID     int        `gorm:"not null" json:"coupon_id"`
+	CouponCodeID int        `gorm:"not null" json:"coupon_code_id"`
+	UserID       int        `gorm:"not null" json:"user_id"`
+	OrderID      int        `gorm:"not null;index" json:"order_id"`
+	Discount     util.Money `gorm:"type:bigint;not null" json:"discount"`
+	Status       int        `gorm:"type:tinyint;not null" json:"status"` // 1:已使用, 0:已释放
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (CouponUsage) TableName() string {
+	return "coupon_usage"
+}
+
+// OrderDiscount 订单优惠明细
+type OrderDiscount struct {
+	ID           int        `gorm:"primary_key;auto_increment" json:"id"`
+	OrderID      int        `gorm:"not null;index" json:"order_id"`
+	CouponID     int        `gorm:"not null" json:"coupon_id"`
+	CouponCodeID int        `gorm:"not null" json:"-"`
+	Code         string     `gorm:"size:32" json:"code"`
+	Description  string     `gorm:"size:100" json:"description"`
+	Amount       util.Money `gorm:"type:bigint;not null" json:"amount"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (OrderDiscount) TableName() string {
+	return "order_discount"
+}
+
+// CouponQuery 优惠券查询条件
+type CouponQuery struct {
+	Keyword string
+	Status  int
+	Page    int
+	Size    int
+}
+
+// GetCoupons 分页获取优惠券
+func GetCoupons(query CouponQuery) ([]*Coupon, int, error) {
+	var coupons []*Coupon
+	var total int
+
+	tx := util.DB.Model(&Coupon{})
+	if query.Keyword != "" {
+		tx = tx.Where("name LIKE ?", "%"+query.Keyword+"%")
+	}
+	if query.Status >= 0 {
+		tx = tx.Where("status = ?", query.Status)
+	}
+	tx.Count(&total)
+
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+	size := query.Size
+	if size < 1 {
+		size = 10
+	}
+	err := tx.Offset((page - 1) * size).Limit(size).Order("id desc").Find(&coupons).Error
+	return coupons, total, err
+}
+
+// GetCouponByID 根据ID获取优惠券
+func GetCouponByID(id int) (*Coupon, error) {
+	var coupon Coupon
+	err := util.DB.Where("id = ?", id).First(&coupon).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// CreateCoupon 创建优惠券
+func CreateCoupon(coupon *Coupon) error {
+	return util.DB.Create(coupon).Error
+}
+
+// UpdateCoupon 更新优惠券
+func UpdateCoupon(coupon *Coupon) error {
+	coupon.UpdatedAt = time.Now()
+	return util.DB.Save(coupon).Error
+}
+
+// DeleteCoupon 删除未使用过的优惠券及其优惠码
+func DeleteCoupon(id int) error {
+	tx := util.DB.Begin()
+	if err := tx.Where("coupon_id = ?", id).Delete(&CouponCode{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Where("id = ?", id).Delete(&Coupon{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// HasCouponUsages 优惠券是否有使用记录
+func HasCouponUsages(couponID int) bool {
+	var count int
+	util.DB.Model(&CouponUsage{}).Where("coupon_id = ?", couponID).Count(&count)
+	return count > 0
+}
+
+// GetCouponCodes 分页获取优惠券的优惠码
+func GetCouponCodes(couponID, page, size int) ([]*CouponCode, int, error) {
+	var codes []*CouponCode
+	var total int
+
+	tx := util.DB.Model(&CouponCode{}).Where("coupon_id = ?", couponID)
+	tx.Count(&total)
+	if page < 1 {
+		page = 1
+	}
+	err := tx.Offset((page - 1) * size).Limit(size).Order("id asc").Find(&codes).Error
+	return codes, total, err
+}
+
+// GetCouponCodeByCode 根据优惠码获取
+func GetCouponCodeByCode(code string) (*CouponCode, error) {
+	var couponCode CouponCode
+	err := util.DB.Where("code = ?", code).First(&couponCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &couponCode, nil
+}
+
+// CreateCouponCode 创建优惠码
+func CreateCouponCode(code *CouponCode) error {
+	return util.DB.Create(code).Error
+}
+
+// CountUserCouponUsagesTx 在事务中统计用户已使用该优惠券的次数。使用加锁读取，读到其他事务已提交的最新记录，
+// 须在 UseCouponTx 锁定优惠券之后调用，同一优惠券的并发下单由此串行
+func CountUserCouponUsagesTx(tx *gorm.DB, couponID, userID int) (int, error) {
+	var count int
+	err := tx.Set("gorm:query_option", "FOR UPDATE").Model(&CouponUsage{}).Where("coupon_id = ? AND user_id = ? AND status = 1", couponID, userID).Count(&count).Error
+	return count, err
+}
+
+// UseCouponTx 在事务中占用一次优惠券和优惠码，超出总量或优惠码次数时返回 false
+func UseCouponTx(tx *gorm.DB, couponID, couponCodeID int) (bool, error) {
+	result := tx.Exec("UPDATE coupon SET used_count = used_count + 1 WHERE id = ? AND status = 1 AND (total_limit = 0 OR used_count < total_limit)", couponID)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	result = tx.Exec("UPDATE coupon_code SET used_count = used_count + 1 WHERE id = ? AND status = 1 AND (max_uses = 0 OR used_count < max_uses)", couponCodeID)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	return true, nil
+}
+
+// CreateCouponUsageTx 在事务中记录优惠券使用
+func CreateCouponUsageTx(tx *gorm.DB, usage *CouponUsage) error {
+	return tx.Create(usage).Error
+}
+
+// CreateOrderDiscountTx 在事务中记录订单优惠明细
+func CreateOrderDiscountTx(tx *gorm.DB, discount *OrderDiscount) error {
+	return tx.Create(discount).Error
+}
+
+// ReleaseOrderCouponsTx 在关单事务中释放优惠券使用次数
+func ReleaseOrderCouponsTx(tx *gorm.DB, orderID int) error {
+	var usages []*CouponUsage
+	if err := tx.Where("order_id = ? AND status = 1", orderID).Find(&usages).Error; err != nil {
+		return err
+	}
+	for _, usage := range usages {
+		result := tx.Model(&CouponUsage{}).Where("id = ? AND status = 1", usage.ID).Updates(map[string]interface{}{
+			"status":     0,
+			"updated_at": time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if err := tx.Exec("UPDATE coupon SET used_count = used_count - 1 WHERE id = ? AND used_count > 0", usage.CouponID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE coupon_code SET used_count = used_count - 1 WHERE id = ? AND used_count > 0", usage.CouponCodeID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 费用策略适用范围，演出优先于分类，分类优先于全局
+const (
+	FeeScopeGlobal      = 0
+	FeeScopeCategory    = 1
+	FeeScopePerformance = 2
+)
+
+// 订单费用明细类型
+const (
+	ChargeTicket      = "ticket"
+	ChargeServiceFee  = "service_fee"
+	ChargeDeliveryFee = "delivery_fee"
+	ChargeDiscount    = "discount"
+	ChargeTax         = "tax"
+)
+
+// FeePolicy 费用与税费策略
+type FeePolicy struct {
+	ID             int        `gorm:"primary_key;auto_increment" json:"id"`
+	Name           string     `gorm:"size:100;not null" json:"name"`
+	ScopeType      int        `gorm:"type:tinyint;not null;unique_index:uk_scope" json:"scope_type"`
+	ScopeID        int        `gorm:"not null;default:0;unique_index:uk_scope" json:"scope_id"`
+	ServiceFee     util.Money `gorm:"type:bigint;not null;default:0" json:"service_fee"`      // 每张票的服务费
+	ServiceFeeRate util.Rate  `gorm:"type:bigint;not null;default:0" json:"service_fee_rate"` // 按票款收取的服务费比例
+	DeliveryFee    util.Money `gorm:"type:bigint;not null;default:0" json:"delivery_fee"`     // 每单配送费
+	TaxRate        util.Rate  `gorm:"type:bigint;not null;default:0" json:"tax_rate"`
+	TaxInclusive   int        `gorm:"type:tinyint;not null;default:0" json:"tax_inclusive"` // 1:价格已含税，税额仅单独列示
+	Status         int        `gorm:"type:tinyint;not null;default:1" json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (FeePolicy) TableName() string {
+	return "fee_policy"
+}
+
+// OrderCharge 订单金额构成明细，优惠为负数；Included 为 1 的明细已含在其他金额中，不计入合计
+type OrderCharge struct {
+	ID          int        `gorm:"primary_key;auto_increment" json:"id"`
+	OrderID     int        `gorm:"not null;index" json:"order_id"`
+	Type        string     `gorm:"size:20;not null" json:"type"`
+	Description string     `gorm:"size:100" json:"description"`
+	Amount      util.Money `gorm:"type:bigint;not null" json:"amount"`
+	Included    int        `gorm:"type:tinyint;not null;default:0" json:"included"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (OrderCharge) TableName() string {
+	return "order_charge"
+}
+
+// GetFeePolicies 获取全部费用策略
+func GetFeePolicies() ([]*FeePolicy, error) {
+	var policies []*FeePolicy
+	err := util.DB.Order("scope_type asc, scope_id asc").Find(&policies).Error
+	return policies, err
+}
+
+// GetFeePolicyByID 根据ID获取费用策略
+func GetFeePolicyByID(id int) (*FeePolicy, error) {
+	var policy FeePolicy
+	err := util.DB.Where("id = ?", id).First(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetEffectiveFeePolicy 获取演出适用的启用策略，依次查找演出、分类、全局策略，都没有时返回 nil
+func GetEffectiveFeePolicy(performanceID, categoryID int) (*FeePolicy, error) {
+	var policies []*FeePolicy
+	err := util.DB.Where("status = 1 AND ((scope_type = ? AND scope_id = ?) OR (scope_type = ? AND scope_id = ?) OR scope_type = ?)",
+		FeeScopePerformance, performanceID, FeeScopeCategory, categoryID, FeeScopeGlobal).
+		Order("scope_type desc").Limit(1).Find(&policies).Error
+	if err != nil || len(policies) == 0 {
+		return nil, err
+	}
+	return policies[0], nil
+}
+
+// CreateFeePolicy 创建费用策略
+func CreateFeePolicy(policy *FeePolicy) error {
+	return util.DB.Create(policy).Error
+}
+
+// UpdateFeePolicy 更新费用策略
+func UpdateFeePolicy(policy *FeePolicy) error {
+	policy.UpdatedAt = time.Now()
+	return util.DB.Save(policy).Error
+}
+
+// DeleteFeePolicy 删除费用策略，已下单的费用保留在订单明细中
+func DeleteFeePolicy(id int) error {
+	return util.DB.Where("id = ?", id).Delete(&FeePolicy{}).Error
+}
+
+// CreateOrderChargeTx 在事务中记录订单费用明细
+func CreateOrderChargeTx(tx *gorm.DB, charge *OrderCharge) error {
+	return tx.Create(charge).Error
+}
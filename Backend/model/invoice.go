@@ -0,0 +1,145 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 发票状态
+const (
+	InvoiceStatusIssued = 1 // 已开具
+	InvoiceStatusVoid   = 2 // 已作废（订单退款）
+)
+
+// Invoice 订单发票，每个已支付订单最多开具一张。
+// 抬头、税号和金额在开具时固化，PDF 按记录和订单明细重新生成。
+type Invoice struct {
+	ID        int        `gorm:"primary_key;auto_increment" json:"id"`
+	InvoiceNo string     `gorm:"size:32;not null;unique_index" json:"invoice_no"`
+	OrderID   int        `gorm:"not null;unique_index" json:"order_id"`
+	UserID    int        `gorm:"not null;index" json:"user_id"`
+	Title     string     `gorm:"size:100;not null" json:"title"`         // 发票抬头
+	TaxID     string     `gorm:"column:tax_id;size:32" json:"tax_id"`    // 购买方纳税人识别号，个人抬头可为空
+	Email     string     `gorm:"size:100;not null" json:"email"`         // 接收电子发票的邮箱
+	Amount    util.Money `gorm:"type:bigint;not null" json:"amount"`     // 价税合计
+	TaxAmount util.Money `gorm:"type:bigint;not null" json:"tax_amount"` // 其中税额
+	Currency  string     `gorm:"size:3;not null" json:"currency"`
+	Status    int        `gorm:"type:tinyint;not null;default:1" json:"status"` // 1:已开具, 2:已作废
+	IssuedAt  time.Time  `json:"issued_at"`
+	VoidedAt  *time.Time `json:"voided_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	Order *Order `gorm:"foreignkey:OrderID" json:"order,omitempty"`
+}
+
+func (Invoice) TableName() string {
+	return "invoice"
+}
+
+// InvoiceQuery 发票查询条件
+type InvoiceQuery struct {
+	Keyword   string
+	Status    int
+	StartDate time.Time
+	EndDate   time.Time
+	Page      int
+	Size      int
+}
+
+// CreateInvoice 创建发票并按自增ID分配发票号，发票号为 前缀 + 年份 + 8 位序号
+func CreateInvoice(invoice *Invoice, prefix string) error {
+	tx := util.DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	// 先以订单维度的临时编号占位，取得ID后再写入正式发票号
+	invoice.InvoiceNo = fmt.Sprintf("TMP%d", invoice.OrderID)
+	if err := tx.Create(invoice).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	invoiceNo := fmt.Sprintf("%s%d%08d", prefix, invoice.IssuedAt.Year(), invoice.ID)
+	if err := tx.Model(invoice).Update("invoice_no", invoiceNo).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	invoice.InvoiceNo = invoiceNo
+
+	return tx.Commit().Error
+}
+
+// GetInvoiceByOrderID 获取订单的发票
+func GetInvoiceByOrderID(orderID int) (*Invoice, error) {
+	var invoice Invoice
+	err := util.DB.Where("order_id = ?", orderID).First(&invoice).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// GetInvoiceByID 根据ID获取发票
+func GetInvoiceByID(id int) (*Invoice, error) {
+	var invoice Invoice
+	err := util.DB.Where("id = ?", id).First(&invoice).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// GetInvoices 后台分页查询发票，关键字匹配发票号、抬头、税号、邮箱或订单号
+func GetInvoices(query InvoiceQuery) ([]*Invoice, int, error) {
+	var invoices []*Invoice
+	var total int
+
+	tx := util.DB.Model(&Invoice{})
+
+	if query.Keyword != "" {
+		keyword := "%" + query.Keyword + "%"
+		tx = tx.Joins("JOIN `order` ON invoice.order_id = `order`.id").
+			Where("invoice.invoice_no LIKE ? OR invoice.title LIKE ? OR invoice.tax_id LIKE ? OR invoice.email LIKE ? OR `order`.order_no LIKE ?",
+				keyword, keyword, keyword, keyword, keyword)
+	}
+
+	if query.Status > 0 {
+		tx = tx.Where("invoice.status = ?", query.Status)
+	}
+
+	if !query.StartDate.IsZero() {
+		tx = tx.Where("invoice.issued_at >= ?", query.StartDate)
+	}
+
+	if !query.EndDate.IsZero() {
+		tx = tx.Where("invoice.issued_at <= ?", query.EndDate)
+	}
+
+	tx.Count(&total)
+
+	page := query.Page
+	if page < 1 {
+		page = 1
+	}
+	size := query.Size
+	if size < 1 {
+		size = 10
+	}
+
+	err := tx.Preload("Order").Offset((page - 1) * size).Limit(size).Order("invoice.id desc").Find(&invoices).Error
+	return invoices, total, err
+}
+
+// VoidOrderInvoiceTx 在关单事务中作废订单的发票
+func VoidOrderInvoiceTx(tx *gorm.DB, orderID int) error {
+	now := time.Now()
+	return tx.Model(&Invoice{}).Where("order_id = ? AND status = ?", orderID, InvoiceStatusIssued).Updates(map[string]interface{}{
+		"status":    InvoiceStatusVoid,
+		"voided_at": &now,
+	}).Error
+}
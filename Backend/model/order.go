@@ -1,29 +1,41 @@
 package model
 
 import (
+	"errors"
 	"time"
 
 	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
 )
 
+// ErrOrderNotPending 订单不是待支付状态
+var ErrOrderNotPending = errors.New("订单不是待支付状态")
+
 // Order 订单模型
 type Order struct {
-	ID            int        `gorm:"primary_key" json:"id"`
-	OrderNo       string     `gorm:"size:50;not null;unique_index" json:"order_no"`
-	UserID        int        `gorm:"not null" json:"user_id"`
-	PerformanceID int        `gorm:"not null" json:"performance_id"`
-	TicketTypeID  int        `gorm:"not null" json:"ticket_type_id"`
-	Quantity      int        `gorm:"not null" json:"quantity"`
-	Amount        float64    `gorm:"type:decimal(10,2);not null" json:"amount"`
-	Status        int        `gorm:"type:tinyint;not null" json:"status"` // 0:待支付,1:已支付,2:已取消
-	ExpireTime    time.Time  `json:"expire_time"`
-	PaymentTime   *time.Time `json:"payment_time,omitempty"` // 改为指针类型，可存储NULL
-	CreatedAt     time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt     time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+	ID             int        `gorm:"primary_key" json:"id"`
+	OrderNo        string     `gorm:"size:50;not null;unique_index" json:"order_no"`
+	UserID         int        `gorm:"not null" json:"user_id"`
+	PerformanceID  int        `gorm:"not null" json:"performance_id"`
+	TicketTypeID   int        `gorm:"not null" json:"ticket_type_id"`
+	Quantity       int        `gorm:"not null" json:"quantity"`
+	Amount         util.Money `gorm:"type:bigint;not null" json:"amount"`                    // 应付合计，即 Charges 中计入合计的明细之和
+	DiscountAmount util.Money `gorm:"type:bigint;not null;default:0" json:"discount_amount"` // 优惠金额
+	Currency       string     `gorm:"size:3;not null;default:'CNY'" json:"currency"`
+	Status         int        `gorm:"type:tinyint;not null" json:"status"` // 0:待支付,1:已支付,2:已取消
+	ExpireTime     time.Time  `json:"expire_time"`
+	PaymentTime    *time.Time `json:"payment_time,omitempty"` // 改为指针类型，可存储NULL
+	CreatedAt      time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
 
 	// 关联数据，不直接映射到数据库
-	Performance *Performance `gorm:"foreignkey:PerformanceID" json:"performance,omitempty"`
-	TicketType  *TicketType  `gorm:"foreignkey:TicketTypeID" json:"ticket_type,omitempty"`
+	Performance *Performance    `gorm:"foreignkey:PerformanceID" json:"performance,omitempty"`
+	TicketType  *TicketType     `gorm:"foreignkey:TicketTypeID" json:"ticket_type,omitempty"`
+	Items       []OrderItem     `gorm:"foreignkey:OrderID" json:"items,omitempty"`
+	Tickets     []*Ticket       `gorm:"foreignkey:OrderID" json:"tickets,omitempty"`
+	Discounts   []OrderDiscount `gorm:"foreignkey:OrderID" json:"discounts,omitempty"`
+	Charges     []OrderCharge   `gorm:"foreignkey:OrderID" json:"charges,omitempty"`
 }
 
 func (Order) TableName() string {
@@ -43,6 +55,11 @@ func CreateOrder(order *Order) error {
 	return util.DB.Create(order).Error
 }
 
+// 在事务中创建订单
+func CreateOrderTx(tx *gorm.DB, order *Order) error {
+	return tx.Create(order).Error
+}
+
 // 获取用户订单列表
 func GetUserOrders(query OrderQuery) ([]*Order, int, error) {
 	var orders []*Order
@@ -74,7 +91,7 @@ func GetUserOrders(query OrderQuery) ([]*Order, int, error) {
 	tx = tx.Order("created_at desc")
 
 	// 查询数据并预加载关联信息
-	err := tx.Preload("Performance").Preload("TicketType").Find(&orders).Error
+	err := tx.Preload("Performance").Preload("TicketType").Preload("Items.TicketType").Find(&orders).Error
 
 	return orders, total, err
 }
@@ -82,7 +99,7 @@ func GetUserOrders(query OrderQuery) ([]*Order, int, error) {
 // 根据ID获取订单详情
 func GetOrderByID(id int) (*Order, error) {
 	var order Order
-	err := util.DB.Where("id = ?", id).Preload("Performance").Preload("TicketType").First(&order).Error
+	err := util.DB.Where("id = ?", id).Preload("Performance").Preload("TicketType").Preload("Items.TicketType").Preload("Tickets").Preload("Discounts").Preload("Charges").First(&order).Error
 	if err != nil {
 		return nil, err
 	}
@@ -104,11 +121,46 @@ func UpdateOrderStatus(orderID int, status int) error {
 	return util.DB.Model(&Order{}).Where("id = ?", orderID).Update("status", status).Error
 }
 
-// 支付订单
+// 支付订单，订单状态、候补状态和电子票在同一事务中更新
 func PayOrder(orderID int) error {
 	now := time.Now()
-	return util.DB.Model(&Order{}).Where("id = ?", orderID).Updates(map[string]interface{}{
+	tx := util.DB.Begin()
+	result := tx.Model(&Order{}).Where("id = ? AND status = 0", orderID).Updates(map[string]interface{}{
 		"status":       1,
 		"payment_time": &now,
-	}).Error
+	})
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	// 订单已被关闭或已支付
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return ErrOrderNotPending
+	}
+	if err := ConvertWaitlistOfferTx(tx, orderID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := ActivateOrderTicketsTx(tx, orderID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// 在事务中按条件变更订单状态，返回是否由本次调用完成变更，用于防止重复取消或退款
+func TransitionOrderStatusTx(tx *gorm.DB, orderID, fromStatus, toStatus int) (bool, error) {
+	result := tx.Model(&Order{}).Where("id = ? AND status = ?", orderID, fromStatus).Updates(map[string]interface{}{
+		"status":     toStatus,
+		"updated_at": time.Now(),
+	})
+	return result.RowsAffected == 1, result.Error
+}
+
+// 获取已过支付期限的待支付订单
+func GetExpiredPendingOrders(limit int) ([]*Order, error) {
+	var orders []*Order
+	err := util.DB.Where("status = 0 AND expire_time < ?", time.Now()).Preload("Items").Order("id asc").Limit(limit).Find(&orders).Error
+	return orders, err
 }
\ No newline at end of file
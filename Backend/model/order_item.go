@@ -0,0 +1,71 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// OrderItem 订单明细，一个订单可以包含多个票种
+type OrderItem struct {
+	ID            int        `gorm:"primary_key;auto_increment" json:"id"`
+	OrderID       int        `gorm:"not null;index" json:"order_id"`
+	PerformanceID int        `gorm:"not null" json:"performance_id"`
+	TicketTypeID  int        `gorm:"not null" json:"ticket_type_id"`
+	Quantity      int        `gorm:"not null" json:"quantity"`
+	UnitPrice     util.Money `gorm:"type:bigint;not null" json:"unit_price"`
+	Amount        util.Money `gorm:"type:bigint;not null" json:"amount"`
+	PriceRuleID   int        `gorm:"not null;default:0" json:"price_rule_id"` // 下单时生效的价格规则，0 为票面价
+	PriceRuleName string     `gorm:"size:100" json:"price_rule_name,omitempty"`
+	CreatedAt     time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	TicketType *TicketType `gorm:"foreignkey:TicketTypeID" json:"ticket_type,omitempty"`
+}
+
+func (OrderItem) TableName() string {
+	return "order_item"
+}
+
+// CreateOrderItemTx 在事务中创建订单明细
+func CreateOrderItemTx(tx *gorm.DB, item *OrderItem) error {
+	return tx.Create(item).Error
+}
+
+// GetOrderItems 获取订单明细
+func GetOrderItems(orderID int) ([]OrderItem, error) {
+	var items []OrderItem
+	err := util.DB.Where("order_id = ?", orderID).Order("id asc").Find(&items).Error
+	return items, err
+}
+
+// StockItems 返回订单占用库存的明细，
+// 旧的单票种订单没有明细记录时按订单自身的票种和数量生成一条
+func (o *Order) StockItems() []OrderItem {
+	if len(o.Items) > 0 {
+		return o.Items
+	}
+	items, err := GetOrderItems(o.ID)
+	if err == nil && len(items) > 0 {
+		return items
+	}
+	return []OrderItem{{
+		OrderID:       o.ID,
+		PerformanceID: o.PerformanceID,
+		TicketTypeID:  o.TicketTypeID,
+		Quantity:      o.Quantity,
+		UnitPrice:     o.Amount.Div(o.Quantity),
+		Amount:        o.Amount,
+	}}
+}
+
+// RestoreOrderStockTx 在事务中归还订单占用的全部库存
+func RestoreOrderStockTx(tx *gorm.DB, order *Order) error {
+	for _, item := range order.StockItems() {
+		if err := IncreaseStockTx(tx, item.TicketTypeID, item.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -8,18 +8,19 @@ import (
 
 // Performance 演出模型
 type Performance struct {
-	ID          int       `gorm:"primary_key" json:"id"`
-	Title       string    `gorm:"size:100;not null" json:"title"`
-	CategoryID  int       `gorm:"not null" json:"category_id"`
-	CoverImage  string    `gorm:"size:255;not null" json:"cover_image"`
-	Description string    `gorm:"type:text" json:"description"`
-	Performer   string    `gorm:"size:100;not null" json:"performer"`
-	Venue       string    `gorm:"size:100;not null" json:"venue"`
-	StartTime   time.Time `gorm:"not null" json:"start_time"`
-	EndTime     time.Time `gorm:"not null" json:"end_time"`
-	Status      int       `gorm:"type:tinyint;not null" json:"status"` // 0:未开售,1:预售,2:在售,3:售罄,4:已结束
-	CreatedAt   time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt   time.Time `gorm:"not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
+	ID              int       `gorm:"primary_key" json:"id"`
+	Title           string    `gorm:"size:100;not null" json:"title"`
+	CategoryID      int       `gorm:"not null" json:"category_id"`
+	CoverImage      string    `gorm:"size:255;not null" json:"cover_image"`
+	Description     string    `gorm:"type:text" json:"description"`
+	Performer       string    `gorm:"size:100;not null" json:"performer"`
+	Venue           string    `gorm:"size:100;not null" json:"venue"`
+	StartTime       time.Time `gorm:"not null" json:"start_time"`
+	EndTime         time.Time `gorm:"not null" json:"end_time"`
+	Status          int       `gorm:"type:tinyint;not null" json:"status"`                      // 0:未开售,1:预售,2:在售,3:售罄,4:已结束
+	RequireRealName int       `gorm:"type:tinyint;not null;default:0" json:"require_real_name"` // 1:实名购票,每张票绑定一位观演人
+	CreatedAt       time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"not null;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
 // PerformanceQuery 演出查询条件
@@ -93,3 +94,8 @@ func UpdatePerformance(performance *Performance) error {
 	performance.UpdatedAt = time.Now()
 	return util.DB.Model(&Performance{}).Where("id = ?", performance.ID).Updates(performance).Error
 }
+
+// SetPerformanceRealName 设置演出是否实名购票
+func SetPerformanceRealName(id, requireRealName int) error {
+	return util.DB.Model(&Performance{}).Where("id = ?", id).Update("require_real_name", requireRealName).Error
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PriceRule 票种价格规则。
+// 规则在时间窗口内（为空表示不限）且票种已售数量达到 SoldThreshold 时生效，
+// 可组合出早鸟价、按销量阶梯涨价、临近开演价等；多条规则同时生效时取优先级最高的一条。
+type PriceRule struct {
+	ID            int        `gorm:"primary_key;auto_increment" json:"id"`
+	TicketTypeID  int        `gorm:"not null;index" json:"ticket_type_id"`
+	Name          string     `gorm:"size:100;not null" json:"name"`
+	Price         util.Money `gorm:"type:bigint;not null" json:"price"`
+	StartTime     *time.Time `json:"start_time"`
+	EndTime       *time.Time `json:"end_time"`
+	SoldThreshold int        `gorm:"not null;default:0" json:"sold_threshold"` // 已售达到该数量后生效
+	Priority      int        `gorm:"not null;default:0" json:"priority"`
+	Status        int        `gorm:"type:tinyint;not null;default:1" json:"status"` // 0:停用, 1:启用
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (PriceRule) TableName() string {
+	return "price_rule"
+}
+
+// ActiveAt 规则在指定时间和已售数量下是否生效
+func (r *PriceRule) ActiveAt(now time.Time, sold int) bool {
+	if r.Status != 1 || sold < r.SoldThreshold {
+		return false
+	}
+	if r.StartTime != nil && now.Before(*r.StartTime) {
+		return false
+	}
+	if r.EndTime != nil && !now.Before(*r.EndTime) {
+		return false
+	}
+	return true
+}
+
+// GetPriceRules 获取票种的全部价格规则
+func GetPriceRules(ticketTypeID int) ([]*PriceRule, error) {
+	var rules []*PriceRule
+	err := util.DB.Where("ticket_type_id = ?", ticketTypeID).Order("priority desc, id asc").Find(&rules).Error
+	return rules, err
+}
+
+// GetEnabledPriceRules 批量获取票种的启用规则，按票种ID分组
+func GetEnabledPriceRules(ticketTypeIDs []int) (map[int][]*PriceRule, error) {
+	var rules []*PriceRule
+	grouped := make(map[int][]*PriceRule)
+	if len(ticketTypeIDs) == 0 {
+		return grouped, nil
+	}
+	err := util.DB.Where("ticket_type_id IN (?) AND status = 1", ticketTypeIDs).Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		grouped[rule.TicketTypeID] = append(grouped[rule.TicketTypeID], rule)
+	}
+	return grouped, nil
+}
+
+// GetEnabledPriceRulesTx 在事务中获取票种的启用规则
+func GetEnabledPriceRulesTx(tx *gorm.DB, ticketTypeID int) ([]*PriceRule, error) {
+	var rules []*PriceRule
+	err := tx.Where("ticket_type_id = ? AND status = 1", ticketTypeID).Find(&rules).Error
+	return rules, err
+}
+
+// GetPriceRuleByID 根据ID获取价格规则
+func GetPriceRuleByID(id int) (*PriceRule, error) {
+	var rule PriceRule
+	err := util.DB.Where("id = ?", id).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreatePriceRule 创建价格规则
+func CreatePriceRule(rule *PriceRule) error {
+	return util.DB.Create(rule).Error
+}
+
+// UpdatePriceRule 更新价格规则
+func UpdatePriceRule(rule *PriceRule) error {
+	rule.UpdatedAt = time.Now()
+	return util.DB.Save(rule).Error
+}
+
+// DeletePriceRule 删除价格规则，已下单的价格保留在订单明细中
+func DeletePriceRule(id int) error {
+	return util.DB.Where("id = ?", id).Delete(&PriceRule{}).Error
+}
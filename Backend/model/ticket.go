@@ -0,0 +1,101 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 电子票状态
+const (
+	TicketStatusPending   = 0 // 订单待支付
+	TicketStatusValid     = 1 // 有效
+	TicketStatusCheckedIn = 2 // 已检票
+	TicketStatusVoid      = 3 // 已作废
+)
+
+// Ticket 电子票，一张票对应一个座位/入场名额
+type Ticket struct {
+	ID             int        `gorm:"primary_key;auto_increment" json:"id"`
+	TicketNo       string     `gorm:"size:50;not null;unique_index" json:"ticket_no"`
+	OrderID        int        `gorm:"not null;index" json:"order_id"`
+	UserID         int        `gorm:"not null" json:"user_id"`
+	PerformanceID  int        `gorm:"not null" json:"performance_id"`
+	TicketTypeID   int        `gorm:"not null" json:"ticket_type_id"`
+	AttendeeID     int        `gorm:"not null;default:0" json:"attendee_id,omitempty"`
+	AttendeeName   string     `gorm:"size:50" json:"attendee_name,omitempty"`
+	IDNumberHash   string     `gorm:"column:id_number_hash;size:64" json:"-"`
+	IDNumberMasked string     `gorm:"column:id_number_masked;size:30" json:"id_number,omitempty"`
+	Claim          *int       `json:"-"` // 1:占用该场演出的实名名额, NULL:未占用或已释放
+	Status         int        `gorm:"type:tinyint;not null" json:"status"`
+	CheckedInAt    *time.Time `json:"checked_in_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	TicketType *TicketType `gorm:"foreignkey:TicketTypeID" json:"ticket_type,omitempty"`
+}
+
+func (Ticket) TableName() string {
+	return "ticket"
+}
+
+// CreateTicketTx 在事务中创建电子票
+func CreateTicketTx(tx *gorm.DB, ticket *Ticket) error {
+	return tx.Create(ticket).Error
+}
+
+// CountClaimedTicketsTx 统计指定演出中已被占用的证件号数量
+func CountClaimedTicketsTx(tx *gorm.DB, performanceID int, idNumberHashes []string) (int, error) {
+	var count int
+	err := tx.Model(&Ticket{}).
+		Where("performance_id = ? AND id_number_hash IN (?) AND claim = 1", performanceID, idNumberHashes).
+		Count(&count).Error
+	return count, err
+}
+
+// GetOrderTickets 获取订单的电子票
+func GetOrderTickets(orderID int) ([]*Ticket, error) {
+	var tickets []*Ticket
+	err := util.DB.Where("order_id = ?", orderID).Preload("TicketType").Order("id asc").Find(&tickets).Error
+	return tickets, err
+}
+
+// GetTicketByNo 根据票号获取电子票
+func GetTicketByNo(ticketNo string) (*Ticket, error) {
+	var ticket Ticket
+	err := util.DB.Where("ticket_no = ?", ticketNo).Preload("TicketType").First(&ticket).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// ActivateOrderTicketsTx 在支付事务中使订单的电子票生效
+func ActivateOrderTicketsTx(tx *gorm.DB, orderID int) error {
+	return tx.Model(&Ticket{}).Where("order_id = ? AND status = ?", orderID, TicketStatusPending).Updates(map[string]interface{}{
+		"status":     TicketStatusValid,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// VoidOrderTicketsTx 在事务中作废订单的电子票并释放实名名额
+func VoidOrderTicketsTx(tx *gorm.DB, orderID int) error {
+	return tx.Model(&Ticket{}).Where("order_id = ?", orderID).Updates(map[string]interface{}{
+		"status":     TicketStatusVoid,
+		"claim":      gorm.Expr("NULL"),
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// CheckInTicket 检票，只有有效状态的票可以检票，返回是否检票成功
+func CheckInTicket(ticketID int) (bool, error) {
+	now := time.Now()
+	result := util.DB.Model(&Ticket{}).Where("id = ? AND status = ?", ticketID, TicketStatusValid).Updates(map[string]interface{}{
+		"status":        TicketStatusCheckedIn,
+		"checked_in_at": &now,
+		"updated_at":    now,
+	})
+	return result.RowsAffected == 1, result.Error
+}
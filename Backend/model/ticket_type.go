@@ -4,21 +4,39 @@ import (
 	"time"
 
 	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
 )
 
 // TicketType 票种模型
 type TicketType struct {
-	ID            int       `gorm:"primary_key;auto_increment" json:"id"`
-	PerformanceID int       `gorm:"index" json:"performance_id"`
-	Name          string    `gorm:"size:50;not null" json:"name"`
-	Price         float64   `gorm:"not null" json:"price"`
-	Stock         int       `gorm:"default:0" json:"stock"`
-	Total         int       `gorm:"default:0" json:"total"`
-	SaleStartTime time.Time `json:"sale_start_time"`
-	SaleEndTime   time.Time `json:"sale_end_time"`
-	Status        int       `gorm:"default:0" json:"status"` // 0:未开售, 1:预售, 2:在售, 3:售罄, 4:已结束
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            int        `gorm:"primary_key;auto_increment" json:"id"`
+	PerformanceID int        `gorm:"index" json:"performance_id"`
+	Name          string     `gorm:"size:50;not null" json:"name"`
+	Price         util.Money `gorm:"type:bigint;not null" json:"price"` // 票面价，单位分
+	Stock         int        `gorm:"default:0" json:"stock"`
+	Total         int        `gorm:"default:0" json:"total"`
+	SaleStartTime time.Time  `json:"sale_start_time"`
+	SaleEndTime   time.Time  `json:"sale_end_time"`
+	Status        int        `gorm:"default:0" json:"status"`    // 0:未开售, 1:预售, 2:在售, 3:售罄, 4:已结束
+	SaleMode      int        `gorm:"default:0" json:"sale_mode"` // 0:先到先得, 1:抽签
+	Hidden        int        `gorm:"default:0" json:"hidden"`    // 1:隐藏票种，凭兑换码解锁
+	// 抽签登记时间窗口，仅抽签模式有效
+	BallotStartTime *time.Time `json:"ballot_start_time,omitempty"`
+	BallotEndTime   *time.Time `json:"ballot_end_time,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// 按价格规则计算的当前售价和下一次价格变化，仅在面向用户的票种列表中填充
+	CurrentPrice    *util.Money  `gorm:"-" json:"current_price,omitempty"`
+	NextPriceChange *PriceChange `gorm:"-" json:"next_price_change,omitempty"`
+}
+
+// PriceChange 票种即将发生的价格变化，按时间或按销量触发
+type PriceChange struct {
+	Price          util.Money `json:"price"`
+	StartTime      *time.Time `json:"start_time,omitempty"`      // 按时间触发的生效时间
+	RemainingUnits int        `json:"remaining_units,omitempty"` // 按销量触发时距生效还需售出的张数
 }
 
 // GetTicketTypesByPerformanceID 根据演出ID获取票种列表
@@ -28,6 +46,19 @@ func GetTicketTypesByPerformanceID(performanceID int) ([]TicketType, error) {
 	return ticketTypes, err
 }
 
+// GetVisibleTicketTypes 获取演出对用户可见的票种，隐藏票种仅在已解锁时可见
+func GetVisibleTicketTypes(performanceID int, unlockedIDs []int) ([]TicketType, error) {
+	var ticketTypes []TicketType
+	tx := util.DB.Where("performance_id = ?", performanceID)
+	if len(unlockedIDs) > 0 {
+		tx = tx.Where("hidden = 0 OR id IN (?)", unlockedIDs)
+	} else {
+		tx = tx.Where("hidden = 0")
+	}
+	err := tx.Find(&ticketTypes).Error
+	return ticketTypes, err
+}
+
 // GetTicketTypeByID 根据ID获取票种
 func GetTicketTypeByID(id int) (*TicketType, error) {
 	var ticketType TicketType
@@ -43,11 +74,35 @@ func DecreaseStock(ticketTypeID, quantity int) error {
 	return util.DB.Exec("UPDATE ticket_type SET stock = stock - ? WHERE id = ? AND stock >= ?", quantity, ticketTypeID, quantity).Error
 }
 
+// DecreaseStockTx 在事务中扣减库存，库存不足时返回 false
+func DecreaseStockTx(tx *gorm.DB, ticketTypeID, quantity int) (bool, error) {
+	result := tx.Exec("UPDATE ticket_type SET stock = stock - ? WHERE id = ? AND stock >= ?", quantity, ticketTypeID, quantity)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// GetTicketTypeByIDTx 在事务中获取票种
+func GetTicketTypeByIDTx(tx *gorm.DB, id int) (*TicketType, error) {
+	var ticketType TicketType
+	err := tx.Where("id = ?", id).First(&ticketType).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ticketType, nil
+}
+
 // IncreaseStock 增加库存
 func IncreaseStock(ticketTypeID, quantity int) error {
 	return util.DB.Exec("UPDATE ticket_type SET stock = stock + ? WHERE id = ?", quantity, ticketTypeID).Error
 }
 
+// IncreaseStockTx 在事务中归还库存
+func IncreaseStockTx(tx *gorm.DB, ticketTypeID, quantity int) error {
+	return tx.Exec("UPDATE ticket_type SET stock = stock + ? WHERE id = ?", quantity, ticketTypeID).Error
+}
+
 // GetAllTicketTypes 获取所有票种
 func GetAllTicketTypes(page, size int) ([]TicketType, int, error) {
 	var ticketTypes []TicketType
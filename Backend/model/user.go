@@ -8,15 +8,17 @@ import (
 
 // User 用户模型
 type User struct {
-	ID        int       `gorm:"primary_key;auto_increment" json:"id"`
-	Username  string    `gorm:"size:50;not null;unique" json:"username"`
-	Password  string    `gorm:"size:255;not null" json:"password"`
-	Phone     string    `gorm:"size:20" json:"phone"`
-	Email     string    `gorm:"size:100" json:"email"`
-	Avatar    string    `gorm:"size:255" json:"avatar"`
-	Status    int       `gorm:"default:1" json:"status"` // 1: 正常, 2: 禁用
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            int       `gorm:"primary_key;auto_increment" json:"id"`
+	Username      string    `gorm:"size:50;not null;unique" json:"username"`
+	Password      string    `gorm:"size:255;not null" json:"password"`
+	Phone         string    `gorm:"size:20" json:"phone"`
+	Email         string    `gorm:"size:100" json:"email"`
+	Avatar        string    `gorm:"size:255" json:"avatar"`
+	Status        int       `gorm:"default:1" json:"status"`         // 1: 正常, 2: 禁用, 3: 已注销
+	EmailVerified int       `gorm:"default:0" json:"email_verified"` // 1: 邮箱已验证
+	PhoneVerified int       `gorm:"default:0" json:"phone_verified"` // 1: 手机号已验证
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 func (User) TableName() string {
@@ -38,6 +40,26 @@ func GetUserByUsername(username string) (*User, error) {
 	return &user, nil
 }
 
+// GetUserByPhone 根据手机号获取用户，同一手机号有多个账号时优先取已验证该手机号的，其次取最早注册的
+func GetUserByPhone(phone string) (*User, error) {
+	var user User
+	err := util.DB.Where("phone = ?", phone).Order("phone_verified desc, id asc").First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByEmail 根据邮箱获取用户，同一邮箱有多个账号时优先取已验证该邮箱的，其次取最早注册的
+func GetUserByEmail(email string) (*User, error) {
+	var user User
+	err := util.DB.Where("email = ?", email).Order("email_verified desc, id asc").First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 // GetUserByID 根据ID获取用户
 func GetUserByID(id int) (*User, error) {
 	var user User
@@ -53,6 +75,62 @@ func UpdateUser(user *User) error {
 	return util.DB.Model(user).Updates(user).Error
 }
 
+// SetUserEmailVerified 设置邮箱验证状态（Updates 会忽略零值，单独更新）
+func SetUserEmailVerified(id int, verified bool) error {
+	value := 0
+	if verified {
+		value = 1
+	}
+	return util.DB.Model(&User{}).Where("id = ?", id).Update("email_verified", value).Error
+}
+
+// SetUserPhoneVerified 设置手机号验证状态
+func SetUserPhoneVerified(id int, verified bool) error {
+	value := 0
+	if verified {
+		value = 1
+	}
+	return util.DB.Model(&User{}).Where("id = ?", id).Update("phone_verified", value).Error
+}
+
+// MarkUserEmailVerified 将邮箱标记为已验证，仅当邮箱仍为 email 时更新，验证期间邮箱被修改则返回 false
+func MarkUserEmailVerified(id int, email string) (bool, error) {
+	result := util.DB.Model(&User{}).Where("id = ? AND email = ?", id, email).Update("email_verified", 1)
+	return result.RowsAffected == 1, result.Error
+}
+
+// MarkUserPhoneVerified 将手机号标记为已验证，仅当手机号仍为 phone 时更新，验证期间手机号被修改则返回 false
+func MarkUserPhoneVerified(id int, phone string) (bool, error) {
+	result := util.DB.Model(&User{}).Where("id = ? AND phone = ?", id, phone).Update("phone_verified", 1)
+	return result.RowsAffected == 1, result.Error
+}
+
+// UpdateUserProfile 更新手机号、邮箱和头像，空值不更新。手机号或邮箱有变化时在同一条语句中清除其验证状态
+func UpdateUserProfile(user *User, phoneChanged, emailChanged bool) error {
+	updates := map[string]interface{}{"updated_at": user.UpdatedAt}
+	if user.Phone != "" {
+		updates["phone"] = user.Phone
+	}
+	if user.Email != "" {
+		updates["email"] = user.Email
+	}
+	if user.Avatar != "" {
+		updates["avatar"] = user.Avatar
+	}
+	if phoneChanged {
+		updates["phone_verified"] = 0
+	}
+	if emailChanged {
+		updates["email_verified"] = 0
+	}
+	return util.DB.Model(&User{}).Where("id = ?", user.ID).Updates(updates).Error
+}
+
+// HasVerifiedContact 是否已验证手机号或邮箱
+func (u *User) HasVerifiedContact() bool {
+	return u.EmailVerified == 1 || u.PhoneVerified == 1
+}
+
 // DeleteUser 删除用户
 func DeleteUser(id int) error {
 	return util.DB.Delete(&User{}, "id = ?", id).Error
@@ -91,11 +169,17 @@ func GetUsers(query UserQuery) ([]*User, int, error) {
 	return users, total, err
 }
 
-// HasActiveOrders 检查用户是否有活跃订单
-func HasActiveOrders(userID int) bool {
+// HasActiveOrders 检查用户是否有活跃订单：待支付的订单，或演出尚未结束的已支付订单。
+// 演出已结束的订单作为财务记录保留，不影响删除或注销账号
+func HasActiveOrders(userID int) (bool, error) {
 	var count int
-	util.DB.Model(&Order{}).Where("user_id = ? AND status IN (0, 1)", userID).Count(&count)
-	return count > 0
+	err := util.DB.Model(&Order{}).
+		Where("user_id = ?", userID).
+		Where("status = 0 OR (status = 1 AND EXISTS ("+
+			"SELECT 1 FROM order_item oi JOIN performance p ON p.id = oi.performance_id "+
+			"WHERE oi.order_id = `order`.id AND p.end_time > ?))", time.Now()).
+		Count(&count).Error
+	return count > 0, err
 }
 
 // UserQuery 用户查询条件
@@ -154,7 +238,7 @@ func GetOrdersForAdmin(query OrderAdminQuery) ([]*Order, int, error) {
 	}
 	offset := (page - 1) * size
 
-	err := tx.Preload("Performance").Preload("TicketType").
+	err := tx.Preload("Performance").Preload("TicketType").Preload("Items.TicketType").Preload("Charges").
 		Offset(offset).Limit(size).Order("order.created_at desc").
 		Find(&orders).Error
 
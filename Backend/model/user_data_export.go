@@ -0,0 +1,164 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+)
+
+// 个人数据导出任务状态
+const (
+	DataExportPending    = 0
+	DataExportProcessing = 1
+	DataExportReady      = 2
+	DataExportFailed     = 3
+	DataExportExpired    = 4 // 文件已过期删除
+)
+
+// UserDataExport 用户个人数据导出任务，由后台任务生成 ZIP 文件
+type UserDataExport struct {
+	ID          int        `gorm:"primary_key;auto_increment" json:"id"`
+	UserID      int        `gorm:"not null;index" json:"user_id"`
+	Status      int        `gorm:"default:0" json:"status"`
+	FilePath    string     `gorm:"size:255" json:"-"`
+	FileSize    int64      `gorm:"default:0" json:"file_size"`
+	Error       string     `gorm:"size:255" json:"-"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (UserDataExport) TableName() string {
+	return "user_data_export"
+}
+
+// StatusName 导出状态，下载有效期已过但文件尚未清理的也视为已过期
+func (e *UserDataExport) StatusName() string {
+	switch {
+	case e.Status == DataExportProcessing:
+		return "processing"
+	case e.Status == DataExportFailed:
+		return "failed"
+	case e.Status == DataExportExpired, e.ExpiresAt != nil && time.Now().After(*e.ExpiresAt):
+		return "expired"
+	case e.Status == DataExportReady:
+		return "ready"
+	default:
+		return "pending"
+	}
+}
+
+func CreateUserDataExport(export *UserDataExport) error {
+	return util.DB.Create(export).Error
+}
+
+// GetUserDataExport 获取用户自己的导出任务
+func GetUserDataExport(userID, id int) (*UserDataExport, error) {
+	var export UserDataExport
+	err := util.DB.Where("id = ? AND user_id = ?", id, userID).First(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetUserDataExports 用户最近的导出任务
+func GetUserDataExports(userID, limit int) ([]*UserDataExport, error) {
+	var exports []*UserDataExport
+	err := util.DB.Where("user_id = ?", userID).Order("id desc").Limit(limit).Find(&exports).Error
+	return exports, err
+}
+
+// HasUnfinishedDataExport 用户是否有排队中或生成中的导出任务
+func HasUnfinishedDataExport(userID int) (bool, error) {
+	var total int
+	err := util.DB.Model(&UserDataExport{}).
+		Where("user_id = ? AND status IN (?)", userID, []int{DataExportPending, DataExportProcessing}).
+		Count(&total).Error
+	return total > 0, err
+}
+
+// GetPendingDataExports 排队中的导出任务，按申请顺序
+func GetPendingDataExports(limit int) ([]*UserDataExport, error) {
+	var exports []*UserDataExport
+	err := util.DB.Where("status = ?", DataExportPending).Order("id asc").Limit(limit).Find(&exports).Error
+	return exports, err
+}
+
+// ClaimDataExport 将排队中的任务标记为生成中，多个实例同时处理时只有一个能成功
+func ClaimDataExport(id int) (bool, error) {
+	result := util.DB.Model(&UserDataExport{}).
+		Where("id = ? AND status = ?", id, DataExportPending).
+		Updates(map[string]interface{}{
+			"status":     DataExportProcessing,
+			"started_at": time.Now(),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// CompleteDataExport 记录生成好的文件
+func CompleteDataExport(id int, filePath string, fileSize int64, expiresAt time.Time) error {
+	return util.DB.Model(&UserDataExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       DataExportReady,
+		"file_path":    filePath,
+		"file_size":    fileSize,
+		"completed_at": time.Now(),
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+// FailDataExport 记录生成失败的原因
+func FailDataExport(id int, reason string) error {
+	return util.DB.Model(&UserDataExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       DataExportFailed,
+		"error":        reason,
+		"completed_at": time.Now(),
+	}).Error
+}
+
+// RequeueStaleDataExports 生成过程中服务重启的任务重新排队
+func RequeueStaleDataExports(startedBefore time.Time) error {
+	return util.DB.Model(&UserDataExport{}).
+		Where("status = ? AND started_at < ?", DataExportProcessing, startedBefore).
+		Update("status", DataExportPending).Error
+}
+
+// GetExpiredDataExports 下载有效期已过、文件待删除的任务
+func GetExpiredDataExports(limit int) ([]*UserDataExport, error) {
+	var exports []*UserDataExport
+	err := util.DB.Where("status = ? AND expires_at < ?", DataExportReady, time.Now()).Limit(limit).Find(&exports).Error
+	return exports, err
+}
+
+// ExpireDataExport 文件删除后标记为已过期
+func ExpireDataExport(id int) error {
+	return util.DB.Model(&UserDataExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":    DataExportExpired,
+		"file_path": "",
+	}).Error
+}
+
+// GetAllUserOrders 用户的全部订单及明细，用于个人数据导出
+func GetAllUserOrders(userID int) ([]*Order, error) {
+	var orders []*Order
+	err := util.DB.Where("user_id = ?", userID).Order("id asc").
+		Preload("Performance").Preload("Items.TicketType").Preload("Discounts").Preload("Charges").
+		Find(&orders).Error
+	return orders, err
+}
+
+// GetAllUserTickets 用户的全部电子票，用于个人数据导出
+func GetAllUserTickets(userID int) ([]*Ticket, error) {
+	var tickets []*Ticket
+	err := util.DB.Where("user_id = ?", userID).Order("id asc").Preload("TicketType").Find(&tickets).Error
+	return tickets, err
+}
+
+// GetAllUserLoginHistory 用户的全部登录记录，用于个人数据导出
+func GetAllUserLoginHistory(userID int) ([]*UserLoginHistory, error) {
+	var histories []*UserLoginHistory
+	err := util.DB.Where("user_id = ?", userID).Order("id asc").Find(&histories).Error
+	return histories, err
+}
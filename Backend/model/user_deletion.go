@@ -0,0 +1,139 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// UserStatusDeleted 已注销并匿名化的账号，不能再登录，也不能被重新启用
+const UserStatusDeleted = 3
+
+// 账号注销申请状态
+const (
+	UserDeletionPending   = 0 // 冷静期中
+	UserDeletionCancelled = 1
+	UserDeletionCompleted = 2
+)
+
+// UserDeletionRequest 账号注销申请，冷静期满后由后台任务匿名化账号
+type UserDeletionRequest struct {
+	ID          int        `gorm:"primary_key;auto_increment" json:"id"`
+	UserID      int        `gorm:"not null" json:"user_id"`
+	Status      int        `gorm:"default:0" json:"status"`
+	Pending     *int       `json:"-"`            // 1:冷静期中, NULL:已撤销或已注销，(user_id, pending) 唯一
+	ScheduledAt time.Time  `json:"scheduled_at"` // 冷静期结束时间
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (UserDeletionRequest) TableName() string {
+	return "user_deletion_request"
+}
+
+// CreateUserDeletionRequest 创建冷静期中的注销申请，已有冷静期中的申请时违反唯一约束
+func CreateUserDeletionRequest(request *UserDeletionRequest) error {
+	pending := 1
+	request.Status = UserDeletionPending
+	request.Pending = &pending
+	return util.DB.Create(request).Error
+}
+
+// GetPendingUserDeletionRequest 用户冷静期中的注销申请
+func GetPendingUserDeletionRequest(userID int) (*UserDeletionRequest, error) {
+	var request UserDeletionRequest
+	err := util.DB.Where("user_id = ? AND status = ?", userID, UserDeletionPending).First(&request).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// CancelUserDeletionRequest 撤销冷静期中的注销申请，申请已执行或已撤销时返回 false
+func CancelUserDeletionRequest(id int) (bool, error) {
+	result := util.DB.Model(&UserDeletionRequest{}).
+		Where("id = ? AND status = ?", id, UserDeletionPending).
+		Updates(map[string]interface{}{
+			"status":       UserDeletionCancelled,
+			"pending":      gorm.Expr("NULL"),
+			"cancelled_at": time.Now(),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// GetDueUserDeletionRequests 冷静期已满、待执行的注销申请
+func GetDueUserDeletionRequests(limit int) ([]*UserDeletionRequest, error) {
+	var requests []*UserDeletionRequest
+	err := util.DB.Where("status = ? AND scheduled_at <= ?", UserDeletionPending, time.Now()).
+		Order("scheduled_at asc").Limit(limit).Find(&requests).Error
+	return requests, err
+}
+
+// AnonymizeUser 执行注销申请：清除账号的用户名、密码、联系方式和头像，删除观演人、第三方账号、
+// 登录历史等个人数据。订单、电子票和发票作为财务记录保留，其中的观演人信息和收票邮箱一并清除。
+// 申请在此期间被撤销时返回 gorm.ErrRecordNotFound
+func AnonymizeUser(request *UserDeletionRequest) error {
+	tx := util.DB.Begin()
+	result := tx.Model(&UserDeletionRequest{}).
+		Where("id = ? AND status = ? AND scheduled_at <= ?", request.ID, UserDeletionPending, time.Now()).
+		Updates(map[string]interface{}{
+			"status":       UserDeletionCompleted,
+			"pending":      gorm.Expr("NULL"),
+			"completed_at": time.Now(),
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+
+	err := tx.Model(&User{}).Where("id = ?", request.UserID).Updates(map[string]interface{}{
+		"username":       fmt.Sprintf("deleted_user_%d", request.UserID),
+		"password":       "",
+		"phone":          "",
+		"email":          "",
+		"avatar":         "",
+		"status":         UserStatusDeleted,
+		"email_verified": 0,
+		"phone_verified": 0,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Model(&Ticket{}).Where("user_id = ?", request.UserID).Updates(map[string]interface{}{
+		"attendee_name":    "",
+		"id_number_hash":   gorm.Expr("NULL"),
+		"id_number_masked": "",
+		"claim":            gorm.Expr("NULL"),
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Model(&Invoice{}).Where("user_id = ?", request.UserID).Update("email", "").Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	personalData := []interface{}{
+		&Attendee{}, &UserIdentity{}, &UserLoginHistory{}, &UserPrivacySetting{},
+		&CalendarFeed{}, &CartItem{}, &UserDataExport{},
+	}
+	for _, table := range personalData {
+		if err := tx.Where("user_id = ?", request.UserID).Delete(table).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
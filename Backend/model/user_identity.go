@@ -0,0 +1,80 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+)
+
+// UserIdentity 用户绑定的第三方账号，同一服务商的同一账号 (provider + subject) 只能绑定一个用户
+type UserIdentity struct {
+	ID          int        `gorm:"primary_key;auto_increment" json:"id"`
+	UserID      int        `gorm:"not null;index" json:"user_id"`
+	Provider    string     `gorm:"size:50;not null;unique_index:idx_provider_subject" json:"provider"`
+	Subject     string     `gorm:"size:255;not null;unique_index:idx_provider_subject" json:"-"`
+	Email       string     `gorm:"size:100" json:"email,omitempty"`
+	Name        string     `gorm:"size:100" json:"name,omitempty"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identity"
+}
+
+// GetUserIdentity 按服务商和第三方账号 ID 查找绑定关系
+func GetUserIdentity(provider, subject string) (*UserIdentity, error) {
+	var identity UserIdentity
+	err := util.DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetUserIdentities 获取用户绑定的全部第三方账号
+func GetUserIdentities(userID int) ([]*UserIdentity, error) {
+	var identities []*UserIdentity
+	err := util.DB.Where("user_id = ?", userID).Order("id asc").Find(&identities).Error
+	return identities, err
+}
+
+func CreateUserIdentity(identity *UserIdentity) error {
+	return util.DB.Create(identity).Error
+}
+
+// CreateUserWithIdentity 第三方登录自动注册时在同一事务中创建用户、隐私设置和第三方账号绑定，
+// 绑定写入失败（如并发回调重复绑定）时不会留下没有登录方式的账号
+func CreateUserWithIdentity(user *User, setting *UserPrivacySetting, identity *UserIdentity) error {
+	tx := util.DB.Begin()
+	if err := tx.Create(user).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	setting.UserID = user.ID
+	if err := tx.Create(setting).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	identity.UserID = user.ID
+	if err := tx.Create(identity).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// DeleteUserIdentity 解绑用户的第三方账号，返回是否找到该绑定
+func DeleteUserIdentity(userID, id int) (bool, error) {
+	result := util.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&UserIdentity{})
+	return result.RowsAffected == 1, result.Error
+}
+
+// TouchUserIdentity 记录通过第三方账号登录的时间，并同步服务商返回的邮箱和昵称
+func TouchUserIdentity(id int, email, name string) error {
+	return util.DB.Model(&UserIdentity{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":         email,
+		"name":          name,
+		"last_login_at": time.Now(),
+	}).Error
+}
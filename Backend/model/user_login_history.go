@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+)
+
+// 用户登录历史中的登录方式，密码登录使用 LoginEventPassword
+const (
+	LoginEventCode   = "code"
+	LoginEventOIDC   = "oidc"
+	LoginEventLocked = "locked" // 账号或 IP 被锁定时的登录尝试
+)
+
+// UserLoginHistory 用户登录历史，字段与 AdminLoginHistory 一致，用户名不存在时 UserID 为 0
+type UserLoginHistory struct {
+	ID         int64     `gorm:"primary_key;auto_increment" json:"id"`
+	UserID     int       `gorm:"not null" json:"user_id"`
+	IP         string    `gorm:"size:50" json:"ip"`
+	UserAgent  string    `gorm:"size:255" json:"user_agent"`
+	Status     int       `gorm:"default:1" json:"status"`
+	Event      string    `gorm:"size:30;default:'password'" json:"event"`
+	FailReason string    `gorm:"size:100" json:"fail_reason,omitempty"`
+	CreatedAt  time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (UserLoginHistory) TableName() string {
+	return "user_login_history"
+}
+
+func CreateUserLoginHistory(history *UserLoginHistory) error {
+	return util.DB.Create(history).Error
+}
+
+// GetUserLoginHistory 获取用户最近的登录记录
+func GetUserLoginHistory(userID, limit int) ([]*UserLoginHistory, error) {
+	var histories []*UserLoginHistory
+	err := util.DB.Where("user_id = ?", userID).Order("id desc").Limit(limit).Find(&histories).Error
+	return histories, err
+}
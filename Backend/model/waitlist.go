@@ -0,0 +1,174 @@
+package model
+
+import (
+	"time"
+
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 候补状态
+const (
+	WaitlistWaiting   = 0 // 排队中
+	WaitlistOffered   = 1 // 已获得候补订单，待支付
+	WaitlistConverted = 2 // 已支付
+	WaitlistLapsed    = 3 // 候补订单逾期或被取消
+	WaitlistLeft      = 4 // 用户主动退出
+)
+
+// WaitlistEntry 售罄票种的候补登记
+type WaitlistEntry struct {
+	ID              int        `gorm:"primary_key;auto_increment" json:"id"`
+	TicketTypeID    int        `gorm:"not null" json:"ticket_type_id"`
+	UserID          int        `gorm:"not null" json:"user_id"`
+	Quantity        int        `gorm:"not null" json:"quantity"`
+	AttendeeIDs     string     `gorm:"column:attendee_ids;size:255" json:"-"` // 逗号分隔的观演人ID
+	Status          int        `gorm:"type:tinyint;not null" json:"status"`
+	Active          *int       `json:"-"` // 1:排队中或待支付, NULL:已结束，(ticket_type_id, user_id, active) 唯一
+	OrderID         int        `gorm:"not null;default:0" json:"order_id,omitempty"`
+	OfferExpireTime *time.Time `json:"offer_expire_time,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	Position   int         `gorm:"-" json:"position,omitempty"` // 排队位置，仅排队中有效
+	TicketType *TicketType `gorm:"foreignkey:TicketTypeID" json:"ticket_type,omitempty"`
+}
+
+func (WaitlistEntry) TableName() string {
+	return "waitlist_entry"
+}
+
+// GetWaitlistEntryByID 根据ID获取候补登记
+func GetWaitlistEntryByID(id int) (*WaitlistEntry, error) {
+	var entry WaitlistEntry
+	err := util.DB.Where("id = ?", id).First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetUserWaitlistEntries 获取用户的候补登记
+func GetUserWaitlistEntries(userID int) ([]*WaitlistEntry, error) {
+	var entries []*WaitlistEntry
+	err := util.DB.Where("user_id = ?", userID).Preload("TicketType").Order("id desc").Find(&entries).Error
+	return entries, err
+}
+
+// WaitingWaitlistQuantity 票种排队中候补所需的总张数，这部分库存只分配给候补
+func WaitingWaitlistQuantity(ticketTypeID int) (int, error) {
+	return waitingWaitlistQuantity(util.DB, ticketTypeID)
+}
+
+// WaitingWaitlistQuantityTx 在下单事务中统计票种排队中候补所需的总张数
+func WaitingWaitlistQuantityTx(tx *gorm.DB, ticketTypeID int) (int, error) {
+	return waitingWaitlistQuantity(tx, ticketTypeID)
+}
+
+func waitingWaitlistQuantity(db *gorm.DB, ticketTypeID int) (int, error) {
+	var total int
+	err := db.Model(&WaitlistEntry{}).Select("COALESCE(SUM(quantity), 0)").
+		Where("ticket_type_id = ? AND status = ?", ticketTypeID, WaitlistWaiting).Row().Scan(&total)
+	return total, err
+}
+
+// HasActiveWaitlistEntry 用户在该票种是否有排队中或待支付的候补
+func HasActiveWaitlistEntry(ticketTypeID, userID int) bool {
+	var count int
+	util.DB.Model(&WaitlistEntry{}).
+		Where("ticket_type_id = ? AND user_id = ? AND status IN (?)", ticketTypeID, userID, []int{WaitlistWaiting, WaitlistOffered}).
+		Count(&count)
+	return count > 0
+}
+
+// GetWaitlistPosition 排队位置，从 1 开始
+func GetWaitlistPosition(entry *WaitlistEntry) int {
+	var count int
+	util.DB.Model(&WaitlistEntry{}).
+		Where("ticket_type_id = ? AND status = ? AND id < ?", entry.TicketTypeID, WaitlistWaiting, entry.ID).
+		Count(&count)
+	return count + 1
+}
+
+// GetNextWaitlistEntry 获取票种排在最前的候补
+func GetNextWaitlistEntry(ticketTypeID int) (*WaitlistEntry, error) {
+	var entry WaitlistEntry
+	err := util.DB.Where("ticket_type_id = ? AND status = ?", ticketTypeID, WaitlistWaiting).Order("id asc").First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetWaitlistedTicketTypeIDs 获取有排队候补且有库存的票种
+func GetWaitlistedTicketTypeIDs() ([]int, error) {
+	var ids []int
+	err := util.DB.Table("waitlist_entry").
+		Joins("JOIN ticket_type tt ON tt.id = waitlist_entry.ticket_type_id").
+		Where("waitlist_entry.status = ? AND tt.stock > 0", WaitlistWaiting).
+		Pluck("DISTINCT waitlist_entry.ticket_type_id", &ids).Error
+	return ids, err
+}
+
+// CreateWaitlistEntry 创建排队中的候补登记，用户在该票种已有未结束的候补时违反唯一约束
+func CreateWaitlistEntry(entry *WaitlistEntry) error {
+	active := 1
+	entry.Active = &active
+	return util.DB.Create(entry).Error
+}
+
+// LeaveWaitlist 退出排队中的候补，返回是否退出成功
+func LeaveWaitlist(id int) (bool, error) {
+	result := util.DB.Model(&WaitlistEntry{}).Where("id = ? AND status = ?", id, WaitlistWaiting).Updates(map[string]interface{}{
+		"status":     WaitlistLeft,
+		"active":     gorm.Expr("NULL"),
+		"updated_at": time.Now(),
+	})
+	return result.RowsAffected == 1, result.Error
+}
+
+// OfferWaitlistEntryTx 在下单事务中将排队中的候补标记为待支付并关联候补订单，
+// 返回是否由本次调用完成标记，候补已退出时返回 false
+func OfferWaitlistEntryTx(tx *gorm.DB, id, orderID int, expireTime time.Time) (bool, error) {
+	result := tx.Model(&WaitlistEntry{}).Where("id = ? AND status = ?", id, WaitlistWaiting).Updates(map[string]interface{}{
+		"status":            WaitlistOffered,
+		"order_id":          orderID,
+		"offer_expire_time": &expireTime,
+		"updated_at":        time.Now(),
+	})
+	return result.RowsAffected == 1, result.Error
+}
+
+// LapseWaitlistEntry 将排队中的候补标记为失效，用于无法为其下单的情况
+func LapseWaitlistEntry(id int) error {
+	return util.DB.Model(&WaitlistEntry{}).Where("id = ? AND status = ?", id, WaitlistWaiting).Updates(map[string]interface{}{
+		"status":     WaitlistLapsed,
+		"active":     gorm.Expr("NULL"),
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// LapseWaitlistOfferTx 在关单事务中将候补订单对应的候补标记为失效
+func LapseWaitlistOfferTx(tx *gorm.DB, orderID int) error {
+	return tx.Model(&WaitlistEntry{}).Where("order_id = ? AND status = ?", orderID, WaitlistOffered).Updates(map[string]interface{}{
+		"status":     WaitlistLapsed,
+		"active":     gorm.Expr("NULL"),
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// ConvertWaitlistOfferTx 在支付事务中将候补订单对应的候补标记为完成
+func ConvertWaitlistOfferTx(tx *gorm.DB, orderID int) error {
+	return tx.Model(&WaitlistEntry{}).Where("order_id = ? AND status = ?", orderID, WaitlistOffered).Updates(map[string]interface{}{
+		"status":     WaitlistConverted,
+		"active":     gorm.Expr("NULL"),
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// LapseClosedWaitlistOffers 将订单已被关闭但仍为待支付的候补标记为失效，用于订单由存储过程关闭的情况
+func LapseClosedWaitlistOffers() error {
+	return util.DB.Exec("UPDATE waitlist_entry w JOIN `order` o ON o.id = w.order_id "+
+		"SET w.status = ?, w.active = NULL, w.updated_at = NOW() WHERE w.status = ? AND o.status IN (2, 3)", WaitlistLapsed, WaitlistOffered).Error
+}
@@ -9,6 +9,7 @@ import (
 
 	"ticket-system-backend/controller"
 	"ticket-system-backend/middleware"
+	"ticket-system-backend/model"
 	"ticket-system-backend/util"
 
 	"github.com/gin-gonic/gin"
@@ -48,6 +49,11 @@ func SetupRouter() *gin.Engine {
 	})
 
 	r.Use(gin.Recovery())
+
+	// 签名公钥集在 /api 前缀之外，按惯例发布于 /.well-known 下
+	authc := &controller.AuthController{}
+	r.GET("/.well-known/jwks.json", authc.JWKS)
+
 	r.Use(apiPrefixMiddleware())
 	r.Use(corsMiddleware())
 
@@ -58,12 +64,30 @@ func SetupRouter() *gin.Engine {
 			uc := &controller.UserController{}
 			auth.POST("/register", uc.Register)
 			auth.POST("/login", uc.Login)
+			auth.POST("/code/send", uc.SendLoginCode)
+			auth.POST("/code/login", uc.CodeLogin)
+			auth.POST("/password/forgot", uc.ForgotPassword)
+			auth.POST("/password/reset", uc.ResetPassword)
+			auth.POST("/refresh", uc.RefreshToken)
+			auth.POST("/logout", uc.Logout)
+
+			oc := &controller.OIDCController{}
+			auth.GET("/oidc/providers", oc.GetProviders)
+			auth.GET("/oidc/:provider/authorize", oc.Authorize)
+			auth.POST("/oidc/:provider/callback", oc.Callback)
 		}
 
 		adminAuth := api.Group("/admin/auth")
 		{
 			ac := &controller.AdminController{}
 			adminAuth.POST("/login", ac.Login)
+			adminAuth.POST("/2fa/verify", ac.VerifyTwoFactor)
+			adminAuth.POST("/2fa/setup", ac.BeginTwoFactorSetup)
+			adminAuth.POST("/2fa/setup/confirm", ac.ConfirmTwoFactorSetup)
+
+			ic := &controller.AdminInvitationController{}
+			adminAuth.GET("/invitation", ic.GetInvitation)
+			adminAuth.POST("/invitation/accept", ic.AcceptInvitation)
 		}
 
 		performance := api.Group("/performances")
@@ -80,6 +104,9 @@ func SetupRouter() *gin.Engine {
 			tc := &controller.TicketController{}
 			ticket.GET("/seckill", middleware.JWTAuthMiddleware(), tc.SeckillTicket)
 		}
+
+		calc := &controller.CalendarController{}
+		api.GET("/calendar/:token", calc.SubscribeCalendar)
 	}
 
 	auth := r.Group("/api")
@@ -91,13 +118,42 @@ func SetupRouter() *gin.Engine {
 			user.GET("/current", uc.GetUserInfo)
 			user.PUT("/current", uc.UpdateUserInfo)
 			user.POST("/current/change-password", uc.ChangePassword)
+			user.POST("/current/contact/send-code", uc.SendContactCode)
+			user.POST("/current/contact/verify", uc.VerifyContact)
 			user.GET("/current/privacy-settings", uc.GetPrivacySettings)
 			user.PUT("/current/privacy-settings", uc.UpdatePrivacySettings)
 			user.POST("/current/export-data", uc.ExportUserData)
+			user.GET("/current/exports", uc.GetUserDataExports)
 			user.POST("/current/delete-data", uc.DeleteUserData)
+			user.GET("/current/delete-data", uc.GetDeletionRequest)
+			user.DELETE("/current/delete-data", uc.CancelDeletionRequest)
 			user.POST("/current/avatar", uc.UploadAvatar)
+
+			atc := &controller.AttendeeController{}
+			user.GET("/current/attendees", atc.GetAttendees)
+			user.POST("/current/attendees", atc.AddAttendee)
+			user.DELETE("/current/attendees/:id", atc.DeleteAttendee)
+
+			calc := &controller.CalendarController{}
+			user.GET("/current/calendar.ics", calc.GetUserCalendar)
+			user.GET("/current/calendar-feed", calc.GetCalendarFeed)
+			user.POST("/current/calendar-feed", calc.ResetCalendarFeed)
+			user.DELETE("/current/calendar-feed", calc.RevokeCalendarFeed)
+
+			sc := &controller.SessionController{}
+			user.GET("/current/sessions", sc.GetSessions)
+			user.DELETE("/current/sessions", sc.RevokeOtherSessions)
+			user.DELETE("/current/sessions/:id", sc.RevokeSession)
+			user.GET("/current/login-history", sc.GetLoginHistory)
+
+			oc := &controller.OIDCController{}
+			user.GET("/current/identities", oc.GetIdentities)
+			user.POST("/current/identities/:provider/authorize", oc.AuthorizeLink)
+			user.DELETE("/current/identities/:id", oc.UnlinkIdentity)
 		}
 
+		auth.GET("/download/user-data/:id", (&controller.UserController{}).DownloadUserData)
+
 		order := auth.Group("/orders")
 		{
 			oc := &controller.OrderController{}
@@ -107,12 +163,48 @@ func SetupRouter() *gin.Engine {
 			order.POST("/:id/cancel", oc.CancelOrder)
 			order.POST("/:id/pay", oc.PayOrder)
 			order.POST("/:id/refund", oc.RefundOrder)
+			order.GET("/:id/tickets.pdf", oc.DownloadTickets)
+
+			ic := &controller.InvoiceController{}
+			order.POST("/:id/invoice", ic.RequestInvoice)
+			order.GET("/:id/invoice", ic.GetInvoice)
+			order.GET("/:id/invoice/pdf", ic.DownloadInvoice)
+
+			calc := &controller.CalendarController{}
+			order.GET("/:id/calendar.ics", calc.GetOrderCalendar)
+		}
+
+		cart := auth.Group("/cart")
+		{
+			cc := &controller.CartController{}
+			cart.GET("", cc.GetCart)
+			cart.POST("/items", cc.AddItem)
+			cart.PUT("/items/:id", cc.UpdateItem)
+			cart.DELETE("/items/:id", cc.RemoveItem)
+			cart.POST("/checkout", cc.Checkout)
+		}
+
+		ballot := auth.Group("/ballots")
+		{
+			bc := &controller.BallotController{}
+			ballot.GET("/entries", bc.GetEntries)
+			ballot.POST("/:ticketTypeId/entry", bc.Enter)
+			ballot.DELETE("/:ticketTypeId/entry", bc.Withdraw)
+		}
+
+		waitlist := auth.Group("/waitlist")
+		{
+			wc := &controller.WaitlistController{}
+			waitlist.GET("", wc.GetEntries)
+			waitlist.POST("", wc.Join)
+			waitlist.DELETE("/:id", wc.Leave)
 		}
 
 		ticket := auth.Group("/tickets")
 		{
 			tc := &controller.TicketController{}
 			ticket.POST("/seckill", tc.SeckillTicket)
+			ticket.POST("/access-codes/redeem", tc.RedeemAccessCode)
 		}
 
 		ticketPerformance := auth.Group("/tickets")
@@ -125,80 +217,173 @@ func SetupRouter() *gin.Engine {
 	admin := r.Group("/api/admin")
 	admin.Use(middleware.AdminAuthMiddleware())
 	{
+		// 每个管理接口都须声明所需权限，只有当前管理员自己的资料、密码和两步验证不需要额外权限
+		perm := middleware.RequirePermission
+
 		dashboard := admin.Group("/dashboard")
 		{
 			ac := &controller.AdminController{}
-			dashboard.GET("/stats", ac.GetDashboardStats)
-			dashboard.GET("/category-distribution", ac.GetCategoryDistribution)
+			dashboard.GET("/stats", perm(model.PermissionDashboardRead), ac.GetDashboardStats)
+			dashboard.GET("/category-distribution", perm(model.PermissionDashboardRead), ac.GetCategoryDistribution)
 		}
 
 		adminMgmt := admin.Group("/admins")
 		{
 			ac := &controller.AdminController{}
-			adminMgmt.GET("", ac.GetAdminList)
-			adminMgmt.GET("/:id", ac.GetAdminInfo)
-			adminMgmt.PUT("/:id", ac.UpdateAdmin)
+			adminMgmt.GET("", perm(model.PermissionAdminRead), ac.GetAdminList)
+			adminMgmt.GET("/info", ac.GetAdminInfo)
+			adminMgmt.GET("/:id", perm(model.PermissionAdminRead), ac.GetAdminInfo)
+			adminMgmt.PUT("/:id", perm(model.PermissionAdminWrite), ac.UpdateAdmin)
 			adminMgmt.POST("/change-password", ac.ChangePassword)
+			adminMgmt.POST("/:id/2fa/reset", perm(model.PermissionAdminSecurity), (&controller.AdminTwoFactorController{}).ResetAdmin)
+			adminMgmt.POST("/:id/unlock", perm(model.PermissionAdminSecurity), ac.UnlockAdmin)
+		}
+
+		invitationMgmt := admin.Group("/invitations")
+		{
+			ic := &controller.AdminInvitationController{}
+			invitationMgmt.GET("", perm(model.PermissionAdminInvite), ic.GetInvitations)
+			invitationMgmt.POST("", perm(model.PermissionAdminInvite), ic.CreateInvitation)
+			invitationMgmt.POST("/:id/revoke", perm(model.PermissionAdminInvite), ic.RevokeInvitation)
+		}
+
+		roleMgmt := admin.Group("/roles")
+		{
+			rc := &controller.AdminRoleController{}
+			roleMgmt.GET("", perm(model.PermissionAdminRead), rc.GetRoles)
+			roleMgmt.GET("/permissions", perm(model.PermissionAdminRead), rc.GetPermissions)
+			roleMgmt.POST("", perm(model.PermissionRoleManage), rc.CreateRole)
+			roleMgmt.PUT("/:id", perm(model.PermissionRoleManage), rc.UpdateRole)
+			roleMgmt.DELETE("/:id", perm(model.PermissionRoleManage), rc.DeleteRole)
+		}
+
+		twoFactor := admin.Group("/2fa")
+		{
+			tfc := &controller.AdminTwoFactorController{}
+			twoFactor.GET("", tfc.GetStatus)
+			twoFactor.POST("/setup", tfc.Setup)
+			twoFactor.POST("/confirm", tfc.Confirm)
+			twoFactor.POST("/disable", tfc.Disable)
+			twoFactor.POST("/recovery-codes", tfc.RegenerateRecoveryCodes)
+			twoFactor.PUT("/policy", perm(model.PermissionAdminSecurity), tfc.UpdatePolicy)
 		}
 
 		userMgmt := admin.Group("/users")
 		{
 			uc := &controller.AdminUserController{}
-			userMgmt.GET("", uc.GetUserList)
-			userMgmt.GET("/:id", uc.GetUserDetail)
-			userMgmt.PUT("/:id/status", uc.UpdateUserStatus)
-			userMgmt.DELETE("/:id", uc.DeleteUser)
+			userMgmt.GET("", perm(model.PermissionUserRead), uc.GetUserList)
+			userMgmt.GET("/:id", perm(model.PermissionUserRead), uc.GetUserDetail)
+			userMgmt.PUT("/:id/status", perm(model.PermissionUserWrite), uc.UpdateUserStatus)
+			userMgmt.POST("/:id/unlock", perm(model.PermissionUserWrite), uc.UnlockUser)
+			userMgmt.DELETE("/:id", perm(model.PermissionUserDelete), uc.DeleteUser)
 		}
 
 		orderMgmt := admin.Group("/orders")
 		{
 			oc := &controller.AdminOrderController{}
-			orderMgmt.GET("", oc.GetOrderList)
-			orderMgmt.GET("/:id", oc.GetOrderDetail)
-			orderMgmt.POST("/:id/refund", oc.ProcessRefund)
-			orderMgmt.GET("/export", oc.ExportOrders)
+			orderMgmt.GET("", perm(model.PermissionOrderRead), oc.GetOrderList)
+			orderMgmt.GET("/:id", perm(model.PermissionOrderRead), oc.GetOrderDetail)
+			orderMgmt.POST("/:id/refund", perm(model.PermissionOrderRefund), oc.ProcessRefund)
+			orderMgmt.GET("/:id/tickets.pdf", perm(model.PermissionOrderRead), oc.DownloadTickets)
+			orderMgmt.GET("/export", perm(model.PermissionOrderExport), oc.ExportOrders)
 		}
 
 		performanceMgmt := admin.Group("/performances")
 		{
 			pc := &controller.AdminPerformanceController{}
-			performanceMgmt.GET("", pc.GetPerformanceList)
-			performanceMgmt.GET("/:id", pc.GetPerformanceDetail)
-			performanceMgmt.POST("", pc.CreatePerformance)
-			performanceMgmt.PUT("/:id", pc.UpdatePerformance)
-			performanceMgmt.DELETE("/:id", pc.DeletePerformance)
+			performanceMgmt.GET("", perm(model.PermissionPerformanceRead), pc.GetPerformanceList)
+			performanceMgmt.GET("/:id", perm(model.PermissionPerformanceRead), pc.GetPerformanceDetail)
+			performanceMgmt.POST("", perm(model.PermissionPerformanceWrite), pc.CreatePerformance)
+			performanceMgmt.PUT("/:id", perm(model.PermissionPerformanceWrite), pc.UpdatePerformance)
+			performanceMgmt.DELETE("/:id", perm(model.PermissionPerformanceWrite), pc.DeletePerformance)
 		}
 
 		ticketTypeMgmt := admin.Group("/ticket-types")
 		{
 			ttc := &controller.AdminTicketTypeController{}
-			ticketTypeMgmt.GET("", ttc.GetTicketTypeList)
-			ticketTypeMgmt.POST("", ttc.CreateTicketType)
-			ticketTypeMgmt.PUT("/:id", ttc.UpdateTicketType)
-			ticketTypeMgmt.DELETE("/:id", ttc.DeleteTicketType)
-			ticketTypeMgmt.POST("/:id/update-stock", ttc.UpdateTicketStock)
+			ticketTypeMgmt.GET("", perm(model.PermissionTicketTypeRead), ttc.GetTicketTypeList)
+			ticketTypeMgmt.POST("", perm(model.PermissionTicketTypeWrite), ttc.CreateTicketType)
+			ticketTypeMgmt.PUT("/:id", perm(model.PermissionTicketTypeWrite), ttc.UpdateTicketType)
+			ticketTypeMgmt.DELETE("/:id", perm(model.PermissionTicketTypeWrite), ttc.DeleteTicketType)
+			ticketTypeMgmt.POST("/:id/update-stock", perm(model.PermissionTicketTypeWrite), ttc.UpdateTicketStock)
+			ticketTypeMgmt.GET("/:id/ballot", perm(model.PermissionTicketTypeRead), ttc.GetBallot)
+			ticketTypeMgmt.POST("/:id/ballot/draw", perm(model.PermissionTicketTypeWrite), ttc.DrawBallot)
+			ticketTypeMgmt.POST("/:id/ballot/publish", perm(model.PermissionTicketTypeWrite), ttc.PublishBallot)
+			ticketTypeMgmt.GET("/:id/access-codes", perm(model.PermissionTicketTypeRead), ttc.GetAccessCodes)
+			ticketTypeMgmt.POST("/:id/access-codes", perm(model.PermissionTicketTypeWrite), ttc.GenerateAccessCodes)
+			ticketTypeMgmt.GET("/:id/access-codes/export", perm(model.PermissionTicketTypeWrite), ttc.ExportAccessCodes)
+			ticketTypeMgmt.GET("/:id/price-rules", perm(model.PermissionTicketTypeRead), ttc.GetPriceRules)
+			ticketTypeMgmt.POST("/:id/price-rules", perm(model.PermissionTicketTypeWrite), ttc.CreatePriceRule)
+		}
+
+		priceRuleMgmt := admin.Group("/price-rules")
+		{
+			prc := &controller.AdminPriceRuleController{}
+			priceRuleMgmt.PUT("/:id", perm(model.PermissionTicketTypeWrite), prc.UpdatePriceRule)
+			priceRuleMgmt.DELETE("/:id", perm(model.PermissionTicketTypeWrite), prc.DeletePriceRule)
+		}
+
+		accessCodeMgmt := admin.Group("/access-codes")
+		{
+			acc := &controller.AdminAccessCodeController{}
+			accessCodeMgmt.POST("/:id/disable", perm(model.PermissionTicketTypeWrite), acc.DisableAccessCode)
+		}
+
+		couponMgmt := admin.Group("/coupons")
+		{
+			cc := &controller.AdminCouponController{}
+			couponMgmt.GET("", perm(model.PermissionCouponRead), cc.GetCouponList)
+			couponMgmt.GET("/:id", perm(model.PermissionCouponRead), cc.GetCouponDetail)
+			couponMgmt.POST("", perm(model.PermissionCouponWrite), cc.CreateCoupon)
+			couponMgmt.PUT("/:id", perm(model.PermissionCouponWrite), cc.UpdateCoupon)
+			couponMgmt.DELETE("/:id", perm(model.PermissionCouponWrite), cc.DeleteCoupon)
+			couponMgmt.GET("/:id/codes", perm(model.PermissionCouponRead), cc.GetCouponCodes)
+			couponMgmt.POST("/:id/codes", perm(model.PermissionCouponWrite), cc.GenerateCouponCodes)
+		}
+
+		feePolicyMgmt := admin.Group("/fee-policies")
+		{
+			fc := &controller.AdminFeePolicyController{}
+			feePolicyMgmt.GET("", perm(model.PermissionFeePolicyRead), fc.GetFeePolicyList)
+			feePolicyMgmt.POST("", perm(model.PermissionFeePolicyWrite), fc.CreateFeePolicy)
+			feePolicyMgmt.PUT("/:id", perm(model.PermissionFeePolicyWrite), fc.UpdateFeePolicy)
+			feePolicyMgmt.DELETE("/:id", perm(model.PermissionFeePolicyWrite), fc.DeleteFeePolicy)
+		}
+
+		invoiceMgmt := admin.Group("/invoices")
+		{
+			ic := &controller.AdminInvoiceController{}
+			invoiceMgmt.GET("", perm(model.PermissionInvoiceRead), ic.GetInvoiceList)
+			invoiceMgmt.GET("/:id/pdf", perm(model.PermissionInvoiceRead), ic.DownloadInvoice)
+		}
+
+		ticketMgmt := admin.Group("/tickets")
+		{
+			tc := &controller.AdminTicketController{}
+			ticketMgmt.GET("/:ticketNo", perm(model.PermissionTicketCheckIn), tc.GetTicket)
+			ticketMgmt.POST("/check-in", perm(model.PermissionTicketCheckIn), tc.CheckIn)
 		}
 
 		categoryMgmt := admin.Group("/categories")
 		{
 			cc := &controller.AdminCategoryController{}
-			categoryMgmt.GET("", cc.GetCategoryList)
-			categoryMgmt.POST("", cc.CreateCategory)
-			categoryMgmt.PUT("/:id", cc.UpdateCategory)
-			categoryMgmt.DELETE("/:id", cc.DeleteCategory)
+			categoryMgmt.GET("", perm(model.PermissionPerformanceRead), cc.GetCategoryList)
+			categoryMgmt.POST("", perm(model.PermissionCategoryWrite), cc.CreateCategory)
+			categoryMgmt.PUT("/:id", perm(model.PermissionCategoryWrite), cc.UpdateCategory)
+			categoryMgmt.DELETE("/:id", perm(model.PermissionCategoryWrite), cc.DeleteCategory)
 		}
 
 		systemMgmt := admin.Group("/system")
 		{
 			sc := &controller.AdminSystemController{}
-			systemMgmt.GET("/config", sc.GetConfig)
-			systemMgmt.PUT("/config", sc.UpdateConfig)
+			systemMgmt.GET("/config", perm(model.PermissionConfigRead), sc.GetConfig)
+			systemMgmt.PUT("/config", perm(model.PermissionConfigWrite), sc.UpdateConfig)
 		}
 
 		logs := admin.Group("/logs")
 		{
 			ac := &controller.AdminController{}
-			logs.GET("", ac.GetAdminLogs)
+			logs.GET("", perm(model.PermissionLogRead), ac.GetAdminLogs)
 		}
 	}
 
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+var (
+	ErrAccessCodeInvalid   = errors.New("兑换码无效或已用完")
+	ErrAccessCodeExhausted = errors.New("兑换码可用次数不足")
+	ErrTicketTypeNotHidden = errors.New("只能为隐藏票种生成兑换码")
+	ErrAccessCodeCount     = errors.New("生成数量须在 1 到 1000 之间")
+)
+
+const (
+	maxAccessCodesPerBatch = 1000
+	accessCodeLength       = 10
+	// 兑换码、优惠码使用的字符，去掉易混淆的 0/O、1/I/L
+	codeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+)
+
+type AccessCodeService struct{}
+
+func NewAccessCodeService() *AccessCodeService {
+	return &AccessCodeService{}
+}
+
+// Redeem 使用兑换码解锁隐藏票种，同一兑换码可解锁的用户数不超过其可用次数
+func (s *AccessCodeService) Redeem(userID int, code string) (*model.TicketType, error) {
+	accessCode, err := model.GetAccessCodeByCode(normalizeCode(code))
+	if err != nil {
+		return nil, ErrAccessCodeInvalid
+	}
+
+	ticketType, err := model.GetTicketTypeByID(accessCode.TicketTypeID)
+	if err != nil {
+		return nil, ErrAccessCodeInvalid
+	}
+
+	if model.HasAccessCodeUnlock(accessCode.ID, userID) {
+		return ticketType, nil
+	}
+
+	// 锁定兑换码行后再统计解锁人数，并发兑换时不会超过可用次数
+	tx := util.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	accessCode, err = model.LockAccessCodeTx(tx, accessCode.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	unlocked, err := model.HasAccessCodeUnlockTx(tx, accessCode.ID, userID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if unlocked {
+		tx.Rollback()
+		return ticketType, nil
+	}
+	count, err := model.CountAccessCodeUnlocksTx(tx, accessCode.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if !accessCode.Usable(time.Now()) || count >= accessCode.MaxUses {
+		tx.Rollback()
+		return nil, ErrAccessCodeInvalid
+	}
+
+	unlock := &model.AccessCodeUnlock{
+		AccessCodeID: accessCode.ID,
+		UserID:       userID,
+		TicketTypeID: accessCode.TicketTypeID,
+		CreatedAt:    time.Now(),
+	}
+	if err := model.CreateAccessCodeUnlockTx(tx, unlock); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return ticketType, nil
+}
+
+// GetVisibleTicketTypes 获取演出对用户可见的票种及当前售价，userID 为 0 时只返回公开票种
+func (s *AccessCodeService) GetVisibleTicketTypes(performanceID, userID int) ([]model.TicketType, error) {
+	var unlockedIDs []int
+	if userID > 0 {
+		ids, err := model.GetUnlockedTicketTypeIDs(userID)
+		if err != nil {
+			return nil, err
+		}
+		unlockedIDs = ids
+	}
+	ticketTypes, err := model.GetVisibleTicketTypes(performanceID, unlockedIDs)
+	if err != nil {
+		return nil, err
+	}
+	priced := make([]*model.TicketType, 0, len(ticketTypes))
+	for i := range ticketTypes {
+		priced = append(priced, &ticketTypes[i])
+	}
+	if err := NewPricingService().FillPrices(priced); err != nil {
+		return nil, err
+	}
+	return ticketTypes, nil
+}
+
+// Generate 为隐藏票种批量生成兑换码，返回批次号和生成的兑换码
+func (s *AccessCodeService) Generate(adminID, ticketTypeID, count, maxUses int, expireTime *time.Time) (string, []*model.AccessCode, error) {
+	ticketType, err := model.GetTicketTypeByID(ticketTypeID)
+	if err != nil {
+		return "", nil, ErrTicketTypeNotFound
+	}
+	if ticketType.Hidden != 1 {
+		return "", nil, ErrTicketTypeNotHidden
+	}
+	if count <= 0 || count > maxAccessCodesPerBatch {
+		return "", nil, ErrAccessCodeCount
+	}
+	if maxUses <= 0 {
+		maxUses = 1
+	}
+
+	batchNo := time.Now().Format("20060102150405") + strconv.Itoa(ticketTypeID)
+	codes := make([]*model.AccessCode, 0, count)
+	for len(codes) < count {
+		code, err := randomCode(accessCodeLength)
+		if err != nil {
+			return "", nil, err
+		}
+		accessCode := &model.AccessCode{
+			TicketTypeID: ticketTypeID,
+			Code:         code,
+			BatchNo:      batchNo,
+			MaxUses:      maxUses,
+			Status:       1,
+			ExpireTime:   expireTime,
+			CreatedBy:    adminID,
+			CreatedAt:    time.Now(),
+		}
+		if err := model.CreateAccessCode(accessCode); err != nil {
+			// 随机码重复时重新生成
+			if util.IsDuplicateKeyError(err) {
+				continue
+			}
+			return "", nil, err
+		}
+		codes = append(codes, accessCode)
+	}
+	return batchNo, codes, nil
+}
+
+// ExportCSV 导出兑换码为 CSV，带 BOM 以便 Excel 正确识别中文
+func (s *AccessCodeService) ExportCSV(ticketTypeID int, batchNo string) ([]byte, error) {
+	codes, _, err := model.GetAccessCodes(model.AccessCodeQuery{TicketTypeID: ticketTypeID, BatchNo: batchNo})
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"兑换码", "批次号", "可用次数", "已用次数", "状态", "过期时间", "创建时间"})
+	for _, code := range codes {
+		status := "有效"
+		if code.Status != 1 {
+			status = "已停用"
+		}
+		expireTime := ""
+		if code.ExpireTime != nil {
+			expireTime = code.ExpireTime.Format("2006-01-02 15:04:05")
+		}
+		w.Write([]string{
+			code.Code,
+			code.BatchNo,
+			strconv.Itoa(code.MaxUses),
+			strconv.Itoa(code.UsedCount),
+			status,
+			expireTime,
+			code.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canAccessTicketType 隐藏票种需用户已解锁才可见、可购买
+func canAccessTicketType(userID int, ticketType *model.TicketType) bool {
+	return ticketType.Hidden != 1 || model.HasUnlockedTicketType(userID, ticketType.ID)
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func randomCode(length int) (string, error) {
+	max := big.NewInt(int64(len(codeAlphabet)))
+	buf := make([]byte, length)
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = codeAlphabet[n.Int64()]
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrDeletionActiveOrders = errors.New("存在待支付或演出尚未结束的订单，无法注销账号")
+	ErrDeletionPending      = errors.New("已申请注销，冷静期结束后将自动注销")
+	ErrDeletionNotFound     = errors.New("没有待执行的注销申请")
+)
+
+const (
+	accountDeletionBatchSize    = 50
+	accountDeletionScanInterval = 10 * time.Minute
+)
+
+type AccountDeletionService struct{}
+
+func NewAccountDeletionService() *AccountDeletionService {
+	return &AccountDeletionService{}
+}
+
+// RequestDeletion 申请注销账号，冷静期内可撤销。存在待支付或演出尚未结束的订单时不能申请，密码由调用方校验
+func (s *AccountDeletionService) RequestDeletion(user *model.User) (*model.UserDeletionRequest, error) {
+	if _, err := model.GetPendingUserDeletionRequest(user.ID); err == nil {
+		return nil, ErrDeletionPending
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	active, err := model.HasActiveOrders(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, ErrDeletionActiveOrders
+	}
+
+	request := &model.UserDeletionRequest{
+		UserID:      user.ID,
+		ScheduledAt: time.Now().AddDate(0, 0, util.GetConfig().Account.DeletionGraceDays),
+	}
+	if err := model.CreateUserDeletionRequest(request); err != nil {
+		// 并发申请时由唯一约束保证只有一条冷静期中的申请
+		if util.IsDuplicateKeyError(err) {
+			return nil, ErrDeletionPending
+		}
+		return nil, err
+	}
+	NewNotificationService().AccountDeletionScheduled(user, request.ScheduledAt)
+	return request, nil
+}
+
+// Pending 用户冷静期中的注销申请，没有时返回 nil
+func (s *AccountDeletionService) Pending(userID int) (*model.UserDeletionRequest, error) {
+	request, err := model.GetPendingUserDeletionRequest(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return request, err
+}
+
+// Cancel 撤销冷静期中的注销申请
+func (s *AccountDeletionService) Cancel(userID int) error {
+	request, err := s.Pending(userID)
+	if err != nil {
+		return err
+	}
+	if request == nil {
+		return ErrDeletionNotFound
+	}
+	cancelled, err := model.CancelUserDeletionRequest(request.ID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrDeletionNotFound
+	}
+	return nil
+}
+
+// StartAccountDeletionWorker 定期执行冷静期已满的注销申请，ctx 取消后退出
+func StartAccountDeletionWorker(ctx context.Context) {
+	ticker := time.NewTicker(accountDeletionScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ProcessAccountDeletions()
+		}
+	}
+}
+
+// ProcessAccountDeletions 匿名化冷静期已满的账号。期间产生了未完成订单的账号暂不处理，
+// 待订单完成或取消后的下一次扫描再注销
+func ProcessAccountDeletions() {
+	requests, err := model.GetDueUserDeletionRequests(accountDeletionBatchSize)
+	if err != nil {
+		log.Printf("查询注销申请失败: %v", err)
+		return
+	}
+
+	for _, request := range requests {
+		active, err := model.HasActiveOrders(request.UserID)
+		if err != nil {
+			log.Printf("查询注销用户订单失败: user=%d, err=%v", request.UserID, err)
+			continue
+		}
+		if active {
+			continue
+		}
+		user, err := model.GetUserByID(request.UserID)
+		if err != nil {
+			log.Printf("查询注销用户失败: user=%d, err=%v", request.UserID, err)
+			continue
+		}
+
+		if err := model.AnonymizeUser(request); err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("注销账号失败: user=%d, err=%v", request.UserID, err)
+			}
+			continue
+		}
+
+		if err := NewAuthService().RevokeUserSessions(user.ID); err != nil {
+			log.Printf("注销账号后清除会话失败: user=%d, err=%v", user.ID, err)
+		}
+		removeUserFiles(user.ID)
+		NewNotificationService().AccountDeleted(user)
+	}
+}
+
+// removeUserFiles 删除用户上传过的全部头像和尚未过期的个人数据导出文件
+func removeUserFiles(userID int) {
+	cfg := util.GetConfig()
+	patterns := []string{
+		filepath.Join(cfg.Upload.Path, "avatars", fmt.Sprintf("%d_*", userID)),
+		filepath.Join(cfg.Export.Dir, fmt.Sprintf("user_data_%d_*.zip", userID)),
+	}
+	for _, pattern := range patterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("删除用户文件失败: %s, err=%v", path, err)
+			}
+		}
+	}
+}
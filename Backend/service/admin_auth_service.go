@@ -0,0 +1,273 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+var (
+	ErrTwoFactorCodeInvalid = errors.New("验证码错误")
+	ErrTwoFactorLocked      = errors.New("验证码错误次数过多，请稍后再试")
+	ErrTwoFactorEnabled     = errors.New("已启用两步验证")
+	ErrTwoFactorNotEnabled  = errors.New("未启用两步验证")
+	ErrTwoFactorNotSetup    = errors.New("请先获取验证器密钥")
+	ErrTwoFactorEnforced    = errors.New("系统要求管理员启用两步验证，不能关闭")
+)
+
+// 管理员登录在密码校验通过后的下一步
+const (
+	AdminLoginStageDone            = ""
+	AdminLoginStageTwoFactorVerify = "verify"
+	AdminLoginStageTwoFactorSetup  = "setup"
+)
+
+const recoveryCodeCount = 10
+
+// TwoFactorSetup 绑定验证器所需的信息，密钥只在绑定时返回一次
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+	QRCode string `json:"qr_code"` // PNG 图片的 data URL
+}
+
+type AdminAuthService struct{}
+
+func NewAdminAuthService() *AdminAuthService {
+	return &AdminAuthService{}
+}
+
+// TwoFactorEnforced 超级管理员是否要求所有管理员启用两步验证
+func (s *AdminAuthService) TwoFactorEnforced() bool {
+	value, err := model.GetSystemConfigValue(model.SystemConfigAdminRequire2FA)
+	if err != nil {
+		return false
+	}
+	return value == "true" || value == "1"
+}
+
+// SetTwoFactorEnforced 开启或关闭强制两步验证
+func (s *AdminAuthService) SetTwoFactorEnforced(enforced bool) error {
+	value := "false"
+	if enforced {
+		value = "true"
+	}
+	return model.SetSystemConfig(model.SystemConfigAdminRequire2FA, value, "管理员必须启用两步验证")
+}
+
+// LoginStage 密码校验通过后判断是否还需要两步验证：已启用的输入动态码，
+// 系统强制启用但尚未绑定的须先完成绑定
+func (s *AdminAuthService) LoginStage(admin *model.Admin) string {
+	if admin.TOTPEnabled == 1 {
+		return AdminLoginStageTwoFactorVerify
+	}
+	if s.TwoFactorEnforced() {
+		return AdminLoginStageTwoFactorSetup
+	}
+	return AdminLoginStageDone
+}
+
+// IssueTwoFactorToken 签发两步验证阶段的临时令牌
+func (s *AdminAuthService) IssueTwoFactorToken(admin *model.Admin, stage string) (string, error) {
+	tokenType := util.TokenTypeTwoFactorVerify
+	if stage == AdminLoginStageTwoFactorSetup {
+		tokenType = util.TokenTypeTwoFactorSetup
+	}
+	return util.GenerateAdminTwoFactorToken(admin.ID, admin.Username, admin.Role, tokenType)
+}
+
+// AdminFromTwoFactorToken 由临时令牌取回管理员，账号被禁用或状态已变化时令牌作废
+func (s *AdminAuthService) AdminFromTwoFactorToken(token, stage string) (*model.Admin, error) {
+	tokenType := util.TokenTypeTwoFactorVerify
+	if stage == AdminLoginStageTwoFactorSetup {
+		tokenType = util.TokenTypeTwoFactorSetup
+	}
+	claims, err := util.ParseAdminTwoFactorToken(token, tokenType)
+	if err != nil {
+		return nil, err
+	}
+	admin, err := model.GetAdminByID(claims.AdminID)
+	if err != nil {
+		return nil, err
+	}
+	if admin.Status == 0 {
+		return nil, ErrUserDisabled
+	}
+	if s.LoginStage(admin) != stage {
+		return nil, errors.New("invalid token")
+	}
+	return admin, nil
+}
+
+// BeginSetup 生成新的验证器密钥，确认动态码之前不生效
+func (s *AdminAuthService) BeginSetup(admin *model.Admin) (*TwoFactorSetup, error) {
+	if admin.TOTPEnabled == 1 {
+		return nil, ErrTwoFactorEnabled
+	}
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := util.EncryptString(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := model.SetAdminTOTPSecret(admin.ID, encrypted); err != nil {
+		return nil, err
+	}
+	admin.TOTPSecret = encrypted
+
+	uri := util.TOTPProvisioningURI(util.GetConfig().Security.TOTPIssuer, admin.Username, secret)
+	setup := &TwoFactorSetup{Secret: secret, URI: uri}
+	if qr, err := util.EncodeQRCode(uri); err == nil {
+		if img, err := qr.PNG(6); err == nil {
+			setup.QRCode = "data:image/png;base64," + base64.StdEncoding.EncodeToString(img)
+		}
+	}
+	return setup, nil
+}
+
+// ConfirmSetup 用验证器生成的动态码确认绑定，返回只展示一次的恢复码
+func (s *AdminAuthService) ConfirmSetup(admin *model.Admin, code string) ([]string, error) {
+	if admin.TOTPEnabled == 1 {
+		return nil, ErrTwoFactorEnabled
+	}
+	if admin.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotSetup
+	}
+	if err := s.checkTOTP(admin, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := model.EnableAdminTOTP(admin.ID, hashes); err != nil {
+		return nil, err
+	}
+	admin.TOTPEnabled = 1
+	return codes, nil
+}
+
+// VerifyCode 校验登录时输入的动态码或恢复码，返回是否使用了恢复码
+func (s *AdminAuthService) VerifyCode(admin *model.Admin, code string) (bool, error) {
+	if admin.TOTPEnabled != 1 {
+		return false, ErrTwoFactorNotEnabled
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == util.TOTPDigits {
+		return false, s.checkTOTP(admin, code)
+	}
+	return true, s.useRecoveryCode(admin, code)
+}
+
+// Disable 关闭两步验证，系统强制启用时不允许关闭
+func (s *AdminAuthService) Disable(admin *model.Admin, code string) error {
+	if admin.TOTPEnabled != 1 {
+		return ErrTwoFactorNotEnabled
+	}
+	if s.TwoFactorEnforced() {
+		return ErrTwoFactorEnforced
+	}
+	if _, err := s.VerifyCode(admin, code); err != nil {
+		return err
+	}
+	return model.DisableAdminTOTP(admin.ID)
+}
+
+// Reset 超级管理员为丢失验证器的管理员解除绑定，对方下次登录时重新绑定
+func (s *AdminAuthService) Reset(adminID int) error {
+	if err := model.DisableAdminTOTP(adminID); err != nil {
+		return err
+	}
+	return util.ClearTwoFactorFailures(adminID)
+}
+
+// RegenerateRecoveryCodes 校验动态码后重新生成恢复码，旧恢复码全部作废
+func (s *AdminAuthService) RegenerateRecoveryCodes(admin *model.Admin, code string) ([]string, error) {
+	if admin.TOTPEnabled != 1 {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.checkTOTP(admin, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := model.ReplaceAdminRecoveryCodes(admin.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkTOTP 校验动态码，同一动态码只能使用一次，连续错误过多时暂停验证
+func (s *AdminAuthService) checkTOTP(admin *model.Admin, code string) error {
+	if err := s.checkLocked(admin.ID); err != nil {
+		return err
+	}
+	secret, err := util.DecryptString(admin.TOTPSecret)
+	if err != nil {
+		return err
+	}
+	step, ok := util.ValidateTOTP(secret, code, time.Now())
+	if ok {
+		ok, err = util.MarkTOTPStepUsed(admin.ID, step)
+		if err != nil {
+			return err
+		}
+	}
+	if !ok {
+		util.RecordTwoFactorFailure(admin.ID)
+		return ErrTwoFactorCodeInvalid
+	}
+	util.ClearTwoFactorFailures(admin.ID)
+	return nil
+}
+
+func (s *AdminAuthService) useRecoveryCode(admin *model.Admin, code string) error {
+	if err := s.checkLocked(admin.ID); err != nil {
+		return err
+	}
+	used, err := model.UseAdminRecoveryCode(admin.ID, util.HashSensitive(util.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		util.RecordTwoFactorFailure(admin.ID)
+		return ErrTwoFactorCodeInvalid
+	}
+	util.ClearTwoFactorFailures(admin.ID)
+	return nil
+}
+
+func (s *AdminAuthService) checkLocked(adminID int) error {
+	locked, err := util.TwoFactorLocked(adminID)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return ErrTwoFactorLocked
+	}
+	return nil
+}
+
+// generateRecoveryCodes 生成恢复码明文（返回给管理员）及其哈希（入库）
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := util.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, util.HashSensitive(code))
+	}
+	return codes, hashes, nil
+}
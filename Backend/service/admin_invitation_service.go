@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvitationNotFound   = errors.New("邀请不存在")
+	ErrInvitationNotPending = errors.New("邀请已被接受或撤销")
+	ErrInvitationPending    = errors.New("该邮箱已有待接受的邀请，请先撤销")
+	ErrAdminEmailExists     = errors.New("该邮箱已是管理员")
+	ErrAdminUsernameExists  = errors.New("用户名已存在")
+)
+
+// AdminInvitationAccept 受邀人接受邀请时填写的账号信息
+type AdminInvitationAccept struct {
+	Username string
+	Password string
+	RealName string
+}
+
+type AdminInvitationService struct{}
+
+func NewAdminInvitationService() *AdminInvitationService {
+	return &AdminInvitationService{}
+}
+
+// Invite 邀请管理员：校验角色后创建邀请记录，并向受邀邮箱发送一次性的邀请链接
+func (s *AdminInvitationService) Invite(inviter *model.Admin, email, realName, role string) (*model.AdminInvitation, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if err := NewAdminRoleService().CheckAdminChange(inviter, nil, role, 1); err != nil {
+		return nil, err
+	}
+
+	if _, err := model.GetAdminByEmail(email); err == nil {
+		return nil, ErrAdminEmailExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	pending, err := model.HasPendingAdminInvitation(email)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, ErrInvitationPending
+	}
+
+	nonce, err := util.NewAdminInviteNonce()
+	if err != nil {
+		return nil, err
+	}
+	invitation := &model.AdminInvitation{
+		Email:     email,
+		RealName:  strings.TrimSpace(realName),
+		Role:      role,
+		Nonce:     nonce,
+		Status:    model.AdminInvitationPending,
+		InvitedBy: inviter.ID,
+		ExpiresAt: time.Now().Add(time.Duration(util.GetConfig().Security.AdminInviteHours) * time.Hour),
+	}
+	if err := model.CreateAdminInvitation(invitation); err != nil {
+		return nil, err
+	}
+
+	token := util.SignAdminInviteToken(invitation.ID, invitation.ExpiresAt, invitation.Nonce)
+	link := util.GetConfig().Security.AdminInviteURL + "?token=" + token
+	NewNotificationService().AdminInvitation(invitation, inviter, s.roleDisplayName(role), link)
+	return invitation, nil
+}
+
+// Revoke 撤销待接受的邀请，邀请链接随之失效
+func (s *AdminInvitationService) Revoke(id, operatorID int) (*model.AdminInvitation, error) {
+	invitation, err := model.GetAdminInvitationByID(id)
+	if err != nil {
+		return nil, ErrInvitationNotFound
+	}
+	revoked, err := model.RevokeAdminInvitation(id, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, ErrInvitationNotPending
+	}
+	return invitation, nil
+}
+
+// List 邀请列表
+func (s *AdminInvitationService) List(query model.AdminInvitationQuery) ([]*model.AdminInvitation, int, error) {
+	return model.GetAdminInvitations(query)
+}
+
+// Lookup 根据邀请令牌查找待接受的邀请，供接受页面展示邮箱和角色
+func (s *AdminInvitationService) Lookup(token string) (*model.AdminInvitation, error) {
+	invitationID, nonce, err := util.ParseAdminInviteToken(token)
+	if err != nil {
+		return nil, err
+	}
+	invitation, err := model.GetAdminInvitationByID(invitationID)
+	if err != nil || invitation.Nonce != nonce || invitation.Status != model.AdminInvitationPending || invitation.Expired() {
+		return nil, util.ErrAdminInviteTokenInvalid
+	}
+	return invitation, nil
+}
+
+// Accept 接受邀请：设置用户名和密码，以邀请时指定的角色创建管理员。邀请链接只能使用一次，
+// 密码强度由调用方校验
+func (s *AdminInvitationService) Accept(token string, input AdminInvitationAccept) (*model.Admin, *model.AdminInvitation, error) {
+	invitation, err := s.Lookup(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := model.GetAdminByUsername(input.Username); err == nil {
+		return nil, nil, ErrAdminUsernameExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+	// 邀请发出后角色可能已被删除
+	if _, err := NewAdminRoleService().checkRoleExists(invitation.Role); err != nil {
+		return nil, nil, err
+	}
+
+	realName := strings.TrimSpace(input.RealName)
+	if realName == "" {
+		realName = invitation.RealName
+	}
+	if realName == "" {
+		realName = input.Username
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, nil, err
+	}
+	admin := &model.Admin{
+		Username: input.Username,
+		Password: string(hashedPassword),
+		RealName: realName,
+		Email:    invitation.Email,
+		Role:     invitation.Role,
+		Status:   1,
+	}
+	if err := model.AcceptAdminInvitation(invitation, admin); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, util.ErrAdminInviteTokenInvalid
+		}
+		return nil, nil, err
+	}
+	return admin, invitation, nil
+}
+
+func (s *AdminInvitationService) roleDisplayName(role string) string {
+	if r, err := model.GetAdminRoleByName(role); err == nil && r.DisplayName != "" {
+		return r.DisplayName
+	}
+	return role
+}
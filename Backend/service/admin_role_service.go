@@ -0,0 +1,310 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrRoleNotFound            = errors.New("角色不存在")
+	ErrRoleExists              = errors.New("角色标识已存在")
+	ErrRoleNameInvalid         = errors.New("角色标识只能包含小写字母、数字和下划线，长度 2-20")
+	ErrRoleBuiltIn             = errors.New("内置角色不能删除")
+	ErrRoleSuperAdminImmutable = errors.New("超级管理员固定拥有全部权限，不能修改")
+	ErrRoleInUse               = errors.New("仍有管理员属于该角色，无法删除")
+	ErrPermissionUnknown       = errors.New("权限不存在")
+	ErrPermissionSuperOnly     = errors.New("该权限只属于超级管理员，不能分配给其他角色")
+	ErrSuperAdminRequired      = errors.New("只有超级管理员可以授予或修改超级管理员")
+	ErrLastSuperAdmin          = errors.New("至少需要保留一个启用的超级管理员")
+	ErrAdminChangeSelf         = errors.New("不能修改自己的角色")
+	ErrRolePermissionExceeded  = errors.New("不能授予或修改权限超出自身的角色")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9_]{2,20}$`)
+
+// AdminRoleInput 创建或修改角色的参数
+type AdminRoleInput struct {
+	Name        string
+	DisplayName string
+	Description string
+	Permissions []string
+}
+
+type AdminRoleService struct{}
+
+func NewAdminRoleService() *AdminRoleService {
+	return &AdminRoleService{}
+}
+
+// AdminPermissions 管理员当前的有效权限：超级管理员拥有全部权限，已禁用的管理员没有任何权限
+func (s *AdminRoleService) AdminPermissions(adminID int) ([]string, error) {
+	permissions, found, err := util.GetCachedAdminPermissions(adminID)
+	if err != nil {
+		log.Printf("读取管理员权限缓存失败: admin=%d err=%v", adminID, err)
+	}
+	if found {
+		return permissions, nil
+	}
+
+	permissions = []string{}
+	admin, err := model.GetAdminByID(adminID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if admin != nil && admin.Status == 1 {
+		if admin.Role == model.RoleSuperAdmin {
+			permissions = allPermissions()
+		} else {
+			role, err := model.GetAdminRoleByName(admin.Role)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if role != nil {
+				permissions = role.PermissionList()
+			}
+		}
+	}
+
+	if err := util.CacheAdminPermissions(adminID, permissions); err != nil {
+		log.Printf("缓存管理员权限失败: admin=%d err=%v", adminID, err)
+	}
+	return permissions, nil
+}
+
+// HasPermission 管理员是否拥有指定权限
+func (s *AdminRoleService) HasPermission(adminID int, permission string) (bool, error) {
+	permissions, err := s.AdminPermissions(adminID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListRoles 全部角色，内置角色在前
+func (s *AdminRoleService) ListRoles() ([]*model.AdminRole, error) {
+	return model.GetAdminRoles()
+}
+
+// RolePermissions 角色的权限列表，超级管理员为全部权限
+func (s *AdminRoleService) RolePermissions(role *model.AdminRole) []string {
+	if role.Name == model.RoleSuperAdmin {
+		return allPermissions()
+	}
+	return role.PermissionList()
+}
+
+// CreateRole 创建自定义角色
+func (s *AdminRoleService) CreateRole(input AdminRoleInput) (*model.AdminRole, error) {
+	if !roleNamePattern.MatchString(input.Name) {
+		return nil, ErrRoleNameInvalid
+	}
+	if input.Name == model.RoleSuperAdmin {
+		return nil, ErrRoleExists
+	}
+	if _, err := model.GetAdminRoleByName(input.Name); err == nil {
+		return nil, ErrRoleExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	permissions, err := normalizePermissions(input.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &model.AdminRole{
+		Name:        input.Name,
+		DisplayName: input.DisplayName,
+		Description: input.Description,
+		Permissions: permissions,
+	}
+	if err := model.CreateAdminRole(role); err != nil {
+		return nil, err
+	}
+	// 角色创建前就已属于该角色的管理员此前没有任何权限
+	s.invalidateRole(role.Name)
+	return role, nil
+}
+
+// UpdateRole 修改角色的名称、说明和权限，角色标识不可修改。修改后立即对该角色的管理员生效
+func (s *AdminRoleService) UpdateRole(id int, input AdminRoleInput) (*model.AdminRole, error) {
+	role, err := model.GetAdminRoleByID(id)
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+	if role.Name == model.RoleSuperAdmin {
+		return nil, ErrRoleSuperAdminImmutable
+	}
+
+	permissions, err := normalizePermissions(input.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role.DisplayName = input.DisplayName
+	role.Description = input.Description
+	role.Permissions = permissions
+	if err := model.UpdateAdminRole(role); err != nil {
+		return nil, err
+	}
+	s.invalidateRole(role.Name)
+	return role, nil
+}
+
+// DeleteRole 删除自定义角色，内置角色和仍有管理员的角色不能删除
+func (s *AdminRoleService) DeleteRole(id int) (*model.AdminRole, error) {
+	role, err := model.GetAdminRoleByID(id)
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+	if role.BuiltIn == 1 {
+		return nil, ErrRoleBuiltIn
+	}
+
+	adminIDs, err := model.GetAdminIDsByRole(role.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(adminIDs) > 0 {
+		return nil, ErrRoleInUse
+	}
+
+	if err := model.DeleteAdminRole(role.ID); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// CheckAdminChange 校验 operator 对管理员角色和状态的修改，target 为空表示邀请新管理员：
+// 角色必须存在；不能修改自己的角色；只有超级管理员可以授予超级管理员或修改超级管理员；
+// 其他管理员只能授予权限不超出自身的角色，也只能修改角色权限不超出自身的管理员；
+// 不能禁用或降级最后一个启用的超级管理员
+func (s *AdminRoleService) CheckAdminChange(operator *model.Admin, target *model.Admin, role string, status int) error {
+	rolePermissions, err := s.checkRoleExists(role)
+	if err != nil {
+		return err
+	}
+
+	if target != nil && target.ID == operator.ID && role != target.Role {
+		return ErrAdminChangeSelf
+	}
+
+	targetIsSuper := target != nil && target.Role == model.RoleSuperAdmin
+	if (role == model.RoleSuperAdmin || targetIsSuper) && operator.Role != model.RoleSuperAdmin {
+		return ErrSuperAdminRequired
+	}
+
+	if operator.Role != model.RoleSuperAdmin {
+		granted, err := s.AdminPermissions(operator.ID)
+		if err != nil {
+			return err
+		}
+		if !permissionSubset(rolePermissions, granted) {
+			return ErrRolePermissionExceeded
+		}
+		if target != nil && target.Role != role {
+			current, err := s.checkRoleExists(target.Role)
+			if err != nil && err != ErrRoleNotFound {
+				return err
+			}
+			if !permissionSubset(current, granted) {
+				return ErrRolePermissionExceeded
+			}
+		}
+	}
+
+	if targetIsSuper && target.Status == 1 && (role != model.RoleSuperAdmin || status != 1) {
+		total, err := model.CountActiveSuperAdmins()
+		if err != nil {
+			return err
+		}
+		if total <= 1 {
+			return ErrLastSuperAdmin
+		}
+	}
+	return nil
+}
+
+// checkRoleExists 校验角色存在并返回其权限列表，超级管理员为全部权限
+func (s *AdminRoleService) checkRoleExists(name string) ([]string, error) {
+	if name == model.RoleSuperAdmin {
+		return allPermissions(), nil
+	}
+	role, err := model.GetAdminRoleByName(name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role.PermissionList(), nil
+}
+
+// invalidateRole 清除该角色下所有管理员的权限缓存
+func (s *AdminRoleService) invalidateRole(name string) {
+	adminIDs, err := model.GetAdminIDsByRole(name)
+	if err == nil {
+		err = util.InvalidateAdminPermissions(adminIDs...)
+	}
+	if err != nil {
+		log.Printf("清除角色权限缓存失败: role=%s err=%v", name, err)
+	}
+}
+
+func allPermissions() []string {
+	permissions := make([]string, 0, len(model.AdminPermissions))
+	for _, permission := range model.AdminPermissions {
+		permissions = append(permissions, permission.Code)
+	}
+	return permissions
+}
+
+// permissionSubset permissions 中的每一项是否都包含在 granted 中
+func permissionSubset(permissions, granted []string) bool {
+	set := make(map[string]bool, len(granted))
+	for _, p := range granted {
+		set[p] = true
+	}
+	for _, p := range permissions {
+		if !set[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizePermissions 校验权限编码并去重排序，返回逗号分隔的形式
+func normalizePermissions(codes []string) (string, error) {
+	seen := make(map[string]bool, len(codes))
+	result := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if code == "" || seen[code] {
+			continue
+		}
+		permission, ok := model.GetAdminPermission(code)
+		if !ok {
+			return "", ErrPermissionUnknown
+		}
+		if permission.SuperAdminOnly {
+			return "", ErrPermissionSuperOnly
+		}
+		seen[code] = true
+		result = append(result, code)
+	}
+	sort.Strings(result)
+	return strings.Join(result, ","), nil
+}
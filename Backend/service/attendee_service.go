@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+var (
+	ErrAttendeeNotFound      = errors.New("观演人不存在")
+	ErrAttendeeExists        = errors.New("该证件号已添加")
+	ErrInvalidAttendeeName   = errors.New("观演人姓名格式错误")
+	ErrInvalidIDNumber       = errors.New("身份证号码无效")
+	ErrAttendeeLimitExceeded = errors.New("观演人数量已达上限")
+)
+
+const maxAttendeesPerUser = 20
+
+type AttendeeService struct{}
+
+func NewAttendeeService() *AttendeeService {
+	return &AttendeeService{}
+}
+
+func (s *AttendeeService) GetAttendees(userID int) ([]*model.Attendee, error) {
+	return model.GetUserAttendees(userID)
+}
+
+// AddAttendee 校验姓名和身份证号后保存观演人，证件号只保存密文、哈希和脱敏值
+func (s *AttendeeService) AddAttendee(userID int, name, idNumber string) (*model.Attendee, error) {
+	name = strings.TrimSpace(name)
+	if n := utf8.RuneCountInString(name); n < 2 || n > 30 {
+		return nil, ErrInvalidAttendeeName
+	}
+
+	idNumber = util.NormalizeIDNumber(idNumber)
+	if !util.ValidateIDNumber(idNumber) {
+		return nil, ErrInvalidIDNumber
+	}
+
+	attendees, err := model.GetUserAttendees(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(attendees) >= maxAttendeesPerUser {
+		return nil, ErrAttendeeLimitExceeded
+	}
+
+	idNumberHash := util.HashSensitive(idNumber)
+	if model.HasUserAttendee(userID, idNumberHash) {
+		return nil, ErrAttendeeExists
+	}
+
+	encrypted, err := util.EncryptString(idNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	attendee := &model.Attendee{
+		UserID:         userID,
+		Name:           name,
+		IDNumberEnc:    encrypted,
+		IDNumberHash:   idNumberHash,
+		IDNumberMasked: util.MaskIDNumber(idNumber),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	if err := model.CreateAttendee(attendee); err != nil {
+		if util.IsDuplicateKeyError(err) {
+			return nil, ErrAttendeeExists
+		}
+		return nil, err
+	}
+	return attendee, nil
+}
+
+func (s *AttendeeService) DeleteAttendee(userID, attendeeID int) error {
+	attendee, err := model.GetAttendeeByID(attendeeID)
+	if err != nil || attendee.UserID != userID {
+		return ErrAttendeeNotFound
+	}
+	return model.DeleteAttendee(attendee.ID)
+}
+
+// loadOrderAttendees 加载下单使用的观演人，要求全部属于当前用户且互不重复
+func loadOrderAttendees(userID int, lines []OrderLine) (map[int]*model.Attendee, error) {
+	var ids []int
+	seen := make(map[int]bool)
+	for _, line := range lines {
+		if len(line.AttendeeIDs) > 0 && len(line.AttendeeIDs) != line.Quantity {
+			return nil, ErrAttendeeCountMismatch
+		}
+		for _, id := range line.AttendeeIDs {
+			if seen[id] {
+				return nil, ErrAttendeeDuplicate
+			}
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	result := make(map[int]*model.Attendee, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	attendees, err := model.GetAttendeesByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, attendee := range attendees {
+		if attendee.UserID == userID {
+			result[attendee.ID] = attendee
+		}
+	}
+	if len(result) != len(ids) {
+		return nil, ErrAttendeeNotFound
+	}
+	return result, nil
+}
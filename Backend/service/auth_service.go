@@ -0,0 +1,322 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrUserDisabled     = errors.New("账号已被禁用")
+	ErrSessionNotFound  = errors.New("登录设备不存在或已注销")
+	ErrResetTooFrequent = errors.New("操作过于频繁，请稍后再试")
+)
+
+// TokenPair 登录凭证：短期访问令牌和可轮换的刷新令牌
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type AuthService struct{}
+
+func NewAuthService() *AuthService {
+	return &AuthService{}
+}
+
+// IssueTokens 为用户创建新的登录会话并签发凭证，在新设备上登录时发送安全通知
+func (s *AuthService) IssueTokens(user *model.User, info util.SessionInfo) (*TokenPair, error) {
+	if user.Status != 1 {
+		return nil, ErrUserDisabled
+	}
+	if info.DeviceName == "" {
+		info.DeviceName = util.DeviceNameFromUserAgent(info.UserAgent)
+	}
+	sessionID, refreshToken, err := util.CreateSession(user.ID, info)
+	if err != nil {
+		return nil, err
+	}
+
+	// 首个设备（如刚注册）不提醒
+	isNew, isFirst, err := util.RememberDevice(user.ID, info.UserAgent)
+	if err != nil {
+		log.Printf("记录登录设备失败: user=%d err=%v", user.ID, err)
+	} else if isNew && !isFirst {
+		NewNotificationService().NewDeviceLogin(user, info, time.Now())
+	}
+
+	return s.tokenPair(user, sessionID, refreshToken)
+}
+
+// LoginWithPassword 用户名密码登录。账号或 IP 失败次数过多时返回 *util.LoginThrottleError，
+// 每次尝试都记入用户登录历史
+func (s *AuthService) LoginWithPassword(username, password string, info util.SessionInfo) (*model.User, *TokenPair, error) {
+	userID, account := 0, ""
+	user, err := model.GetUserByUsername(username)
+	if err == nil {
+		userID, account = user.ID, strconv.Itoa(user.ID)
+	}
+
+	if err := util.CheckLoginAllowed(util.LoginScopeUser, account, info.IP); err != nil {
+		recordUserLogin(userID, info, model.LoginEventLocked, err)
+		return nil, nil, err
+	}
+
+	if user == nil || !util.CheckPassword(password, user.Password) {
+		recordUserLogin(userID, info, model.LoginEventPassword, ErrInvalidPassword)
+		throttle, err := util.RecordLoginFailure(util.LoginScopeUser, account, info.IP)
+		if err != nil {
+			log.Printf("记录登录失败次数出错: user=%d err=%v", userID, err)
+		}
+		if throttle != nil && throttle.Locked {
+			return nil, nil, throttle
+		}
+		if user == nil {
+			return nil, nil, ErrUserNotFound
+		}
+		return nil, nil, ErrInvalidPassword
+	}
+
+	tokens, err := s.IssueTokens(user, info)
+	recordUserLogin(user.ID, info, model.LoginEventPassword, err)
+	if err != nil {
+		return nil, nil, err
+	}
+	util.ClearLoginFailures(util.LoginScopeUser, account)
+	return user, tokens, nil
+}
+
+// SendLoginCode 发送登录验证码。未注册的邮箱不发送但同样返回成功，避免泄露账号是否存在；
+// 重发冷却和次数限制在查询账号之前生效，已注册和未注册的邮箱表现一致。未注册的手机号在登录时自动注册
+func (s *AuthService) SendLoginCode(target, ip string) error {
+	verification := NewVerificationService()
+	channel, target, err := verification.NormalizeTarget(target)
+	if err != nil {
+		return err
+	}
+	code, err := util.IssueVerifyCode(VerifyPurposeLogin, target, ip)
+	if err != nil {
+		return err
+	}
+	if channel == util.ChannelEmail {
+		if _, err := model.GetUserByEmail(target); err != nil {
+			return nil
+		}
+	}
+	return verification.deliverCode(VerifyPurposeLogin, channel, target, code)
+}
+
+// LoginWithCode 验证码登录，返回用户、登录凭证以及是否为本次自动注册的新用户
+func (s *AuthService) LoginWithCode(target, code string, info util.SessionInfo) (*model.User, *TokenPair, bool, error) {
+	verification := NewVerificationService()
+	channel, target, err := verification.NormalizeTarget(target)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if err := verification.CheckCode(VerifyPurposeLogin, target, code); err != nil {
+		var user *model.User
+		if channel == util.ChannelEmail {
+			user, _ = model.GetUserByEmail(target)
+		} else {
+			user, _ = model.GetUserByPhone(target)
+		}
+		if user != nil {
+			recordUserLogin(user.ID, info, model.LoginEventCode, err)
+		}
+		return nil, nil, false, err
+	}
+
+	created := false
+	var user *model.User
+	if channel == util.ChannelEmail {
+		if user, err = model.GetUserByEmail(target); err != nil {
+			return nil, nil, false, util.ErrVerifyCodeInvalid
+		}
+	} else if user, err = model.GetUserByPhone(target); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, false, err
+		}
+		if user, err = NewUserService().RegisterByPhone(target); err != nil {
+			return nil, nil, false, err
+		}
+		created = true
+	}
+
+	// 能收到验证码即证明持有该手机号/邮箱
+	if channel == util.ChannelEmail && user.EmailVerified != 1 {
+		if err := model.SetUserEmailVerified(user.ID, true); err == nil {
+			user.EmailVerified = 1
+		}
+	} else if channel == util.ChannelSMS && user.PhoneVerified != 1 {
+		if err := model.SetUserPhoneVerified(user.ID, true); err == nil {
+			user.PhoneVerified = 1
+		}
+	}
+
+	tokens, err := s.IssueTokens(user, info)
+	recordUserLogin(user.ID, info, model.LoginEventCode, err)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return user, tokens, created, nil
+}
+
+// RequestPasswordReset 申请找回密码，向账号绑定的手机号或邮箱发送一次性重置链接。
+// 无论账号是否存在都返回成功，避免泄露账号信息
+func (s *AuthService) RequestPasswordReset(target string) error {
+	channel, target, err := NewVerificationService().NormalizeTarget(target)
+	if err != nil {
+		return err
+	}
+	allowed, err := util.AllowPasswordResetRequest(target)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrResetTooFrequent
+	}
+
+	var user *model.User
+	if channel == util.ChannelEmail {
+		user, err = model.GetUserByEmail(target)
+	} else {
+		user, err = model.GetUserByPhone(target)
+	}
+	if err != nil || user.Status != 1 {
+		return nil
+	}
+
+	token, err := util.IssuePasswordResetToken(user.ID, user.Password)
+	if err != nil {
+		return err
+	}
+	link := util.GetConfig().Security.ResetPasswordURL + "?token=" + token
+	NewNotificationService().PasswordResetLink(user, channel, target, link)
+	return nil
+}
+
+// ResetPassword 使用重置令牌设置新密码，完成后注销该用户的全部会话
+func (s *AuthService) ResetPassword(token, newPassword string) error {
+	userID, err := util.ParsePasswordResetToken(token)
+	if err != nil {
+		return err
+	}
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return util.ErrResetTokenInvalid
+	}
+	if err := util.ConsumePasswordResetToken(token, user.Password); err != nil {
+		return err
+	}
+
+	hashedPassword, err := util.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	user.UpdatedAt = time.Now()
+	if err := model.UpdateUser(user); err != nil {
+		return err
+	}
+
+	NewNotificationService().PasswordChanged(user, time.Now())
+	return s.RevokeUserSessions(user.ID)
+}
+
+// Refresh 用刷新令牌换取新的访问令牌，刷新令牌同时轮换
+func (s *AuthService) Refresh(refreshToken, ip string) (*TokenPair, error) {
+	userID, sessionID, newRefreshToken, err := util.RotateSession(refreshToken, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := model.GetUserByID(userID)
+	if err != nil || user.Status != 1 {
+		util.DeleteUserSessions(userID)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		return nil, ErrUserDisabled
+	}
+	return s.tokenPair(user, sessionID, newRefreshToken)
+}
+
+// Logout 注销刷新令牌所属的会话，该会话的访问令牌随之失效
+func (s *AuthService) Logout(refreshToken string) error {
+	return util.RevokeRefreshToken(refreshToken)
+}
+
+// RevokeUserSessions 注销用户的全部会话，修改密码、禁用或删除账号后调用
+func (s *AuthService) RevokeUserSessions(userID int) error {
+	return util.DeleteUserSessions(userID)
+}
+
+// ListSessions 列出用户的登录设备，并标记当前会话
+func (s *AuthService) ListSessions(userID int, currentSessionID string) ([]*util.Session, error) {
+	sessions, err := util.ListUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession 注销用户的某个登录设备
+func (s *AuthService) RevokeSession(userID int, sessionID string) error {
+	found, err := util.DeleteUserSession(userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions 注销除当前设备以外的全部登录设备
+func (s *AuthService) RevokeOtherSessions(userID int, currentSessionID string) error {
+	return util.DeleteOtherSessions(userID, currentSessionID)
+}
+
+// recordUserLogin 写入用户登录历史，err 为空表示登录成功
+func recordUserLogin(userID int, info util.SessionInfo, event string, err error) {
+	history := &model.UserLoginHistory{
+		UserID:    userID,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+		Status:    1,
+		Event:     event,
+	}
+	if err != nil {
+		reason := []rune(err.Error())
+		if len(reason) > 100 {
+			reason = reason[:100]
+		}
+		history.Status = 0
+		history.FailReason = string(reason)
+	}
+	if err := model.CreateUserLoginHistory(history); err != nil {
+		log.Printf("记录登录历史失败: user=%d err=%v", userID, err)
+	}
+}
+
+func (s *AuthService) tokenPair(user *model.User, sessionID, refreshToken string) (*TokenPair, error) {
+	token, err := util.GenerateToken(user.ID, user.Username, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    util.GetConfig().JWT.AccessExpire,
+	}, nil
+}
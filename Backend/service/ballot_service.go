@@ -0,0 +1,422 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+var (
+	ErrNotBallotTicketType = errors.New("该票种不支持抽签")
+	ErrBallotClosed        = errors.New("不在抽签登记时间内")
+	ErrBallotEntryExists   = errors.New("已登记该票种的抽签")
+	ErrBallotEntryNotFound = errors.New("抽签登记不存在")
+	ErrBallotNotEnded      = errors.New("抽签登记尚未结束")
+	ErrBallotDrawPending   = errors.New("上一轮抽签结果尚未公布")
+	ErrBallotPaymentOpen   = errors.New("上一轮中签用户的支付期限尚未结束")
+	ErrBallotNoDraw        = errors.New("没有待公布的抽签结果")
+	ErrBallotNoEntrants    = errors.New("没有可参与抽签的登记")
+	ErrBallotNoStock       = errors.New("没有可供抽签的库存")
+)
+
+// 默认中签支付期限
+const defaultBallotPaymentHours = 24
+
+type BallotService struct{}
+
+func NewBallotService() *BallotService {
+	return &BallotService{}
+}
+
+// BallotSummary 票种抽签概况
+type BallotSummary struct {
+	TicketType *model.TicketType   `json:"ticket_type"`
+	Draws      []*model.BallotDraw `json:"draws"`
+	Pending    int                 `json:"pending"`
+	Won        int                 `json:"won"`
+	Lost       int                 `json:"lost"`
+	Lapsed     int                 `json:"lapsed"`
+}
+
+// PublishResult 公布抽签结果后的统计
+type PublishResult struct {
+	Round           int       `json:"round"`
+	Orders          int       `json:"orders"`
+	Failed          int       `json:"failed"`
+	PaymentDeadline time.Time `json:"payment_deadline"`
+}
+
+func (s *BallotService) GetEntries(userID int) ([]*model.BallotEntry, error) {
+	return model.GetUserBallotEntries(userID)
+}
+
+// Enter 在登记时间窗口内登记抽签，实名演出需按张指定观演人
+func (s *BallotService) Enter(userID, ticketTypeID, quantity int, attendeeIDs []int) (*model.BallotEntry, error) {
+	ticketType, err := s.getBallotTicketType(ticketTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canAccessTicketType(userID, ticketType) {
+		return nil, ErrTicketTypeNotFound
+	}
+
+	if !ballotOpen(ticketType, time.Now()) {
+		return nil, ErrBallotClosed
+	}
+
+	if quantity <= 0 || quantity > util.GetConfig().Seckill.MaxQuantityPerUser {
+		return nil, ErrTicketLimitExceeded
+	}
+
+	performance, err := model.GetPerformanceByID(ticketType.PerformanceID)
+	if err != nil || performance == nil {
+		return nil, ErrPerformanceNotFound
+	}
+
+	if performance.RequireRealName == 1 && len(attendeeIDs) == 0 {
+		return nil, ErrRealNameRequired
+	}
+	if _, err := loadOrderAttendees(userID, []OrderLine{{
+		TicketTypeID: ticketTypeID,
+		Quantity:     quantity,
+		AttendeeIDs:  attendeeIDs,
+	}}); err != nil {
+		return nil, err
+	}
+
+	if _, err := model.GetBallotEntry(ticketTypeID, userID); err == nil {
+		return nil, ErrBallotEntryExists
+	}
+
+	entry := &model.BallotEntry{
+		TicketTypeID: ticketTypeID,
+		UserID:       userID,
+		Quantity:     quantity,
+		AttendeeIDs:  joinIDs(attendeeIDs),
+		Status:       model.BallotEntryPending,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if err := model.CreateBallotEntry(entry); err != nil {
+		if util.IsDuplicateKeyError(err) {
+			return nil, ErrBallotEntryExists
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Withdraw 登记截止前撤销抽签登记
+func (s *BallotService) Withdraw(userID, ticketTypeID int) error {
+	ticketType, err := s.getBallotTicketType(ticketTypeID)
+	if err != nil {
+		return err
+	}
+
+	if !ballotOpen(ticketType, time.Now()) {
+		return ErrBallotClosed
+	}
+
+	entry, err := model.GetBallotEntry(ticketTypeID, userID)
+	if err != nil {
+		return ErrBallotEntryNotFound
+	}
+	return model.DeleteBallotEntry(entry.ID)
+}
+
+func (s *BallotService) GetSummary(ticketTypeID int) (*BallotSummary, error) {
+	ticketType, err := s.getBallotTicketType(ticketTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	draws, err := model.GetBallotDraws(ticketTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := model.CountBallotEntries(ticketTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BallotSummary{
+		TicketType: ticketType,
+		Draws:      draws,
+		Pending:    counts[model.BallotEntryPending],
+		Won:        counts[model.BallotEntryWon],
+		Lost:       counts[model.BallotEntryLost],
+		Lapsed:     counts[model.BallotEntryLapsed],
+	}, nil
+}
+
+// Draw 执行一轮抽签，seed 为空时随机生成。
+// 每条登记按 sha256(seed + ":" + 登记ID) 的十六进制值升序排列，依次分配整单数量，
+// 数量超过剩余库存的登记跳过，直至库存分配完毕。相同的种子和登记总能得到相同的结果。
+// 第二轮起先将上一轮逾期未支付的中签登记作废，归还库存后在未中签的登记中重新抽取。
+func (s *BallotService) Draw(ticketTypeID int, seed string) (*model.BallotDraw, error) {
+	ticketType, err := s.getBallotTicketType(ticketTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ticketType.BallotEndTime == nil || time.Now().Before(*ticketType.BallotEndTime) {
+		return nil, ErrBallotNotEnded
+	}
+
+	round := 1
+	if last, err := model.GetLatestBallotDraw(ticketTypeID); err == nil {
+		if last.Status != model.BallotDrawPublished {
+			return nil, ErrBallotDrawPending
+		}
+		if last.PaymentDeadline != nil && time.Now().Before(*last.PaymentDeadline) {
+			return nil, ErrBallotPaymentOpen
+		}
+		if err := s.lapseUnpaidWinners(ticketTypeID); err != nil {
+			return nil, err
+		}
+		round = last.Round + 1
+
+		// 归还库存后重新读取
+		if ticketType, err = model.GetTicketTypeByID(ticketTypeID); err != nil {
+			return nil, ErrTicketTypeNotFound
+		}
+	}
+
+	candidates, err := model.GetBallotEntriesByStatus(ticketTypeID, model.BallotEntryPending)
+	if err != nil {
+		return nil, err
+	}
+	if round > 1 {
+		losers, err := model.GetBallotEntriesByStatus(ticketTypeID, model.BallotEntryLost)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, losers...)
+	}
+	if len(candidates) == 0 {
+		return nil, ErrBallotNoEntrants
+	}
+	if ticketType.Stock <= 0 {
+		return nil, ErrBallotNoStock
+	}
+
+	if seed == "" {
+		if seed, err = generateBallotSeed(); err != nil {
+			return nil, err
+		}
+	}
+
+	winners, quantity := drawBallot(candidates, seed, ticketType.Stock)
+
+	draw := &model.BallotDraw{
+		TicketTypeID:   ticketTypeID,
+		Round:          round,
+		Seed:           seed,
+		Stock:          ticketType.Stock,
+		EntryCount:     len(candidates),
+		WinnerCount:    len(winners),
+		WinnerQuantity: quantity,
+		Status:         model.BallotDrawDrawn,
+		CreatedAt:      time.Now(),
+	}
+
+	tx := util.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if round > 1 {
+		if err := model.ResetBallotEntriesTx(tx, ticketTypeID, model.BallotEntryLost); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := model.CreateBallotDrawTx(tx, draw); err != nil {
+		tx.Rollback()
+		if util.IsDuplicateKeyError(err) {
+			return nil, ErrBallotDrawPending
+		}
+		return nil, err
+	}
+	if err := model.MarkBallotWinnersTx(tx, winners, round); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return draw, nil
+}
+
+// Publish 公布最近一轮抽签结果，为中签用户创建待支付订单，支付截止时间为公布后 paymentHours 小时。
+// 每条中签登记在各自的下单事务中标记为已中签，全部处理完后才将批次标记为已公布；
+// 中途失败时批次仍为待公布，再次调用只为尚未下单的中签登记下单。
+func (s *BallotService) Publish(ctx context.Context, ticketTypeID, paymentHours int) (*PublishResult, error) {
+	if _, err := s.getBallotTicketType(ticketTypeID); err != nil {
+		return nil, err
+	}
+
+	draw, err := model.GetLatestBallotDraw(ticketTypeID)
+	if err != nil || draw.Status != model.BallotDrawDrawn {
+		return nil, ErrBallotNoDraw
+	}
+
+	if paymentHours <= 0 {
+		paymentHours = defaultBallotPaymentHours
+	}
+	deadline := time.Now().Add(time.Duration(paymentHours) * time.Hour)
+
+	winners, err := model.GetBallotWinners(ticketTypeID, draw.Round)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PublishResult{Round: draw.Round, PaymentDeadline: deadline}
+	orderService := NewOrderService()
+	for _, entry := range winners {
+		_, err := orderService.placeOrder(ctx, entry.UserID, []OrderLine{{
+			TicketTypeID: ticketTypeID,
+			Quantity:     entry.Quantity,
+			AttendeeIDs:  splitIDs(entry.AttendeeIDs),
+		}}, orderOptions{Ballot: true, BallotEntryID: entry.ID, ExpireTime: deadline})
+		if err != nil {
+			// 无法下单的中签登记仍为待开奖，随后与未中签的登记一起标记为未中签，可参与下一轮
+			log.Printf("抽签下单失败: entry=%d, err=%v", entry.ID, err)
+			result.Failed++
+			continue
+		}
+		result.Orders++
+	}
+
+	if err := model.MarkBallotLosers(ticketTypeID); err != nil {
+		return nil, err
+	}
+
+	ok, err := model.PublishBallotDraw(draw.ID, deadline)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrBallotNoDraw
+	}
+
+	return result, nil
+}
+
+// lapseUnpaidWinners 作废逾期未支付的中签订单并归还库存
+func (s *BallotService) lapseUnpaidWinners(ticketTypeID int) error {
+	entries, err := model.GetUnpaidBallotWinners(ticketTypeID)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		order, err := model.GetOrderByID(entry.OrderID)
+		if err != nil {
+			return err
+		}
+		// 已由过期关单任务关闭的订单库存已归还
+		if order.Status == 0 {
+			if _, err := CloseOrder(order, 0, 2); err != nil {
+				return err
+			}
+		}
+		if err := model.UpdateBallotEntryResult(entry.ID, model.BallotEntryLapsed, entry.OrderID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BallotService) getBallotTicketType(ticketTypeID int) (*model.TicketType, error) {
+	ticketType, err := model.GetTicketTypeByID(ticketTypeID)
+	if err != nil {
+		return nil, ErrTicketTypeNotFound
+	}
+	if ticketType.SaleMode != model.SaleModeBallot {
+		return nil, ErrNotBallotTicketType
+	}
+	return ticketType, nil
+}
+
+func ballotOpen(ticketType *model.TicketType, now time.Time) bool {
+	if ticketType.BallotStartTime == nil || ticketType.BallotEndTime == nil {
+		return false
+	}
+	return !now.Before(*ticketType.BallotStartTime) && now.Before(*ticketType.BallotEndTime)
+}
+
+// drawBallot 按种子对登记排序并分配库存，返回中签登记ID和中签总张数
+func drawBallot(entries []*model.BallotEntry, seed string, stock int) ([]int, int) {
+	keys := make(map[int]string, len(entries))
+	for _, entry := range entries {
+		sum := sha256.Sum256([]byte(seed + ":" + strconv.Itoa(entry.ID)))
+		keys[entry.ID] = hex.EncodeToString(sum[:])
+	}
+
+	ordered := make([]*model.BallotEntry, len(entries))
+	copy(ordered, entries)
+	sort.Slice(ordered, func(i, j int) bool {
+		if keys[ordered[i].ID] != keys[ordered[j].ID] {
+			return keys[ordered[i].ID] < keys[ordered[j].ID]
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+
+	var (
+		winners  []int
+		quantity int
+	)
+	for _, entry := range ordered {
+		if quantity == stock {
+			break
+		}
+		if entry.Quantity > stock-quantity {
+			continue
+		}
+		winners = append(winners, entry.ID)
+		quantity += entry.Quantity
+	}
+	return winners, quantity
+}
+
+func generateBallotSeed() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitIDs(value string) []int {
+	if value == "" {
+		return nil
+	}
+	var ids []int
+	for _, part := range strings.Split(value, ",") {
+		if id, err := strconv.Atoi(part); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
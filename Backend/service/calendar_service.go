@@ -0,0 +1,116 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+var (
+	ErrCalendarFeedNotFound = errors.New("日历订阅不存在或已失效")
+	ErrCalendarOrderNotPaid = errors.New("只有已支付的订单可以导出日历")
+)
+
+type CalendarService struct{}
+
+func NewCalendarService() *CalendarService {
+	return &CalendarService{}
+}
+
+// UserCalendar 生成用户尚未结束的演出日历，每个已支付订单一个事件
+func (s *CalendarService) UserCalendar(userID int) ([]byte, error) {
+	orders, err := model.GetUserUpcomingPaidOrders(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	events := make([]util.ICSEvent, 0, len(orders))
+	for _, order := range orders {
+		events = append(events, orderEvent(order))
+	}
+	return util.BuildICS("我的演出", events), nil
+}
+
+// OrderCalendar 生成单个订单的日历文件
+func (s *CalendarService) OrderCalendar(userID, orderID int) ([]byte, *model.Order, error) {
+	order, err := model.GetOrderByID(orderID)
+	if err != nil || order.UserID != userID {
+		return nil, nil, ErrOrderNotFound
+	}
+	if order.Status != 1 || order.Performance == nil {
+		return nil, nil, ErrCalendarOrderNotPaid
+	}
+	return util.BuildICS(order.Performance.Title, []util.ICSEvent{orderEvent(order)}), order, nil
+}
+
+// GetFeed 获取用户的日历订阅，未开启时返回 nil
+func (s *CalendarService) GetFeed(userID int) *model.CalendarFeed {
+	feed, err := model.GetCalendarFeedByUserID(userID)
+	if err != nil {
+		return nil
+	}
+	return feed
+}
+
+// ResetFeed 开启或重新生成日历订阅，旧链接立即失效。令牌只在此时返回一次
+func (s *CalendarService) ResetFeed(userID int) (string, *model.CalendarFeed, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	feed, err := model.SaveCalendarFeed(userID, util.HashSensitive(token))
+	if err != nil {
+		return "", nil, err
+	}
+	return token, feed, nil
+}
+
+// RevokeFeed 关闭日历订阅
+func (s *CalendarService) RevokeFeed(userID int) error {
+	return model.DeleteCalendarFeed(userID)
+}
+
+// FeedCalendar 按订阅令牌生成日历，供日历应用定期拉取
+func (s *CalendarService) FeedCalendar(token string) ([]byte, error) {
+	feed, err := model.GetCalendarFeedByTokenHash(util.HashSensitive(token))
+	if err != nil {
+		return nil, ErrCalendarFeedNotFound
+	}
+	model.TouchCalendarFeed(feed.ID)
+	return s.UserCalendar(feed.UserID)
+}
+
+func orderEvent(order *model.Order) util.ICSEvent {
+	performance := order.Performance
+
+	lines := []string{"订单号：" + order.OrderNo}
+	for _, item := range order.StockItems() {
+		name := "票种 " + strconv.Itoa(item.TicketTypeID)
+		if item.TicketType != nil {
+			name = item.TicketType.Name
+		} else if order.TicketType != nil && order.TicketType.ID == item.TicketTypeID {
+			name = order.TicketType.Name
+		}
+		lines = append(lines, name+" × "+strconv.Itoa(item.Quantity))
+	}
+	if performance.Performer != "" {
+		lines = append(lines, "演出者："+performance.Performer)
+	}
+
+	return util.ICSEvent{
+		UID:         "order-" + order.OrderNo + "@ticket-system",
+		Summary:     performance.Title,
+		Location:    performance.Venue,
+		Description: strings.Join(lines, "\n"),
+		Start:       performance.StartTime,
+		End:         performance.EndTime,
+		Updated:     performance.UpdatedAt,
+	}
+}
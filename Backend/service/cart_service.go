@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+var (
+	ErrCartItemNotFound = errors.New("购物车条目不存在")
+	ErrCartEmpty        = errors.New("购物车为空")
+)
+
+type CartService struct{}
+
+func NewCartService() *CartService {
+	return &CartService{}
+}
+
+// Cart 购物车及汇总
+type Cart struct {
+	Items    []*model.CartItem `json:"items"`
+	Quantity int               `json:"quantity"`
+	Amount   util.Money        `json:"amount"`
+}
+
+func (s *CartService) GetCart(userID int) (*Cart, error) {
+	items, err := model.GetCartItems(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按当前价格规则估算金额，实际金额以下单时为准
+	var ticketTypes []*model.TicketType
+	for _, item := range items {
+		if item.TicketType != nil {
+			ticketTypes = append(ticketTypes, item.TicketType)
+		}
+	}
+	if err := NewPricingService().FillPrices(ticketTypes); err != nil {
+		return nil, err
+	}
+
+	cart := &Cart{Items: items}
+	for _, item := range items {
+		cart.Quantity += item.Quantity
+		if item.TicketType != nil {
+			cart.Amount += item.TicketType.CurrentPrice.Mul(item.Quantity)
+		}
+	}
+	return cart, nil
+}
+
+// AddItem 加入购物车，已有同票种条目时累加数量
+func (s *CartService) AddItem(userID, ticketTypeID, quantity int) (*model.CartItem, error) {
+	ticketType, err := model.GetTicketTypeByID(ticketTypeID)
+	if err != nil || !canAccessTicketType(userID, ticketType) {
+		return nil, ErrTicketTypeNotFound
+	}
+	if ticketType.SaleMode == model.SaleModeBallot {
+		return nil, ErrBallotTicketType
+	}
+
+	item, err := model.GetCartItemByTicketType(userID, ticketTypeID)
+	if err == nil {
+		if err := s.checkQuantity(item.Quantity + quantity); err != nil {
+			return nil, err
+		}
+		item.Quantity += quantity
+		if err := model.UpdateCartItemQuantity(item.ID, item.Quantity); err != nil {
+			return nil, err
+		}
+		return model.GetCartItemByID(item.ID)
+	}
+
+	if err := s.checkQuantity(quantity); err != nil {
+		return nil, err
+	}
+
+	item = &model.CartItem{
+		UserID:       userID,
+		TicketTypeID: ticketTypeID,
+		Quantity:     quantity,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if err := model.CreateCartItem(item); err != nil {
+		return nil, err
+	}
+	return model.GetCartItemByID(item.ID)
+}
+
+func (s *CartService) UpdateItem(userID, itemID, quantity int) (*model.CartItem, error) {
+	item, err := s.getOwnItem(userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkQuantity(quantity); err != nil {
+		return nil, err
+	}
+
+	if err := model.UpdateCartItemQuantity(item.ID, quantity); err != nil {
+		return nil, err
+	}
+	item.Quantity = quantity
+	return item, nil
+}
+
+func (s *CartService) RemoveItem(userID, itemID int) error {
+	item, err := s.getOwnItem(userID, itemID)
+	if err != nil {
+		return err
+	}
+	return model.DeleteCartItem(item.ID)
+}
+
+// Checkout 将购物车条目结算为一个订单，itemIDs 为空时结算全部条目，attendees 按票种指定观演人，couponCode 为可选的优惠码。
+// 已结算的条目在下单事务中一并移除。
+func (s *CartService) Checkout(ctx context.Context, userID int, itemIDs []int, attendees map[int][]int, couponCode string) (*model.Order, error) {
+	items, err := model.GetCartItems(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[int]bool, len(itemIDs))
+	for _, id := range itemIDs {
+		selected[id] = true
+	}
+
+	var (
+		lines   []OrderLine
+		usedIDs []int
+	)
+	for _, item := range items {
+		if len(selected) > 0 && !selected[item.ID] {
+			continue
+		}
+		lines = append(lines, OrderLine{
+			TicketTypeID: item.TicketTypeID,
+			Quantity:     item.Quantity,
+			AttendeeIDs:  attendees[item.TicketTypeID],
+		})
+		usedIDs = append(usedIDs, item.ID)
+	}
+
+	if len(lines) == 0 {
+		return nil, ErrCartEmpty
+	}
+
+	return NewOrderService().placeOrder(ctx, userID, lines, orderOptions{CouponCode: couponCode, CartItemIDs: usedIDs})
+}
+
+func (s *CartService) getOwnItem(userID, itemID int) (*model.CartItem, error) {
+	item, err := model.GetCartItemByID(itemID)
+	if err != nil || item.UserID != userID {
+		return nil, ErrCartItemNotFound
+	}
+	return item, nil
+}
+
+func (s *CartService) checkQuantity(quantity int) error {
+	if quantity > util.GetConfig().Seckill.MaxQuantityPerUser {
+		return ErrTicketLimitExceeded
+	}
+	return nil
+}
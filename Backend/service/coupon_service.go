@@ -0,0 +1,298 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrCouponNotFound      = errors.New("优惠券不存在")
+	ErrCouponInvalid       = errors.New("优惠码无效")
+	ErrCouponExpired       = errors.New("优惠券不在有效期内")
+	ErrCouponNotApplicable = errors.New("优惠券不适用于所选票种")
+	ErrCouponMinSpend      = errors.New("未达到优惠券的最低消费金额")
+	ErrCouponUserLimit     = errors.New("已达到该优惠券的使用次数上限")
+	ErrCouponExhausted     = errors.New("优惠券已被领完")
+	ErrCouponInUse         = errors.New("优惠券已被使用，无法删除，请停用")
+	ErrCouponParams        = errors.New("优惠券参数错误")
+	ErrCouponCodeCount     = errors.New("生成数量须在 1 到 1000 之间")
+	ErrCouponCodeExists    = errors.New("优惠码已存在")
+)
+
+const (
+	maxCouponCodesPerBatch = 1000
+	couponCodeLength       = 8
+)
+
+// CouponInput 创建或更新优惠券的参数，Code 不为空时同时创建一个公开优惠码
+type CouponInput struct {
+	Name         string
+	Type         int
+	Value        util.Money
+	MaxDiscount  util.Money
+	MinSpend     util.Money
+	StartTime    time.Time
+	EndTime      time.Time
+	TotalLimit   int
+	PerUserLimit int
+	ScopeType    int
+	ScopeIDs     []int
+	Status       int
+	Code         string
+}
+
+type CouponService struct{}
+
+func NewCouponService() *CouponService {
+	return &CouponService{}
+}
+
+func (s *CouponService) Create(input CouponInput) (*model.Coupon, error) {
+	if err := validateCouponInput(input); err != nil {
+		return nil, err
+	}
+
+	coupon := &model.Coupon{CreatedAt: time.Now()}
+	applyCouponInput(coupon, input)
+	if err := model.CreateCoupon(coupon); err != nil {
+		return nil, err
+	}
+
+	if input.Code != "" {
+		code := &model.CouponCode{
+			CouponID:  coupon.ID,
+			Code:      normalizeCode(input.Code),
+			Status:    1,
+			CreatedAt: time.Now(),
+		}
+		if err := model.CreateCouponCode(code); err != nil {
+			model.DeleteCoupon(coupon.ID)
+			if util.IsDuplicateKeyError(err) {
+				return nil, ErrCouponCodeExists
+			}
+			return nil, err
+		}
+	}
+	return coupon, nil
+}
+
+func (s *CouponService) Update(id int, input CouponInput) (*model.Coupon, error) {
+	coupon, err := model.GetCouponByID(id)
+	if err != nil {
+		return nil, ErrCouponNotFound
+	}
+	if err := validateCouponInput(input); err != nil {
+		return nil, err
+	}
+
+	applyCouponInput(coupon, input)
+	if err := model.UpdateCoupon(coupon); err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// Delete 删除优惠券，已被使用过的只能停用
+func (s *CouponService) Delete(id int) error {
+	if _, err := model.GetCouponByID(id); err != nil {
+		return ErrCouponNotFound
+	}
+	if model.HasCouponUsages(id) {
+		return ErrCouponInUse
+	}
+	return model.DeleteCoupon(id)
+}
+
+// GenerateCodes 为优惠券批量生成优惠码，maxUses 为每个码的可用次数
+func (s *CouponService) GenerateCodes(couponID, count, maxUses int) ([]*model.CouponCode, error) {
+	if _, err := model.GetCouponByID(couponID); err != nil {
+		return nil, ErrCouponNotFound
+	}
+	if count <= 0 || count > maxCouponCodesPerBatch {
+		return nil, ErrCouponCodeCount
+	}
+	if maxUses <= 0 {
+		maxUses = 1
+	}
+
+	codes := make([]*model.CouponCode, 0, count)
+	for len(codes) < count {
+		value, err := randomCode(couponCodeLength)
+		if err != nil {
+			return nil, err
+		}
+		code := &model.CouponCode{
+			CouponID:  couponID,
+			Code:      value,
+			MaxUses:   maxUses,
+			Status:    1,
+			CreatedAt: time.Now(),
+		}
+		if err := model.CreateCouponCode(code); err != nil {
+			if util.IsDuplicateKeyError(err) {
+				continue
+			}
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// couponApplication 下单时优惠券的计算结果
+type couponApplication struct {
+	Coupon   *model.Coupon
+	Code     *model.CouponCode
+	Discount util.Money
+}
+
+// applyCouponTx 在下单事务中校验优惠码并占用使用次数。
+// 优惠金额按适用范围内的票款计算：固定金额券不超过适用票款，折扣券按万分比四舍五入到分并受优惠上限约束。
+func applyCouponTx(tx *gorm.DB, userID int, code string, performance *model.Performance, items []model.OrderItem) (*couponApplication, error) {
+	couponCode, err := model.GetCouponCodeByCode(normalizeCode(code))
+	if err != nil || couponCode.Status != 1 {
+		return nil, ErrCouponInvalid
+	}
+
+	coupon, err := model.GetCouponByID(couponCode.CouponID)
+	if err != nil || coupon.Status != 1 {
+		return nil, ErrCouponInvalid
+	}
+
+	now := time.Now()
+	if now.Before(coupon.StartTime) || now.After(coupon.EndTime) {
+		return nil, ErrCouponExpired
+	}
+
+	var eligible util.Money
+	for _, item := range items {
+		if couponApplies(coupon, performance, item.TicketTypeID) {
+			eligible += item.Amount
+		}
+	}
+	if eligible <= 0 {
+		return nil, ErrCouponNotApplicable
+	}
+	if eligible < coupon.MinSpend {
+		return nil, ErrCouponMinSpend
+	}
+
+	ok, err := model.UseCouponTx(tx, coupon.ID, couponCode.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrCouponExhausted
+	}
+
+	// 优惠券行已被 UseCouponTx 锁定到事务结束，同一用户的并发下单在此排队，不会同时通过每人限用次数的检查
+	if coupon.PerUserLimit > 0 {
+		used, err := model.CountUserCouponUsagesTx(tx, coupon.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= coupon.PerUserLimit {
+			return nil, ErrCouponUserLimit
+		}
+	}
+
+	return &couponApplication{
+		Coupon:   coupon,
+		Code:     couponCode,
+		Discount: couponDiscount(coupon, eligible),
+	}, nil
+}
+
+func couponDiscount(coupon *model.Coupon, eligible util.Money) util.Money {
+	var discount util.Money
+	switch coupon.Type {
+	case model.CouponTypeFixed:
+		discount = coupon.Value
+	case model.CouponTypePercent:
+		// 折扣百分比以两位小数定点存储，数值即为万分比
+		discount = eligible.MulRate(int64(coupon.Value))
+		if coupon.MaxDiscount > 0 {
+			discount = discount.Min(coupon.MaxDiscount)
+		}
+	}
+	return discount.Min(eligible)
+}
+
+func couponApplies(coupon *model.Coupon, performance *model.Performance, ticketTypeID int) bool {
+	var target int
+	switch coupon.ScopeType {
+	case model.CouponScopeAll:
+		return true
+	case model.CouponScopeCategory:
+		target = performance.CategoryID
+	case model.CouponScopePerformance:
+		target = performance.ID
+	case model.CouponScopeTicketType:
+		target = ticketTypeID
+	default:
+		return false
+	}
+	for _, id := range splitIDs(coupon.ScopeIDs) {
+		if id == target {
+			return true
+		}
+	}
+	return false
+}
+
+func validateCouponInput(input CouponInput) error {
+	if input.Name == "" || !input.EndTime.After(input.StartTime) {
+		return ErrCouponParams
+	}
+	switch input.Type {
+	case model.CouponTypeFixed:
+		if input.Value <= 0 {
+			return ErrCouponParams
+		}
+	case model.CouponTypePercent:
+		if input.Value <= 0 || input.Value >= 10000 {
+			return ErrCouponParams
+		}
+	default:
+		return ErrCouponParams
+	}
+	if input.ScopeType < model.CouponScopeAll || input.ScopeType > model.CouponScopeTicketType {
+		return ErrCouponParams
+	}
+	if input.ScopeType != model.CouponScopeAll && len(input.ScopeIDs) == 0 {
+		return ErrCouponParams
+	}
+	if input.MinSpend < 0 || input.MaxDiscount < 0 || input.TotalLimit < 0 || input.PerUserLimit < 0 {
+		return ErrCouponParams
+	}
+	return nil
+}
+
+func applyCouponInput(coupon *model.Coupon, input CouponInput) {
+	coupon.Name = input.Name
+	coupon.Type = input.Type
+	coupon.Value = input.Value
+	coupon.MaxDiscount = input.MaxDiscount
+	coupon.MinSpend = input.MinSpend
+	coupon.StartTime = input.StartTime
+	coupon.EndTime = input.EndTime
+	coupon.TotalLimit = input.TotalLimit
+	coupon.PerUserLimit = input.PerUserLimit
+	coupon.ScopeType = input.ScopeType
+	coupon.ScopeIDs = joinIDs(input.ScopeIDs)
+	coupon.Status = input.Status
+	coupon.UpdatedAt = time.Now()
+}
+
+// couponDescription 订单优惠明细的说明文字
+func couponDescription(coupon *model.Coupon) string {
+	if coupon.Type == model.CouponTypePercent {
+		return coupon.Name + "（" + coupon.Value.String() + "% 折扣）"
+	}
+	return coupon.Name
+}
@@ -0,0 +1,463 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrDataExportTooFrequent = errors.New("申请过于频繁，请稍后再试")
+	ErrDataExportInProgress  = errors.New("已有正在生成的导出，请稍后查看")
+	ErrDataExportNotFound    = errors.New("导出记录不存在")
+	ErrDataExportNotReady    = errors.New("导出文件尚未生成或已过期")
+)
+
+const (
+	dataExportBatchSize     = 20
+	dataExportScanInterval  = time.Minute
+	dataExportStaleDuration = 30 * time.Minute // 超过该时间仍在生成中的任务视为服务重启中断
+	dataExportListLimit     = 10
+)
+
+// dataExportWake 有新的导出申请时唤醒后台任务，不必等到下一次定时扫描
+var dataExportWake = make(chan struct{}, 1)
+
+type DataExportService struct{}
+
+func NewDataExportService() *DataExportService {
+	return &DataExportService{}
+}
+
+// RequestExport 申请导出个人数据，文件由后台任务异步生成。被限流时返回还需等待的时间
+func (s *DataExportService) RequestExport(userID int) (*model.UserDataExport, time.Duration, error) {
+	unfinished, err := model.HasUnfinishedDataExport(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if unfinished {
+		return nil, 0, ErrDataExportInProgress
+	}
+
+	allowed, retryAfter, err := util.AllowDataExportRequest(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !allowed {
+		return nil, retryAfter, ErrDataExportTooFrequent
+	}
+
+	export := &model.UserDataExport{UserID: userID, Status: model.DataExportPending}
+	if err := model.CreateUserDataExport(export); err != nil {
+		util.ClearDataExportCooldown(userID)
+		return nil, 0, err
+	}
+
+	select {
+	case dataExportWake <- struct{}{}:
+	default:
+	}
+	return export, 0, nil
+}
+
+// List 用户最近的导出记录
+func (s *DataExportService) List(userID int) ([]*model.UserDataExport, error) {
+	return model.GetUserDataExports(userID, dataExportListLimit)
+}
+
+// DownloadURL 生成好的导出文件的下载地址，令牌在文件过期时同时失效
+func (s *DataExportService) DownloadURL(export *model.UserDataExport) string {
+	if export.StatusName() != "ready" {
+		return ""
+	}
+	token := util.SignDataExportToken(export.ID, export.UserID, *export.ExpiresAt)
+	return fmt.Sprintf("/api/download/user-data/%d?token=%s", export.ID, token)
+}
+
+// Download 校验下载令牌，令牌必须属于当前登录用户，返回可下载的导出记录
+func (s *DataExportService) Download(userID, exportID int, token string) (*model.UserDataExport, error) {
+	tokenExportID, tokenUserID, err := util.ParseDataExportToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if tokenExportID != exportID || tokenUserID != userID {
+		return nil, util.ErrDataExportTokenInvalid
+	}
+
+	export, err := model.GetUserDataExport(userID, exportID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDataExportNotFound
+		}
+		return nil, err
+	}
+	if export.StatusName() != "ready" {
+		return nil, ErrDataExportNotReady
+	}
+	return export, nil
+}
+
+// StartDataExportWorker 生成排队中的导出文件并删除过期文件，ctx 取消后退出
+func StartDataExportWorker(ctx context.Context) {
+	ticker := time.NewTicker(dataExportScanInterval)
+	defer ticker.Stop()
+
+	ProcessDataExports()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-dataExportWake:
+			ProcessDataExports()
+		case <-ticker.C:
+			ProcessDataExports()
+			PurgeExpiredDataExports()
+		}
+	}
+}
+
+// ProcessDataExports 依次生成排队中的导出文件
+func ProcessDataExports() {
+	if err := model.RequeueStaleDataExports(time.Now().Add(-dataExportStaleDuration)); err != nil {
+		log.Printf("重置中断的导出任务失败: %v", err)
+	}
+
+	exports, err := model.GetPendingDataExports(dataExportBatchSize)
+	if err != nil {
+		log.Printf("查询导出任务失败: %v", err)
+		return
+	}
+
+	for _, export := range exports {
+		ok, err := model.ClaimDataExport(export.ID)
+		if err != nil {
+			log.Printf("领取导出任务失败: export=%d, err=%v", export.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		generateDataExport(export)
+	}
+}
+
+// PurgeExpiredDataExports 删除已过下载有效期的导出文件
+func PurgeExpiredDataExports() {
+	exports, err := model.GetExpiredDataExports(dataExportBatchSize)
+	if err != nil {
+		log.Printf("查询过期导出失败: %v", err)
+		return
+	}
+
+	for _, export := range exports {
+		if export.FilePath != "" {
+			if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+				log.Printf("删除导出文件失败: export=%d, err=%v", export.ID, err)
+				continue
+			}
+		}
+		if err := model.ExpireDataExport(export.ID); err != nil {
+			log.Printf("标记导出过期失败: export=%d, err=%v", export.ID, err)
+		}
+	}
+}
+
+func generateDataExport(export *model.UserDataExport) {
+	user, err := model.GetUserByID(export.UserID)
+	if err != nil {
+		model.FailDataExport(export.ID, "用户不存在")
+		return
+	}
+
+	cfg := util.GetConfig().Export
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		log.Printf("创建导出目录失败: %v", err)
+		model.FailDataExport(export.ID, "创建导出目录失败")
+		return
+	}
+
+	filePath := filepath.Join(cfg.Dir, fmt.Sprintf("user_data_%d_%d.zip", user.ID, export.ID))
+	size, err := writeDataExportArchive(filePath, user)
+	if err != nil {
+		os.Remove(filePath)
+		log.Printf("生成导出文件失败: export=%d, err=%v", export.ID, err)
+		model.FailDataExport(export.ID, "生成导出文件失败")
+		return
+	}
+
+	expiresAt := time.Now().Add(time.Duration(cfg.ExpireHours) * time.Hour)
+	if err := model.CompleteDataExport(export.ID, filePath, size, expiresAt); err != nil {
+		os.Remove(filePath)
+		log.Printf("保存导出结果失败: export=%d, err=%v", export.ID, err)
+		return
+	}
+	NewNotificationService().DataExportReady(user, expiresAt)
+}
+
+// writeDataExportArchive 将用户的个人数据写入 ZIP：结构化数据为 JSON，列表另附 CSV 便于表格软件查看
+func writeDataExportArchive(filePath string, user *model.User) (int64, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	if err := writeDataExportEntries(archive, user); err != nil {
+		archive.Close()
+		return 0, err
+	}
+	if err := archive.Close(); err != nil {
+		return 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func writeDataExportEntries(archive *zip.Writer, user *model.User) error {
+	identities, err := model.GetUserIdentities(user.ID)
+	if err != nil {
+		return err
+	}
+	attendees, err := model.GetUserAttendees(user.ID)
+	if err != nil {
+		return err
+	}
+	profile := map[string]interface{}{
+		"id":             user.ID,
+		"username":       user.Username,
+		"phone":          user.Phone,
+		"email":          user.Email,
+		"avatar":         user.Avatar,
+		"status":         user.Status,
+		"email_verified": user.EmailVerified,
+		"phone_verified": user.PhoneVerified,
+		"created_at":     user.CreatedAt,
+		"updated_at":     user.UpdatedAt,
+		"identities":     identities,
+		"attendees":      attendees,
+		"exported_at":    time.Now(),
+	}
+	if err := writeDataExportJSON(archive, "profile.json", profile); err != nil {
+		return err
+	}
+
+	setting, err := model.GetUserPrivacySettingByUserID(user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if setting != nil {
+		if err := writeDataExportJSON(archive, "privacy_settings.json", setting); err != nil {
+			return err
+		}
+	}
+
+	orders, err := model.GetAllUserOrders(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeDataExportJSON(archive, "orders.json", orders); err != nil {
+		return err
+	}
+	orderRows := [][]string{{"订单号", "演出", "数量", "应付金额", "优惠金额", "币种", "状态", "下单时间", "支付时间"}}
+	for _, order := range orders {
+		title := ""
+		if order.Performance != nil {
+			title = order.Performance.Title
+		}
+		orderRows = append(orderRows, []string{
+			order.OrderNo,
+			title,
+			strconv.Itoa(order.Quantity),
+			order.Amount.String(),
+			order.DiscountAmount.String(),
+			order.Currency,
+			dataExportOrderStatus(order.Status),
+			order.CreatedAt.Format("2006-01-02 15:04:05"),
+			dataExportTime(order.PaymentTime),
+		})
+	}
+	if err := writeDataExportCSV(archive, "orders.csv", orderRows); err != nil {
+		return err
+	}
+
+	tickets, err := model.GetAllUserTickets(user.ID)
+	if err != nil {
+		return err
+	}
+	ticketRows := [][]string{{"票号", "订单ID", "演出ID", "票种", "观演人", "证件号", "状态", "检票时间", "创建时间"}}
+	for _, ticket := range tickets {
+		ticketType := ""
+		if ticket.TicketType != nil {
+			ticketType = ticket.TicketType.Name
+		}
+		ticketRows = append(ticketRows, []string{
+			ticket.TicketNo,
+			strconv.Itoa(ticket.OrderID),
+			strconv.Itoa(ticket.PerformanceID),
+			ticketType,
+			ticket.AttendeeName,
+			ticket.IDNumberMasked,
+			dataExportTicketStatus(ticket.Status),
+			dataExportTime(ticket.CheckedInAt),
+			ticket.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	if err := writeDataExportCSV(archive, "tickets.csv", ticketRows); err != nil {
+		return err
+	}
+
+	histories, err := model.GetAllUserLoginHistory(user.ID)
+	if err != nil {
+		return err
+	}
+	historyRows := [][]string{{"时间", "IP", "设备", "方式", "结果", "失败原因"}}
+	for _, history := range histories {
+		result := "成功"
+		if history.Status != 1 {
+			result = "失败"
+		}
+		historyRows = append(historyRows, []string{
+			history.CreatedAt.Format("2006-01-02 15:04:05"),
+			history.IP,
+			history.UserAgent,
+			history.Event,
+			result,
+			history.FailReason,
+		})
+	}
+	if err := writeDataExportCSV(archive, "login_history.csv", historyRows); err != nil {
+		return err
+	}
+
+	return writeDataExportAvatars(archive, user.ID)
+}
+
+// writeDataExportAvatars 打包用户上传过的全部头像，包括已被替换的旧头像
+func writeDataExportAvatars(archive *zip.Writer, userID int) error {
+	pattern := filepath.Join(util.GetConfig().Upload.Path, "avatars", fmt.Sprintf("%d_*", userID))
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		dst, err := archive.Create("avatars/" + filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(dst, src)
+		}
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDataExportJSON(archive *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// writeDataExportCSV 写入带 BOM 的 CSV，以便 Excel 正确识别中文。登录记录中的 User-Agent 等内容可由他人控制，
+// 可能被 Excel 当作公式执行的单元格一律转为文本
+func writeDataExportCSV(archive *zip.Writer, name string, rows [][]string) error {
+	var buf bytes.Buffer
+	buf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(&buf)
+	for _, row := range rows {
+		escaped := make([]string, len(row))
+		for i, cell := range row {
+			escaped[i] = escapeCSVFormula(cell)
+		}
+		w.Write(escaped)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	dst, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(buf.Bytes())
+	return err
+}
+
+// escapeCSVFormula 以 = + - @ 制表符或回车开头的单元格前加单引号，防止 CSV 公式注入
+func escapeCSVFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func dataExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func dataExportOrderStatus(status int) string {
+	switch status {
+	case 0:
+		return "待支付"
+	case 1:
+		return "已支付"
+	case 2:
+		return "已取消"
+	case 3:
+		return "已退款"
+	default:
+		return strconv.Itoa(status)
+	}
+}
+
+func dataExportTicketStatus(status int) string {
+	switch status {
+	case model.TicketStatusPending:
+		return "待支付"
+	case model.TicketStatusValid:
+		return "有效"
+	case model.TicketStatusCheckedIn:
+		return "已检票"
+	case model.TicketStatusVoid:
+		return "已作废"
+	default:
+		return strconv.Itoa(status)
+	}
+}
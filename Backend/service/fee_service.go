@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+var (
+	ErrFeePolicyNotFound = errors.New("费用策略不存在")
+	ErrFeePolicyParams   = errors.New("费用策略参数错误")
+	ErrFeePolicyExists   = errors.New("该范围已存在费用策略")
+)
+
+// FeePolicyInput 创建或更新费用策略的参数
+type FeePolicyInput struct {
+	Name           string
+	ScopeType      int
+	ScopeID        int
+	ServiceFee     util.Money
+	ServiceFeeRate util.Rate
+	DeliveryFee    util.Money
+	TaxRate        util.Rate
+	TaxInclusive   int
+	Status         int
+}
+
+type FeeService struct{}
+
+func NewFeeService() *FeeService {
+	return &FeeService{}
+}
+
+func (s *FeeService) Create(input FeePolicyInput) (*model.FeePolicy, error) {
+	if err := validateFeePolicyInput(input); err != nil {
+		return nil, err
+	}
+
+	policy := &model.FeePolicy{CreatedAt: time.Now()}
+	applyFeePolicyInput(policy, input)
+	if err := model.CreateFeePolicy(policy); err != nil {
+		if util.IsDuplicateKeyError(err) {
+			return nil, ErrFeePolicyExists
+		}
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (s *FeeService) Update(id int, input FeePolicyInput) (*model.FeePolicy, error) {
+	policy, err := model.GetFeePolicyByID(id)
+	if err != nil {
+		return nil, ErrFeePolicyNotFound
+	}
+	if err := validateFeePolicyInput(input); err != nil {
+		return nil, err
+	}
+
+	applyFeePolicyInput(policy, input)
+	if err := model.UpdateFeePolicy(policy); err != nil {
+		if util.IsDuplicateKeyError(err) {
+			return nil, ErrFeePolicyExists
+		}
+		return nil, err
+	}
+	return policy, nil
+}
+
+// orderCharges 按费用策略计算订单金额构成，返回明细和应付合计。
+// 服务费 = 每张服务费 × 张数 + 票款 × 服务费比例；配送费每单收取一次；
+// 税基为优惠后的票款加服务费和配送费，含税价格只拆分列示税额，不含税价格在合计上加收税额。
+func orderCharges(policy *model.FeePolicy, quantity int, subtotal, discount util.Money) ([]model.OrderCharge, util.Money) {
+	charges := []model.OrderCharge{{Type: model.ChargeTicket, Description: "票款", Amount: subtotal}}
+	if discount > 0 {
+		charges = append(charges, model.OrderCharge{Type: model.ChargeDiscount, Description: "优惠", Amount: -discount})
+	}
+
+	if policy != nil {
+		serviceFee := policy.ServiceFee.Mul(quantity) + policy.ServiceFeeRate.Of(subtotal)
+		if serviceFee > 0 {
+			charges = append(charges, model.OrderCharge{Type: model.ChargeServiceFee, Description: "服务费", Amount: serviceFee})
+		}
+		if policy.DeliveryFee > 0 {
+			charges = append(charges, model.OrderCharge{Type: model.ChargeDeliveryFee, Description: "配送费", Amount: policy.DeliveryFee})
+		}
+
+		if policy.TaxRate > 0 {
+			var base util.Money
+			for _, charge := range charges {
+				base += charge.Amount
+			}
+			if policy.TaxInclusive == 1 {
+				charges = append(charges, model.OrderCharge{Type: model.ChargeTax, Description: "税费（已含）", Amount: policy.TaxRate.IncludedIn(base), Included: 1})
+			} else {
+				charges = append(charges, model.OrderCharge{Type: model.ChargeTax, Description: "税费", Amount: policy.TaxRate.Of(base)})
+			}
+		}
+	}
+
+	var total util.Money
+	for i := range charges {
+		charges[i].CreatedAt = time.Now()
+		if charges[i].Included == 0 {
+			total += charges[i].Amount
+		}
+	}
+	return charges, total
+}
+
+func validateFeePolicyInput(input FeePolicyInput) error {
+	if input.Name == "" || input.ScopeType < model.FeeScopeGlobal || input.ScopeType > model.FeeScopePerformance {
+		return ErrFeePolicyParams
+	}
+	if input.ScopeType != model.FeeScopeGlobal && input.ScopeID <= 0 {
+		return ErrFeePolicyParams
+	}
+	if input.ServiceFee < 0 || input.DeliveryFee < 0 || input.ServiceFeeRate < 0 || input.TaxRate < 0 ||
+		input.ServiceFeeRate >= util.BasisPoints || input.TaxRate >= util.BasisPoints {
+		return ErrFeePolicyParams
+	}
+	return nil
+}
+
+func applyFeePolicyInput(policy *model.FeePolicy, input FeePolicyInput) {
+	policy.Name = input.Name
+	policy.ScopeType = input.ScopeType
+	policy.ScopeID = input.ScopeID
+	if input.ScopeType == model.FeeScopeGlobal {
+		policy.ScopeID = 0
+	}
+	policy.ServiceFee = input.ServiceFee
+	policy.ServiceFeeRate = input.ServiceFeeRate
+	policy.DeliveryFee = input.DeliveryFee
+	policy.TaxRate = input.TaxRate
+	policy.TaxInclusive = input.TaxInclusive
+	policy.Status = input.Status
+	policy.UpdatedAt = time.Now()
+}
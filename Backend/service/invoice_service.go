@@ -0,0 +1,209 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+var (
+	ErrInvoiceNotFound     = errors.New("发票不存在")
+	ErrInvoiceExists       = errors.New("该订单已开具发票")
+	ErrInvoiceOrderNotPaid = errors.New("只有已支付的订单可以开具发票")
+	ErrInvoiceParams       = errors.New("发票抬头、税号或邮箱格式错误")
+)
+
+// 纳税人识别号为 15、18 或 20 位数字和大写字母
+var invoiceTaxIDPattern = regexp.MustCompile(`^[0-9A-Z]{15,20}$`)
+
+// 每页发票明细的最大行数
+const invoiceRowsPerPage = 22
+
+// InvoiceInput 开票申请，TaxID 为空表示个人抬头，Email 为空时使用账号邮箱
+type InvoiceInput struct {
+	Title string
+	TaxID string
+	Email string
+}
+
+type InvoiceService struct{}
+
+func NewInvoiceService() *InvoiceService {
+	return &InvoiceService{}
+}
+
+// Request 为已支付订单开具发票，价税合计和税额取自订单金额明细
+func (s *InvoiceService) Request(userID, orderID int, input InvoiceInput) (*model.Invoice, error) {
+	order, err := model.GetOrderByID(orderID)
+	if err != nil || order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+	if order.Status != 1 {
+		return nil, ErrInvoiceOrderNotPaid
+	}
+	if _, err := model.GetInvoiceByOrderID(orderID); err == nil {
+		return nil, ErrInvoiceExists
+	}
+
+	input.Title = strings.TrimSpace(input.Title)
+	input.TaxID = strings.ToUpper(strings.TrimSpace(input.TaxID))
+	input.Email = strings.TrimSpace(input.Email)
+	if input.Email == "" {
+		if user, err := model.GetUserByID(userID); err == nil {
+			input.Email = user.Email
+		}
+	}
+	if input.Title == "" || len([]rune(input.Title)) > 100 || input.Email == "" {
+		return nil, ErrInvoiceParams
+	}
+	if input.TaxID != "" && !invoiceTaxIDPattern.MatchString(input.TaxID) {
+		return nil, ErrInvoiceParams
+	}
+
+	var taxAmount util.Money
+	for _, charge := range order.Charges {
+		if charge.Type == model.ChargeTax {
+			taxAmount += charge.Amount
+		}
+	}
+
+	now := time.Now()
+	invoice := &model.Invoice{
+		OrderID:   order.ID,
+		UserID:    userID,
+		Title:     input.Title,
+		TaxID:     input.TaxID,
+		Email:     input.Email,
+		Amount:    order.Amount,
+		TaxAmount: taxAmount,
+		Currency:  order.Currency,
+		Status:    model.InvoiceStatusIssued,
+		IssuedAt:  now,
+		CreatedAt: now,
+	}
+	if err := model.CreateInvoice(invoice, util.GetConfig().Invoice.Prefix); err != nil {
+		// 并发申请时唯一索引冲突
+		if util.IsDuplicateKeyError(err) {
+			return nil, ErrInvoiceExists
+		}
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// GetOrderInvoice 获取用户订单的发票
+func (s *InvoiceService) GetOrderInvoice(userID, orderID int) (*model.Invoice, error) {
+	invoice, err := model.GetInvoiceByOrderID(orderID)
+	if err != nil || invoice.UserID != userID {
+		return nil, ErrInvoiceNotFound
+	}
+	return invoice, nil
+}
+
+// RenderPDF 生成发票 PDF：开票方与购买方信息、票种明细、费用明细及价税合计。
+// 已作废的发票仍可下载，页眉标注作废。
+func (s *InvoiceService) RenderPDF(invoice *model.Invoice) ([]byte, error) {
+	order, err := model.GetOrderByID(invoice.OrderID)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+	seller := util.GetConfig().Invoice
+
+	doc := util.NewPDFDocument()
+	const left, right = 50.0, 545.0
+
+	header := func() float64 {
+		title := "电子发票"
+		if invoice.Status == model.InvoiceStatusVoid {
+			title += "（已作废）"
+		}
+		doc.Text(left, 70, 20, title)
+		doc.TextRight(right, 62, 10, "发票号码："+invoice.InvoiceNo)
+		doc.TextRight(right, 78, 10, "开票日期："+invoice.IssuedAt.Format("2006-01-02"))
+		doc.Line(left, 90, right, 90, 1)
+		return 110
+	}
+
+	y := header()
+	doc.Text(left, y, 11, "销售方："+seller.CompanyName)
+	doc.Text(300, y, 11, "购买方："+invoice.Title)
+	y += 18
+	doc.Text(left, y, 10, "纳税人识别号："+seller.TaxID)
+	doc.Text(300, y, 10, "纳税人识别号："+invoice.TaxID)
+	y += 16
+	doc.Text(left, y, 10, "地址："+seller.Address)
+	doc.Text(300, y, 10, "邮箱："+invoice.Email)
+	y += 16
+	doc.Text(left, y, 10, "电话："+seller.Phone)
+	doc.Text(300, y, 10, "订单号："+order.OrderNo)
+	y += 16
+	if order.Performance != nil {
+		doc.Text(left, y, 10, "演出："+order.Performance.Title+"  "+order.Performance.StartTime.Format("2006-01-02 15:04")+"  "+order.Performance.Venue)
+		y += 16
+	}
+
+	y += 10
+	tableHeader := func(y float64) float64 {
+		doc.Line(left, y, right, y, 0.5)
+		y += 16
+		doc.Text(left+4, y, 10, "项目")
+		doc.TextRight(340, y, 10, "数量")
+		doc.TextRight(440, y, 10, "单价")
+		doc.TextRight(right-4, y, 10, "金额")
+		y += 8
+		doc.Line(left, y, right, y, 0.5)
+		return y + 16
+	}
+	y = tableHeader(y)
+
+	rows := 0
+	nextRow := func() {
+		rows++
+		if rows%invoiceRowsPerPage == 0 {
+			doc.AddPage()
+			y = tableHeader(header())
+		}
+	}
+
+	// 早期订单没有明细记录，按订单票种生成一行
+	for _, item := range order.StockItems() {
+		name := "票种 " + strconv.Itoa(item.TicketTypeID)
+		if item.TicketType != nil {
+			name = item.TicketType.Name
+		} else if order.TicketType != nil && order.TicketType.ID == item.TicketTypeID {
+			name = order.TicketType.Name
+		}
+		if item.PriceRuleName != "" {
+			name += "（" + item.PriceRuleName + "）"
+		}
+		doc.Text(left+4, y, 10, name)
+		doc.TextRight(340, y, 10, strconv.Itoa(item.Quantity))
+		doc.TextRight(440, y, 10, item.UnitPrice.String())
+		doc.TextRight(right-4, y, 10, item.Amount.String())
+		y += 18
+		nextRow()
+	}
+
+	for _, charge := range order.Charges {
+		if charge.Type == model.ChargeTicket {
+			continue
+		}
+		doc.Text(left+4, y, 10, charge.Description)
+		doc.TextRight(right-4, y, 10, charge.Amount.String())
+		y += 18
+		nextRow()
+	}
+
+	doc.Line(left, y-8, right, y-8, 0.5)
+	y += 8
+	doc.TextRight(right-4, y, 10, "其中税额（"+invoice.Currency+"）："+invoice.TaxAmount.String())
+	y += 20
+	doc.TextRight(right-4, y, 12, "价税合计（"+invoice.Currency+"）："+invoice.Amount.String())
+
+	return doc.Bytes()
+}
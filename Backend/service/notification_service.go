@@ -0,0 +1,98 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+type NotificationService struct{}
+
+func NewNotificationService() *NotificationService {
+	return &NotificationService{}
+}
+
+// NewDeviceLogin 新设备登录时通知用户，未绑定邮箱的用户不发送
+func (s *NotificationService) NewDeviceLogin(user *model.User, info util.SessionInfo, at time.Time) {
+	if user.Email == "" {
+		return
+	}
+	body := fmt.Sprintf("%s，您好：\n\n"+
+		"您的账号于 %s 在新设备上登录。\n\n"+
+		"设备：%s\nIP：%s\n\n"+
+		"如果这是您本人的操作，请忽略此邮件；否则请立即修改密码，并在「登录设备」中注销该设备。",
+		user.Username, at.Format("2006-01-02 15:04:05"), info.DeviceName, info.IP)
+	util.SendMailAsync(user.Email, "账号安全提醒：新设备登录", body)
+}
+
+// PasswordResetLink 发送找回密码链接，target 为用户申请时填写的手机号或邮箱
+func (s *NotificationService) PasswordResetLink(user *model.User, channel, target, link string) {
+	minutes := int(util.PasswordResetExpiration.Minutes())
+	if channel == util.ChannelSMS {
+		util.SendSMSAsync(target, fmt.Sprintf("【票务系统】您正在找回密码，请在 %d 分钟内打开链接设置新密码：%s 如非本人操作请忽略。", minutes, link))
+		return
+	}
+	body := fmt.Sprintf("%s，您好：\n\n"+
+		"我们收到了重置您账号密码的请求。请在 %d 分钟内打开以下链接设置新密码，链接只能使用一次：\n\n%s\n\n"+
+		"如果这不是您本人的操作，请忽略此邮件，您的密码不会改变。",
+		user.Username, minutes, link)
+	util.SendMailAsync(target, "重置密码", body)
+}
+
+// PasswordChanged 密码被重置后通知用户，未绑定邮箱的用户不发送
+func (s *NotificationService) PasswordChanged(user *model.User, at time.Time) {
+	if user.Email == "" {
+		return
+	}
+	body := fmt.Sprintf("%s，您好：\n\n"+
+		"您的账号密码已于 %s 重置，所有设备上的登录均已失效。\n\n"+
+		"如果这不是您本人的操作，请立即通过找回密码重新设置密码。",
+		user.Username, at.Format("2006-01-02 15:04:05"))
+	util.SendMailAsync(user.Email, "账号安全提醒：密码已重置", body)
+}
+
+// AdminInvitation 向受邀人发送管理员邀请链接
+func (s *NotificationService) AdminInvitation(invitation *model.AdminInvitation, inviter *model.Admin, roleName, link string) {
+	body := fmt.Sprintf("您好：\n\n"+
+		"%s 邀请您成为票务系统管理员，角色为「%s」。请在 %s 前打开以下链接设置用户名和密码，链接只能使用一次：\n\n%s\n\n"+
+		"如果您不认识邀请人，请忽略此邮件。",
+		inviter.RealName, roleName, invitation.ExpiresAt.Format("2006-01-02 15:04"), link)
+	util.SendMailAsync(invitation.Email, "管理员邀请", body)
+}
+
+// DataExportReady 个人数据导出文件生成后通知用户，未绑定邮箱的用户不发送
+func (s *NotificationService) DataExportReady(user *model.User, expiresAt time.Time) {
+	if user.Email == "" {
+		return
+	}
+	body := fmt.Sprintf("%s，您好：\n\n"+
+		"您申请导出的个人数据已生成。请登录后在「个人数据导出」中下载，文件将于 %s 删除。\n\n"+
+		"如果这不是您本人的操作，请立即修改密码。",
+		user.Username, expiresAt.Format("2006-01-02 15:04"))
+	util.SendMailAsync(user.Email, "个人数据导出已完成", body)
+}
+
+// AccountDeletionScheduled 申请注销后通知用户冷静期结束时间，未绑定邮箱的用户不发送
+func (s *NotificationService) AccountDeletionScheduled(user *model.User, scheduledAt time.Time) {
+	if user.Email == "" {
+		return
+	}
+	body := fmt.Sprintf("%s，您好：\n\n"+
+		"我们收到了注销您账号的申请，账号将于 %s 注销。注销后用户名、联系方式、头像、观演人等个人信息将被清除且无法恢复，订单仅以匿名形式保留。\n\n"+
+		"在此之前您可以登录并在「账号注销」中撤销申请。如果这不是您本人的操作，请立即撤销并修改密码。",
+		user.Username, scheduledAt.Format("2006-01-02 15:04"))
+	util.SendMailAsync(user.Email, "账号注销申请", body)
+}
+
+// AccountDeleted 账号注销完成后通知用户，user 为注销前的账号信息
+func (s *NotificationService) AccountDeleted(user *model.User) {
+	if user.Email == "" {
+		return
+	}
+	body := fmt.Sprintf("%s，您好：\n\n"+
+		"您的账号已按申请注销，个人信息已被清除。感谢您的使用。",
+		user.Username)
+	util.SendMailAsync(user.Email, "账号已注销", body)
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	ErrOIDCLoginFailed        = errors.New("第三方登录失败，请重试")
+	ErrIdentityLinkedToOther  = errors.New("该第三方账号已绑定其他用户")
+	ErrIdentityProviderLinked = errors.New("已绑定该平台的其他账号，请先解绑")
+	ErrIdentityNotFound       = errors.New("绑定的第三方账号不存在")
+	ErrLastLoginMethod        = errors.New("解绑后将无法登录，请先验证手机号或邮箱")
+)
+
+// OIDCCallbackResult 第三方授权回调的处理结果：登录时包含登录凭证，绑定时只包含绑定关系
+type OIDCCallbackResult struct {
+	User     *model.User
+	Identity *model.UserIdentity
+	Tokens   *TokenPair
+	NewUser  bool
+	Linked   bool
+}
+
+type OIDCService struct{}
+
+func NewOIDCService() *OIDCService {
+	return &OIDCService{}
+}
+
+// Providers 已配置的第三方登录服务商
+func (s *OIDCService) Providers() []util.OIDCProviderConfig {
+	return util.GetConfig().OIDC.Providers
+}
+
+// AuthorizationURL 生成跳转到服务商的授权地址，userID 大于 0 时为已登录用户绑定第三方账号
+func (s *OIDCService) AuthorizationURL(providerName string, userID int) (string, error) {
+	provider, err := util.GetOIDCProvider(providerName)
+	if err != nil {
+		return "", err
+	}
+	state, data, challenge, err := util.NewOIDCState(provider.Name, userID)
+	if err != nil {
+		return "", err
+	}
+	authURL, err := util.OIDCAuthorizationURL(provider, state, data.Nonce, challenge)
+	if err != nil {
+		log.Printf("获取第三方登录配置失败: provider=%s err=%v", provider.Name, err)
+		return "", ErrOIDCLoginFailed
+	}
+	return authURL, nil
+}
+
+// HandleCallback 处理服务商回调：校验 state，用授权码换取并验证 ID Token，然后登录或绑定
+func (s *OIDCService) HandleCallback(providerName, code, state string, info util.SessionInfo) (*OIDCCallbackResult, error) {
+	data, err := util.ConsumeOIDCState(state)
+	if err != nil {
+		return nil, err
+	}
+	if data.Provider != providerName {
+		return nil, util.ErrOIDCStateInvalid
+	}
+	provider, err := util.GetOIDCProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := util.OIDCExchangeCode(provider, code, data)
+	if err != nil {
+		log.Printf("第三方登录校验失败: provider=%s err=%v", provider.Name, err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	if data.UserID > 0 {
+		return s.link(data.UserID, provider.Name, identity)
+	}
+	return s.login(provider.Name, identity, info)
+}
+
+// login 已绑定的第三方账号直接登录；未绑定的自动注册新用户，服务商确认过的邮箱在未被占用时写入新账号。
+// 不会按邮箱自动绑定已有账号，已有账号须登录后在个人资料中绑定
+func (s *OIDCService) login(provider string, identity *util.OIDCIdentity, info util.SessionInfo) (*OIDCCallbackResult, error) {
+	result := &OIDCCallbackResult{}
+
+	linked, err := model.GetUserIdentity(provider, identity.Subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if linked != nil {
+		user, err := model.GetUserByID(linked.UserID)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		model.TouchUserIdentity(linked.ID, identity.Email, identity.Name)
+		result.User, result.Identity = user, linked
+	} else {
+		email := ""
+		if identity.EmailVerified && identity.Email != "" {
+			if _, err := model.GetUserByEmail(identity.Email); errors.Is(err, gorm.ErrRecordNotFound) {
+				email = identity.Email
+			}
+		}
+		linked = &model.UserIdentity{
+			Provider: provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+			Name:     identity.Name,
+		}
+		user, err := NewUserService().registerWithIdentity(email, linked)
+		if err != nil {
+			return nil, err
+		}
+		result.User, result.Identity, result.NewUser = user, linked, true
+	}
+
+	tokens, err := NewAuthService().IssueTokens(result.User, info)
+	recordUserLogin(result.User.ID, info, model.LoginEventOIDC, err)
+	if err != nil {
+		return nil, err
+	}
+	result.Tokens = tokens
+	return result, nil
+}
+
+// link 将第三方账号绑定到当前用户，每个平台只能绑定一个账号
+func (s *OIDCService) link(userID int, provider string, identity *util.OIDCIdentity) (*OIDCCallbackResult, error) {
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	existing, err := model.GetUserIdentity(provider, identity.Subject)
+	if err == nil {
+		if existing.UserID != userID {
+			return nil, ErrIdentityLinkedToOther
+		}
+		return &OIDCCallbackResult{User: user, Identity: existing, Linked: true}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	identities, err := model.GetUserIdentities(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range identities {
+		if item.Provider == provider {
+			return nil, ErrIdentityProviderLinked
+		}
+	}
+
+	linked := &model.UserIdentity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		Name:     identity.Name,
+	}
+	if err := model.CreateUserIdentity(linked); err != nil {
+		return nil, err
+	}
+	return &OIDCCallbackResult{User: user, Identity: linked, Linked: true}, nil
+}
+
+// ListIdentities 用户绑定的第三方账号
+func (s *OIDCService) ListIdentities(userID int) ([]*model.UserIdentity, error) {
+	return model.GetUserIdentities(userID)
+}
+
+// Unlink 解绑第三方账号。解绑最后一个第三方账号前须已验证手机号或邮箱，
+// 否则通过第三方登录自动注册的用户将无法再登录
+func (s *OIDCService) Unlink(userID, identityID int) error {
+	identities, err := model.GetUserIdentities(userID)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, item := range identities {
+		if item.ID == identityID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrIdentityNotFound
+	}
+
+	if len(identities) == 1 {
+		user, err := model.GetUserByID(userID)
+		if err != nil {
+			return ErrUserNotFound
+		}
+		if !user.HasVerifiedContact() {
+			return ErrLastLoginMethod
+		}
+	}
+
+	if _, err := model.DeleteUserIdentity(userID, identityID); err != nil {
+		return err
+	}
+	return nil
+}
@@ -1,19 +1,49 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+
+	"github.com/jinzhu/gorm"
 )
 
 var (
-	ErrOrderNotFound     = errors.New("订单不存在")
-	ErrOrderExpired      = errors.New("订单已过期")
-	ErrOrderStatusError  = errors.New("订单状态错误")
-	ErrInsufficientStock = errors.New("库存不足")
+	ErrOrderNotFound         = errors.New("订单不存在")
+	ErrOrderExpired          = errors.New("订单已过期")
+	ErrOrderStatusError      = errors.New("订单状态错误")
+	ErrInsufficientStock     = errors.New("库存不足")
+	ErrOrderEmpty            = errors.New("订单中没有票种")
+	ErrOrderMixPerformances  = errors.New("一个订单只能包含同一场演出的票种")
+	ErrRealNameRequired      = errors.New("该演出需实名购票，请为每张票指定观演人")
+	ErrAttendeeCountMismatch = errors.New("观演人数量与购票数量不一致")
+	ErrAttendeeDuplicate     = errors.New("同一观演人不能重复购票")
+	ErrAttendeeTicketed      = errors.New("观演人已购买该场演出的门票")
+	ErrBallotTicketType      = errors.New("该票种仅支持抽签购买")
 )
 
+// OrderLine 下单明细，AttendeeIDs 按张指定观演人，实名演出必填
+type OrderLine struct {
+	TicketTypeID int
+	Quantity     int
+	AttendeeIDs  []int
+}
+
+// orderOptions 下单时的附加选项，普通下单使用零值
+type orderOptions struct {
+	Ballot          bool      // 抽签中签下单，允许抽签票种
+	BallotEntryID   int       // 中签的抽签登记，在同一事务中标记为已中签并关联订单
+	WaitlistEntryID int       // 候补登记，在同一事务中标记为待支付并关联订单
+	ExpireTime      time.Time // 支付截止时间，零值时按配置的订单有效期计算
+	CouponCode      string    // 优惠码，为空时不使用优惠
+	CartItemIDs     []int     // 购物车结算时已结算的条目，在同一事务中移除
+}
+
 type OrderService struct{}
 
 func NewOrderService() *OrderService {
@@ -57,11 +87,14 @@ func (s *OrderService) CancelOrder(orderID, userID int) error {
 		return ErrOrderStatusError
 	}
 
-	if err := model.UpdateOrderStatus(order.ID, 2); err != nil {
+	ok, err := CloseOrder(order, 0, 2)
+	if err != nil {
 		return err
 	}
-
-	return model.IncreaseStock(order.TicketTypeID, order.Quantity)
+	if !ok {
+		return ErrOrderStatusError
+	}
+	return nil
 }
 
 func (s *OrderService) PayOrder(orderID, userID int) (*model.Order, error) {
@@ -83,6 +116,9 @@ func (s *OrderService) PayOrder(orderID, userID int) (*model.Order, error) {
 	}
 
 	if err := model.PayOrder(order.ID); err != nil {
+		if err == model.ErrOrderNotPending {
+			return nil, ErrOrderStatusError
+		}
 		return nil, err
 	}
 
@@ -103,11 +139,14 @@ func (s *OrderService) RefundOrder(orderID, userID int) error {
 		return ErrOrderStatusError
 	}
 
-	if err := model.UpdateOrderStatus(order.ID, 3); err != nil {
+	ok, err := CloseOrder(order, 1, 3)
+	if err != nil {
 		return err
 	}
-
-	return model.IncreaseStock(order.TicketTypeID, order.Quantity)
+	if !ok {
+		return ErrOrderStatusError
+	}
+	return nil
 }
 
 func (s *OrderService) CreateOrderFromSeckill(orderID, userID int) (*model.Order, error) {
@@ -126,3 +165,431 @@ func (s *OrderService) CreateOrderFromSeckill(orderID, userID int) (*model.Order
 
 	return order, nil
 }
+
+// PlaceOrder 按明细创建待支付订单。
+// 涉及的票种按ID顺序加锁，全部库存扣减、订单、明细和电子票写入在同一事务中完成，任一票种失败则整体回滚。
+// 实名演出要求每张票绑定一位观演人，同一证件号在同一场演出中只能持有一张有效票。
+// couponCode 不为空时在同一事务中校验并占用优惠码；订单金额由票款、优惠及演出适用的服务费、配送费和税费构成，明细记录在订单上。
+func (s *OrderService) PlaceOrder(ctx context.Context, userID int, lines []OrderLine, couponCode string) (*model.Order, error) {
+	return s.placeOrder(ctx, userID, lines, orderOptions{CouponCode: couponCode})
+}
+
+func (s *OrderService) placeOrder(ctx context.Context, userID int, lines []OrderLine, opts orderOptions) (*model.Order, error) {
+	cfg := util.GetConfig()
+
+	merged := make([]OrderLine, 0, len(lines))
+	index := make(map[int]int)
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			continue
+		}
+		if i, ok := index[line.TicketTypeID]; ok {
+			merged[i].Quantity += line.Quantity
+			merged[i].AttendeeIDs = append(merged[i].AttendeeIDs, line.AttendeeIDs...)
+			continue
+		}
+		index[line.TicketTypeID] = len(merged)
+		merged = append(merged, line)
+	}
+	if len(merged) == 0 {
+		return nil, ErrOrderEmpty
+	}
+
+	// 每人限购按整个订单的总张数计算，多个票种合计也不能超过
+	ticketTypeIDs := make([]int, 0, len(merged))
+	totalQuantity := 0
+	for _, line := range merged {
+		totalQuantity += line.Quantity
+		ticketTypeIDs = append(ticketTypeIDs, line.TicketTypeID)
+	}
+	if totalQuantity > cfg.Seckill.MaxQuantityPerUser {
+		return nil, ErrTicketLimitExceeded
+	}
+	sort.Ints(ticketTypeIDs)
+
+	attendees, err := loadOrderAttendees(userID, merged)
+	if err != nil {
+		return nil, err
+	}
+
+	locks := make([]*util.DistributedLock, 0, len(ticketTypeIDs))
+	defer func() {
+		for _, lock := range locks {
+			util.ReleaseSeckillLock(lock, ctx)
+		}
+	}()
+	for _, ticketTypeID := range ticketTypeIDs {
+		lock, err := util.AcquireSeckillLock(ctx, ticketTypeID, 10*time.Second)
+		if err != nil {
+			if err == util.ErrLockAcquireFailed {
+				return nil, ErrLockAcquireFailed
+			}
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+
+	tx := util.DB.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	var (
+		items       []model.OrderItem
+		usages      []*model.OrderAccessCode
+		performance *model.Performance
+		quantity    int
+		amount      util.Money
+	)
+	for _, line := range merged {
+		ticketType, err := model.GetTicketTypeByIDTx(tx, line.TicketTypeID)
+		if err != nil {
+			tx.Rollback()
+			return nil, ErrTicketTypeNotFound
+		}
+
+		if ticketType.SaleMode == model.SaleModeBallot && !opts.Ballot {
+			tx.Rollback()
+			return nil, ErrBallotTicketType
+		}
+
+		// 排队中候补所需的库存只分配给候补，普通购买只能使用超出的部分。
+		// 候补订单由后台任务按排队顺序创建，与普通购买持有同一票种锁
+		if opts.WaitlistEntryID == 0 {
+			waiting, err := model.WaitingWaitlistQuantityTx(tx, ticketType.ID)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			if ticketType.Stock-waiting < line.Quantity {
+				tx.Rollback()
+				return nil, ErrStockInsufficient
+			}
+		}
+
+		if ticketType.Hidden == 1 {
+			usage, err := useAccessCodeTx(tx, userID, ticketType.ID, line.Quantity)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			usages = append(usages, usage)
+		}
+
+		if performance == nil {
+			performance, err = model.GetPerformanceByID(ticketType.PerformanceID)
+			if err != nil {
+				tx.Rollback()
+				return nil, ErrPerformanceNotFound
+			}
+		} else if performance.ID != ticketType.PerformanceID {
+			tx.Rollback()
+			return nil, ErrOrderMixPerformances
+		}
+
+		if performance.RequireRealName == 1 && len(line.AttendeeIDs) == 0 {
+			tx.Rollback()
+			return nil, ErrRealNameRequired
+		}
+
+		// 售价按扣减库存前的已售数量计算，整行使用同一价格
+		rules, err := model.GetEnabledPriceRulesTx(tx, ticketType.ID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		unitPrice, rule := effectivePrice(ticketType, rules, time.Now(), soldCount(ticketType))
+
+		ok, err := model.DecreaseStockTx(tx, ticketType.ID, line.Quantity)
+		if err != nil {
+			tx.Rollback()
+			return nil, ErrSeckillFailed
+		}
+		if !ok {
+			tx.Rollback()
+			return nil, ErrStockInsufficient
+		}
+
+		lineAmount := unitPrice.Mul(line.Quantity)
+		item := model.OrderItem{
+			PerformanceID: ticketType.PerformanceID,
+			TicketTypeID:  ticketType.ID,
+			Quantity:      line.Quantity,
+			UnitPrice:     unitPrice,
+			Amount:        lineAmount,
+			CreatedAt:     time.Now(),
+		}
+		if rule != nil {
+			item.PriceRuleID = rule.ID
+			item.PriceRuleName = rule.Name
+		}
+		items = append(items, item)
+		quantity += line.Quantity
+		amount += lineAmount
+	}
+
+	if performance.RequireRealName == 1 {
+		hashes := make([]string, 0, len(attendees))
+		for _, attendee := range attendees {
+			hashes = append(hashes, attendee.IDNumberHash)
+		}
+		count, err := model.CountClaimedTicketsTx(tx, performance.ID, hashes)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if count > 0 {
+			tx.Rollback()
+			return nil, ErrAttendeeTicketed
+		}
+	}
+
+	var coupon *couponApplication
+	if opts.CouponCode != "" {
+		coupon, err = applyCouponTx(tx, userID, opts.CouponCode, performance, items)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	var discount util.Money
+	if coupon != nil {
+		discount = coupon.Discount
+	}
+	policy, err := model.GetEffectiveFeePolicy(performance.ID, performance.CategoryID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	charges, total := orderCharges(policy, quantity, amount, discount)
+
+	expireTime := opts.ExpireTime
+	if expireTime.IsZero() {
+		expireTime = time.Now().Add(time.Duration(cfg.Seckill.OrderExpireMinutes) * time.Minute)
+	}
+
+	order := &model.Order{
+		OrderNo:        GenerateOrderNo(),
+		UserID:         userID,
+		PerformanceID:  performance.ID,
+		TicketTypeID:   items[0].TicketTypeID,
+		Quantity:       quantity,
+		Amount:         total,
+		DiscountAmount: discount,
+		Currency:       util.DefaultCurrency(),
+		Status:         0,
+		ExpireTime:     expireTime,
+		PaymentTime:    nil,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	if err := model.CreateOrderTx(tx, order); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].OrderID = order.ID
+		if err := model.CreateOrderItemTx(tx, &items[i]); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	for i := range charges {
+		charges[i].OrderID = order.ID
+		if err := model.CreateOrderChargeTx(tx, &charges[i]); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	for _, usage := range usages {
+		usage.OrderID = order.ID
+		if err := model.CreateOrderAccessCodeTx(tx, usage); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if coupon != nil {
+		usage := &model.CouponUsage{
+			CouponID:     coupon.Coupon.ID,
+			CouponCodeID: coupon.Code.ID,
+			UserID:       userID,
+			OrderID:      order.ID,
+			Discount:     coupon.Discount,
+			Status:       1,
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		}
+		if err := model.CreateCouponUsageTx(tx, usage); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		discount := model.OrderDiscount{
+			OrderID:      order.ID,
+			CouponID:     coupon.Coupon.ID,
+			CouponCodeID: coupon.Code.ID,
+			Code:         coupon.Code.Code,
+			Description:  couponDescription(coupon.Coupon),
+			Amount:       coupon.Discount,
+			CreatedAt:    time.Now(),
+		}
+		if err := model.CreateOrderDiscountTx(tx, &discount); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		order.Discounts = append(order.Discounts, discount)
+	}
+
+	seq := 0
+	for _, line := range merged {
+		for i := 0; i < line.Quantity; i++ {
+			seq++
+			ticket := &model.Ticket{
+				TicketNo:      fmt.Sprintf("%s%02d", order.OrderNo, seq),
+				OrderID:       order.ID,
+				UserID:        userID,
+				PerformanceID: performance.ID,
+				TicketTypeID:  line.TicketTypeID,
+				Status:        model.TicketStatusPending,
+				CreatedAt:     time.Now(),
+				UpdatedAt:     time.Now(),
+			}
+			if len(line.AttendeeIDs) > 0 {
+				attendee := attendees[line.AttendeeIDs[i]]
+				ticket.AttendeeID = attendee.ID
+				ticket.AttendeeName = attendee.Name
+				ticket.IDNumberHash = attendee.IDNumberHash
+				ticket.IDNumberMasked = attendee.IDNumberMasked
+				if performance.RequireRealName == 1 {
+					claim := 1
+					ticket.Claim = &claim
+				}
+			}
+			if err := model.CreateTicketTx(tx, ticket); err != nil {
+				tx.Rollback()
+				if util.IsDuplicateKeyError(err) {
+					return nil, ErrAttendeeTicketed
+				}
+				return nil, err
+			}
+		}
+	}
+
+	if opts.BallotEntryID > 0 {
+		ok, err := model.MarkBallotEntryWonTx(tx, opts.BallotEntryID, order.ID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if !ok {
+			tx.Rollback()
+			return nil, ErrBallotNoDraw
+		}
+	}
+
+	if opts.WaitlistEntryID > 0 {
+		ok, err := model.OfferWaitlistEntryTx(tx, opts.WaitlistEntryID, order.ID, expireTime)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if !ok {
+			tx.Rollback()
+			return nil, ErrWaitlistNotWaiting
+		}
+	}
+
+	if len(opts.CartItemIDs) > 0 {
+		if err := model.DeleteCartItemsTx(tx, userID, opts.CartItemIDs); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	order.Items = items
+	order.Charges = charges
+	return order, nil
+}
+
+// CloseOrder 取消、过期关闭或退款订单：在同一事务中按条件变更订单状态，并归还库存、作废电子票和发票、
+// 释放兑换码和优惠券。返回 false 表示订单已不是 fromStatus，由其他请求处理过，此时不做任何变更。
+// 归还的库存由后台任务优先分配给该票种的候补用户。
+func CloseOrder(order *model.Order, fromStatus, toStatus int) (bool, error) {
+	tx := util.DB.Begin()
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+
+	ok, err := model.TransitionOrderStatusTx(tx, order.ID, fromStatus, toStatus)
+	if err != nil || !ok {
+		tx.Rollback()
+		return false, err
+	}
+	if err := model.RestoreOrderStockTx(tx, order); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := model.VoidOrderTicketsTx(tx, order.ID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := model.ReleaseOrderAccessCodesTx(tx, order.ID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := model.ReleaseOrderCouponsTx(tx, order.ID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := model.LapseWaitlistOfferTx(tx, order.ID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := model.VoidOrderInvoiceTx(tx, order.ID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+
+	wakeOrderWorker()
+	return true, nil
+}
+
+// useAccessCodeTx 为隐藏票种占用一次用户已解锁的兑换码，未解锁时按票种不存在处理
+func useAccessCodeTx(tx *gorm.DB, userID, ticketTypeID, quantity int) (*model.OrderAccessCode, error) {
+	codes, err := model.GetUserAccessCodesTx(tx, userID, ticketTypeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(codes) == 0 {
+		return nil, ErrTicketTypeNotFound
+	}
+
+	for _, code := range codes {
+		ok, err := model.UseAccessCodeTx(tx, code.ID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &model.OrderAccessCode{
+				AccessCodeID: code.ID,
+				UserID:       userID,
+				TicketTypeID: ticketTypeID,
+				Quantity:     quantity,
+				CreatedAt:    time.Now(),
+			}, nil
+		}
+	}
+	return nil, ErrAccessCodeExhausted
+}
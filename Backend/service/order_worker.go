@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+// 单次扫描关闭的过期订单上限
+const expiredOrderBatchSize = 200
+
+// orderWorkerWake 订单取消或退款归还库存后唤醒后台任务，尽快分配给候补用户，不在请求中同步下单
+var orderWorkerWake = make(chan struct{}, 1)
+
+func wakeOrderWorker() {
+	select {
+	case orderWorkerWake <- struct{}{}:
+	default:
+	}
+}
+
+// StartOrderWorker 定期关闭过期未支付的订单并将归还的库存分配给候补用户，ctx 取消后退出
+func StartOrderWorker(ctx context.Context) {
+	interval := time.Duration(util.GetConfig().Waitlist.ScanIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-orderWorkerWake:
+			offerWaitlists(ctx)
+		case <-ticker.C:
+			CloseExpiredOrders()
+			offerWaitlists(ctx)
+		}
+	}
+}
+
+// CloseExpiredOrders 关闭已过支付期限的待支付订单，归还库存并作废电子票
+func CloseExpiredOrders() {
+	orders, err := model.GetExpiredPendingOrders(expiredOrderBatchSize)
+	if err != nil {
+		log.Printf("查询过期订单失败: %v", err)
+		return
+	}
+
+	for _, order := range orders {
+		if _, err := CloseOrder(order, 0, 2); err != nil {
+			log.Printf("关闭过期订单失败: order=%d, err=%v", order.ID, err)
+		}
+	}
+}
+
+// offerWaitlists 处理由其他途径（如存储过程关单、后台调整库存）归还的库存
+func offerWaitlists(ctx context.Context) {
+	if err := model.LapseClosedWaitlistOffers(); err != nil {
+		log.Printf("同步候补状态失败: %v", err)
+	}
+
+	ticketTypeIDs, err := model.GetWaitlistedTicketTypeIDs()
+	if err != nil {
+		log.Printf("查询候补票种失败: %v", err)
+		return
+	}
+
+	waitlistService := NewWaitlistService()
+	for _, ticketTypeID := range ticketTypeIDs {
+		waitlistService.OfferReleasedStock(ctx, ticketTypeID)
+	}
+}
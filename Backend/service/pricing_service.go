@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+var (
+	ErrPriceRuleNotFound = errors.New("价格规则不存在")
+	ErrPriceRuleParams   = errors.New("价格规则参数错误")
+)
+
+// PriceRuleInput 创建或更新价格规则的参数
+type PriceRuleInput struct {
+	Name          string
+	Price         util.Money
+	StartTime     *time.Time
+	EndTime       *time.Time
+	SoldThreshold int
+	Priority      int
+	Status        int
+}
+
+type PricingService struct{}
+
+func NewPricingService() *PricingService {
+	return &PricingService{}
+}
+
+func (s *PricingService) GetRules(ticketTypeID int) ([]*model.PriceRule, error) {
+	if _, err := model.GetTicketTypeByID(ticketTypeID); err != nil {
+		return nil, ErrTicketTypeNotFound
+	}
+	return model.GetPriceRules(ticketTypeID)
+}
+
+func (s *PricingService) CreateRule(ticketTypeID int, input PriceRuleInput) (*model.PriceRule, error) {
+	if _, err := model.GetTicketTypeByID(ticketTypeID); err != nil {
+		return nil, ErrTicketTypeNotFound
+	}
+	if err := validatePriceRuleInput(input); err != nil {
+		return nil, err
+	}
+
+	rule := &model.PriceRule{TicketTypeID: ticketTypeID, CreatedAt: time.Now()}
+	applyPriceRuleInput(rule, input)
+	if err := model.CreatePriceRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *PricingService) UpdateRule(id int, input PriceRuleInput) (*model.PriceRule, error) {
+	rule, err := model.GetPriceRuleByID(id)
+	if err != nil {
+		return nil, ErrPriceRuleNotFound
+	}
+	if err := validatePriceRuleInput(input); err != nil {
+		return nil, err
+	}
+
+	applyPriceRuleInput(rule, input)
+	if err := model.UpdatePriceRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// FillPrices 为面向用户的票种列表填充当前售价和下一次价格变化
+func (s *PricingService) FillPrices(ticketTypes []*model.TicketType) error {
+	ids := make([]int, 0, len(ticketTypes))
+	for _, ticketType := range ticketTypes {
+		ids = append(ids, ticketType.ID)
+	}
+	rules, err := model.GetEnabledPriceRules(ids)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, ticketType := range ticketTypes {
+		price, _ := effectivePrice(ticketType, rules[ticketType.ID], now, soldCount(ticketType))
+		ticketType.CurrentPrice = &price
+		ticketType.NextPriceChange = nextPriceChange(ticketType, rules[ticketType.ID], now)
+	}
+	return nil
+}
+
+// effectivePrice 计算票种在指定时间和已售数量下的售价，返回生效的规则，无规则生效时为票面价。
+// 多条规则同时生效时取优先级最高的；优先级相同时取销量门槛更高的，再取较新的规则。
+func effectivePrice(ticketType *model.TicketType, rules []*model.PriceRule, now time.Time, sold int) (util.Money, *model.PriceRule) {
+	var applied *model.PriceRule
+	for _, rule := range rules {
+		if !rule.ActiveAt(now, sold) {
+			continue
+		}
+		if applied == nil || rule.Priority > applied.Priority ||
+			(rule.Priority == applied.Priority && (rule.SoldThreshold > applied.SoldThreshold ||
+				(rule.SoldThreshold == applied.SoldThreshold && rule.ID > applied.ID))) {
+			applied = rule
+		}
+	}
+	if applied == nil {
+		return ticketType.Price, nil
+	}
+	return applied.Price, applied
+}
+
+// nextPriceChange 查找当前销量下最近一次按时间触发的价格变化，没有时再查找按销量触发的变化
+func nextPriceChange(ticketType *model.TicketType, rules []*model.PriceRule, now time.Time) *model.PriceChange {
+	sold := soldCount(ticketType)
+	current, _ := effectivePrice(ticketType, rules, now, sold)
+
+	var points []time.Time
+	for _, rule := range rules {
+		if rule.StartTime != nil && rule.StartTime.After(now) {
+			points = append(points, *rule.StartTime)
+		}
+		if rule.EndTime != nil && rule.EndTime.After(now) {
+			points = append(points, *rule.EndTime)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Before(points[j]) })
+	for _, point := range points {
+		if price, _ := effectivePrice(ticketType, rules, point, sold); price != current {
+			at := point
+			return &model.PriceChange{Price: price, StartTime: &at}
+		}
+	}
+
+	var thresholds []int
+	for _, rule := range rules {
+		if rule.SoldThreshold > sold && rule.SoldThreshold <= ticketType.Total {
+			thresholds = append(thresholds, rule.SoldThreshold)
+		}
+	}
+	sort.Ints(thresholds)
+	for _, threshold := range thresholds {
+		if price, _ := effectivePrice(ticketType, rules, now, threshold); price != current {
+			return &model.PriceChange{Price: price, RemainingUnits: threshold - sold}
+		}
+	}
+	return nil
+}
+
+func soldCount(ticketType *model.TicketType) int {
+	if sold := ticketType.Total - ticketType.Stock; sold > 0 {
+		return sold
+	}
+	return 0
+}
+
+func validatePriceRuleInput(input PriceRuleInput) error {
+	if input.Name == "" || input.Price < 0 || input.SoldThreshold < 0 {
+		return ErrPriceRuleParams
+	}
+	if input.StartTime != nil && input.EndTime != nil && !input.EndTime.After(*input.StartTime) {
+		return ErrPriceRuleParams
+	}
+	return nil
+}
+
+func applyPriceRuleInput(rule *model.PriceRule, input PriceRuleInput) {
+	rule.Name = input.Name
+	rule.Price = input.Price
+	rule.StartTime = input.StartTime
+	rule.EndTime = input.EndTime
+	rule.SoldThreshold = input.SoldThreshold
+	rule.Priority = input.Priority
+	rule.Status = input.Status
+	rule.UpdatedAt = time.Now()
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+var ErrNoPrintableTickets = errors.New("订单没有可打印的电子票")
+
+// RenderOrderTicketsPDF 生成订单的纸质票 PDF，每张有效或已检票的电子票一页，
+// 包含演出封面、演出信息、票种、观演人和带签名的入场二维码。
+func (s *TicketService) RenderOrderTicketsPDF(order *model.Order) ([]byte, error) {
+	if order.Status != 1 {
+		return nil, ErrNoPrintableTickets
+	}
+	tickets, err := model.GetOrderTickets(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	performance, err := model.GetPerformanceByID(order.PerformanceID)
+	if err != nil || performance == nil {
+		return nil, ErrPerformanceNotFound
+	}
+	cover := loadCoverImage(performance.CoverImage)
+
+	var doc *util.PDFDocument
+	for _, ticket := range tickets {
+		if ticket.Status != model.TicketStatusValid && ticket.Status != model.TicketStatusCheckedIn {
+			continue
+		}
+		qr, err := util.EncodeQRCode(util.SignTicketCode(ticket.TicketNo))
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			doc = util.NewPDFDocument()
+		} else {
+			doc.AddPage()
+		}
+		drawTicketPage(doc, performance, order, ticket, cover, qr)
+	}
+	if doc == nil {
+		return nil, ErrNoPrintableTickets
+	}
+	return doc.Bytes()
+}
+
+func drawTicketPage(doc *util.PDFDocument, performance *model.Performance, order *model.Order, ticket *model.Ticket, cover image.Image, qr *util.QRCode) {
+	const left, width = 50.0, 495.0
+	y := 50.0
+
+	if cover != nil {
+		bounds := cover.Bounds()
+		height := width * float64(bounds.Dy()) / float64(bounds.Dx())
+		if height > 240 {
+			height = 240
+		}
+		doc.Image(left, y, width, height, cover)
+		y += height
+	}
+
+	y += 40
+	doc.Text(left, y, 20, performance.Title)
+	y += 30
+	doc.Text(left, y, 12, "时间："+performance.StartTime.Format("2006-01-02 15:04"))
+	y += 22
+	doc.Text(left, y, 12, "场馆："+performance.Venue)
+	y += 22
+	ticketTypeName := ""
+	if ticket.TicketType != nil {
+		ticketTypeName = ticket.TicketType.Name
+	}
+	doc.Text(left, y, 12, "票种："+ticketTypeName)
+	y += 22
+	if ticket.AttendeeName != "" {
+		doc.Text(left, y, 12, "观演人："+ticket.AttendeeName+"  "+ticket.IDNumberMasked)
+		y += 22
+	}
+	doc.Text(left, y, 10, "票号："+ticket.TicketNo)
+	y += 18
+	doc.Text(left, y, 10, "订单号："+order.OrderNo)
+	if ticket.Status == model.TicketStatusCheckedIn {
+		y += 18
+		doc.Text(left, y, 10, "该票已检票")
+	}
+
+	y += 20
+	doc.Line(left, y, left+width, y, 0.5)
+	y += 20
+	const qrSize = 200.0
+	doc.QRCode(left+(width-qrSize)/2, y, qrSize, qr)
+	y += qrSize + 16
+	notice := "入场时请出示此二维码，每张票仅限一人一次入场"
+	doc.Text(left+(width-doc.TextWidth(notice, 10))/2, y, 10, notice)
+}
+
+// loadCoverImage 读取上传目录中的演出封面，外部链接或无法解码的格式（如 WebP）返回 nil，纸质票不印封面
+func loadCoverImage(coverImage string) image.Image {
+	if !strings.HasPrefix(coverImage, "/uploads/") {
+		return nil
+	}
+	base := filepath.Clean(util.GetConfig().Upload.Path)
+	path := filepath.Join(base, strings.TrimPrefix(coverImage, "/uploads/"))
+	if !strings.HasPrefix(path, base+string(filepath.Separator)) {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil
+	}
+	return img
+}
@@ -17,6 +17,7 @@ var (
 	ErrSeckillFailed       = errors.New("抢票失败")
 	ErrLockAcquireFailed   = errors.New("系统繁忙，请稍后重试")
 	ErrTicketLimitExceeded = errors.New("超出每人限购数量")
+	ErrContactNotVerified  = errors.New("请先验证手机号或邮箱后再抢票")
 )
 
 type TicketService struct{}
@@ -42,69 +43,47 @@ func (s *TicketService) GetTicketTypeByID(ticketTypeID int) (*model.TicketType,
 	return ticketType, nil
 }
 
-func (s *TicketService) Seckill(ctx context.Context, userID, ticketTypeID, quantity int) (*model.Order, error) {
+func (s *TicketService) Seckill(ctx context.Context, userID, ticketTypeID, quantity int, attendeeIDs []int, couponCode string) (*model.Order, error) {
 	cfg := util.GetConfig()
 
 	if quantity > cfg.Seckill.MaxQuantityPerUser {
 		return nil, ErrTicketLimitExceeded
 	}
 
-	ticketType, err := model.GetTicketTypeByID(ticketTypeID)
-	if err != nil || ticketType == nil {
-		return nil, ErrTicketTypeNotFound
-	}
-
-	if ticketType.Stock < quantity {
-		return nil, ErrStockInsufficient
-	}
-
-	performance, err := model.GetPerformanceByID(ticketType.PerformanceID)
-	if err != nil || performance == nil {
-		return nil, ErrPerformanceNotFound
-	}
-
-	lock, err := util.AcquireSeckillLock(ctx, ticketTypeID, 10*time.Second)
-	if err != nil {
-		if err == util.ErrLockAcquireFailed {
-			return nil, ErrLockAcquireFailed
+	if cfg.Seckill.RequireVerifiedContact {
+		user, err := model.GetUserByID(userID)
+		if err != nil || !user.HasVerifiedContact() {
+			return nil, ErrContactNotVerified
 		}
-		return nil, err
 	}
-	defer util.ReleaseSeckillLock(lock, ctx)
 
-	ticketType, err = model.GetTicketTypeByID(ticketTypeID)
+	ticketType, err := model.GetTicketTypeByID(ticketTypeID)
 	if err != nil || ticketType == nil {
 		return nil, ErrTicketTypeNotFound
 	}
 
-	if ticketType.Stock < quantity {
-		return nil, ErrStockInsufficient
+	if !canAccessTicketType(userID, ticketType) {
+		return nil, ErrTicketTypeNotFound
 	}
 
-	if err := model.DecreaseStock(ticketTypeID, quantity); err != nil {
-		return nil, ErrSeckillFailed
+	if ticketType.SaleMode == model.SaleModeBallot {
+		return nil, ErrBallotTicketType
 	}
 
-	order := &model.Order{
-		OrderNo:       GenerateOrderNo(),
-		UserID:        userID,
-		PerformanceID: ticketType.PerformanceID,
-		TicketTypeID:  ticketTypeID,
-		Quantity:      quantity,
-		Amount:        ticketType.Price * float64(quantity),
-		Status:        0,
-		ExpireTime:    time.Now().Add(time.Duration(cfg.Seckill.OrderExpireMinutes) * time.Minute),
-		PaymentTime:   nil,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+	if ticketType.Stock < quantity {
+		return nil, ErrStockInsufficient
 	}
 
-	if err := model.CreateOrder(order); err != nil {
-		model.IncreaseStock(ticketTypeID, quantity)
-		return nil, err
+	performance, err := model.GetPerformanceByID(ticketType.PerformanceID)
+	if err != nil || performance == nil {
+		return nil, ErrPerformanceNotFound
 	}
 
-	return order, nil
+	return NewOrderService().PlaceOrder(ctx, userID, []OrderLine{{
+		TicketTypeID: ticketTypeID,
+		Quantity:     quantity,
+		AttendeeIDs:  attendeeIDs,
+	}}, couponCode)
 }
 
 func GenerateOrderNo() string {
@@ -1,7 +1,11 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"math/big"
 	"time"
 
 	"ticket-system-backend/model"
@@ -9,9 +13,18 @@ import (
 )
 
 var (
-	ErrUserNotFound      = errors.New("用户不存在")
-	ErrUserAlreadyExists = errors.New("用户已存在")
-	ErrInvalidPassword   = errors.New("密码错误")
+	ErrUserNotFound       = errors.New("用户不存在")
+	ErrUserAlreadyExists  = errors.New("用户已存在")
+	ErrInvalidPassword    = errors.New("密码错误")
+	ErrContactTypeInvalid = errors.New("联系方式类型无效")
+	ErrContactEmpty       = errors.New("尚未填写该联系方式")
+	ErrContactVerified    = errors.New("该联系方式已验证")
+)
+
+// 需要验证的联系方式类型
+const (
+	ContactTypePhone = "phone"
+	ContactTypeEmail = "email"
 )
 
 type UserService struct{}
@@ -61,22 +74,98 @@ func (s *UserService) Register(username, password, phone, email string) (*model.
 	return user, nil
 }
 
-func (s *UserService) Login(username, password string) (*model.User, string, error) {
-	user, err := model.GetUserByUsername(username)
+// RegisterByPhone 验证码登录时为未注册的手机号自动创建账号，用户名随机生成，
+// 密码为不可猜测的随机值，此类账号通过验证码登录
+func (s *UserService) RegisterByPhone(phone string) (*model.User, error) {
+	return s.registerWithGeneratedUsername(phone, "")
+}
+
+// registerWithGeneratedUsername 为验证码登录、第三方登录自动注册的用户生成用户名和随机密码
+func (s *UserService) registerWithGeneratedUsername(phone, email string) (*model.User, error) {
+	password, err := randomPassword()
 	if err != nil {
-		return nil, "", ErrUserNotFound
+		return nil, err
 	}
 
-	if !util.CheckPassword(password, user.Password) {
-		return nil, "", ErrInvalidPassword
+	for i := 0; i < 5; i++ {
+		username, err := generateUsername()
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.Register(username, password, phone, email)
+		if err == ErrUserAlreadyExists {
+			continue
+		}
+		return user, err
 	}
+	return nil, ErrUserAlreadyExists
+}
 
-	token, err := util.GenerateToken(user.ID, user.Username)
+// registerWithIdentity 通过第三方登录自动注册，用户与第三方账号绑定在同一事务中创建。
+// email 为服务商确认过且未被占用的邮箱，写入时即标记为已验证
+func (s *UserService) registerWithIdentity(email string, identity *model.UserIdentity) (*model.User, error) {
+	password, err := randomPassword()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := util.HashPassword(password)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
-	return user, token, nil
+	for i := 0; i < 5; i++ {
+		username, err := generateUsername()
+		if err != nil {
+			return nil, err
+		}
+		if existingUser, _ := model.GetUserByUsername(username); existingUser != nil {
+			continue
+		}
+
+		user := &model.User{
+			Username:  username,
+			Password:  hashedPassword,
+			Email:     email,
+			Avatar:    "/uploads/avatars/default.png",
+			Status:    1,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if email != "" {
+			user.EmailVerified = 1
+		}
+		setting := &model.UserPrivacySetting{
+			DataCollection:    1,
+			PersonalizedAds:   1,
+			ThirdPartySharing: 0,
+			MarketingEmails:   1,
+		}
+		if err := model.CreateUserWithIdentity(user, setting, identity); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+	return nil, ErrUserAlreadyExists
+}
+
+func generateUsername() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(100000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("user_%08d", n.Int64()), nil
+}
+
+func randomPassword() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func (s *UserService) Login(username, password string, info util.SessionInfo) (*model.User, *TokenPair, error) {
+	return NewAuthService().LoginWithPassword(username, password, info)
 }
 
 func (s *UserService) GetUserByID(id int) (*model.User, error) {
@@ -109,7 +198,81 @@ func (s *UserService) ChangePassword(userID int, currentPassword, newPassword st
 	user.Password = hashedPassword
 	user.UpdatedAt = time.Now()
 
-	return model.UpdateUser(user)
+	if err := model.UpdateUser(user); err != nil {
+		return err
+	}
+
+	return NewAuthService().RevokeUserSessions(userID)
+}
+
+// SendContactCode 向用户当前填写的手机号或邮箱发送验证码
+func (s *UserService) SendContactCode(userID int, contactType, ip string) error {
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	channel, target, verified, err := userContact(user, contactType)
+	if err != nil {
+		return err
+	}
+	if verified {
+		return ErrContactVerified
+	}
+	return NewVerificationService().SendCode(VerifyPurposeContact, channel, target, ip)
+}
+
+// VerifyContact 校验验证码并标记联系方式已验证。验证码与发送时的地址绑定，
+// 发送后或校验期间修改了联系方式则原验证码无法通过
+func (s *UserService) VerifyContact(userID int, contactType, code string) error {
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	_, target, verified, err := userContact(user, contactType)
+	if err != nil {
+		return err
+	}
+	if verified {
+		return nil
+	}
+	if err := NewVerificationService().CheckCode(VerifyPurposeContact, target, code); err != nil {
+		return err
+	}
+	var marked bool
+	if contactType == ContactTypeEmail {
+		marked, err = model.MarkUserEmailVerified(userID, user.Email)
+	} else {
+		marked, err = model.MarkUserPhoneVerified(userID, user.Phone)
+	}
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return util.ErrVerifyCodeInvalid
+	}
+	return nil
+}
+
+// userContact 返回用户某类联系方式的发送渠道、规范化地址和验证状态
+func userContact(user *model.User, contactType string) (string, string, bool, error) {
+	var contact string
+	var verified bool
+	switch contactType {
+	case ContactTypePhone:
+		contact, verified = user.Phone, user.PhoneVerified == 1
+	case ContactTypeEmail:
+		contact, verified = user.Email, user.EmailVerified == 1
+	default:
+		return "", "", false, ErrContactTypeInvalid
+	}
+	if contact == "" {
+		return "", "", false, ErrContactEmpty
+	}
+	channel, target, err := NewVerificationService().NormalizeTarget(contact)
+	if err != nil {
+		return "", "", false, err
+	}
+	return channel, target, verified, nil
 }
 
 func (s *UserService) GetPrivacySettings(userID int) (*model.UserPrivacySetting, error) {
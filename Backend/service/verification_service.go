@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+
+	"ticket-system-backend/util"
+)
+
+// 验证码用途，不同用途的验证码互不通用
+const (
+	VerifyPurposeLogin   = "login"
+	VerifyPurposeContact = "contact"
+)
+
+var verifyPurposeNames = map[string]string{
+	VerifyPurposeLogin:   "登录",
+	VerifyPurposeContact: "绑定",
+}
+
+var (
+	ErrVerifyTargetInvalid  = errors.New("请输入正确的手机号或邮箱")
+	ErrVerifyCodeSendFailed = errors.New("验证码发送失败，请稍后再试")
+)
+
+type VerificationService struct{}
+
+func NewVerificationService() *VerificationService {
+	return &VerificationService{}
+}
+
+// NormalizeTarget 识别手机号或邮箱并规范化，返回发送渠道和规范化后的地址
+func (s *VerificationService) NormalizeTarget(target string) (string, string, error) {
+	target = strings.TrimSpace(target)
+	if strings.Contains(target, "@") {
+		addr, err := mail.ParseAddress(target)
+		if err != nil || addr.Address != target {
+			return "", "", ErrVerifyTargetInvalid
+		}
+		return util.ChannelEmail, strings.ToLower(target), nil
+	}
+	target = strings.TrimPrefix(target, "+86")
+	if !util.ValidatePhone(target) {
+		return "", "", ErrVerifyTargetInvalid
+	}
+	return util.ChannelSMS, target, nil
+}
+
+// SendCode 生成验证码并通过短信或邮件发送，target 须已规范化
+func (s *VerificationService) SendCode(purpose, channel, target, ip string) error {
+	code, err := util.IssueVerifyCode(purpose, target, ip)
+	if err != nil {
+		return err
+	}
+	return s.deliverCode(purpose, channel, target, code)
+}
+
+// deliverCode 通过短信或邮件发送已签发的验证码
+func (s *VerificationService) deliverCode(purpose, channel, target, code string) error {
+	minutes := int(util.VerifyCodeExpiration.Minutes())
+	name := verifyPurposeNames[purpose]
+	body := fmt.Sprintf("您的%s验证码为 %s，%d 分钟内有效。请勿泄露给他人，如非本人操作请忽略。", name, code, minutes)
+	var err error
+	if channel == util.ChannelEmail {
+		err = util.SendMail(target, name+"验证码", body)
+	} else {
+		err = util.SendSMS(target, "【票务系统】"+body)
+	}
+	if err != nil {
+		log.Printf("验证码发送失败: channel=%s to=%s err=%v", channel, target, err)
+		return ErrVerifyCodeSendFailed
+	}
+	return nil
+}
+
+// CheckCode 校验验证码，target 须已规范化
+func (s *VerificationService) CheckCode(purpose, target, code string) error {
+	return util.CheckVerifyCode(purpose, target, code)
+}
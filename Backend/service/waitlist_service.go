@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"ticket-system-backend/model"
+	"ticket-system-backend/util"
+)
+
+var (
+	ErrWaitlistNotSoldOut    = errors.New("票种尚有库存，请直接购买")
+	ErrWaitlistEntryExists   = errors.New("已在该票种的候补队列中")
+	ErrWaitlistEntryNotFound = errors.New("候补登记不存在")
+	ErrWaitlistNotWaiting    = errors.New("候补已获得订单，请在订单中取消")
+)
+
+// 单次为一个票种发放的候补订单上限，避免长时间占用锁
+const maxOffersPerRun = 50
+
+type WaitlistService struct{}
+
+func NewWaitlistService() *WaitlistService {
+	return &WaitlistService{}
+}
+
+// GetEntries 获取用户的候补登记，排队中的登记附带当前位置
+func (s *WaitlistService) GetEntries(userID int) ([]*model.WaitlistEntry, error) {
+	entries, err := model.GetUserWaitlistEntries(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Status == model.WaitlistWaiting {
+			entry.Position = model.GetWaitlistPosition(entry)
+		}
+	}
+	return entries, nil
+}
+
+// Join 加入售罄票种的候补队列，实名演出需按张指定观演人。
+// 库存扣除排队中候补所需的张数后不足 quantity 即视为售罄
+func (s *WaitlistService) Join(userID, ticketTypeID, quantity int, attendeeIDs []int) (*model.WaitlistEntry, error) {
+	ticketType, err := model.GetTicketTypeByID(ticketTypeID)
+	if err != nil || !canAccessTicketType(userID, ticketType) {
+		return nil, ErrTicketTypeNotFound
+	}
+
+	if ticketType.SaleMode == model.SaleModeBallot {
+		return nil, ErrBallotTicketType
+	}
+
+	if quantity <= 0 || quantity > util.GetConfig().Seckill.MaxQuantityPerUser {
+		return nil, ErrTicketLimitExceeded
+	}
+
+	// 已有候补排队时，库存先留给排在前面的候补
+	waiting, err := model.WaitingWaitlistQuantity(ticketTypeID)
+	if err != nil {
+		return nil, err
+	}
+	if ticketType.Stock-waiting >= quantity {
+		return nil, ErrWaitlistNotSoldOut
+	}
+
+	performance, err := model.GetPerformanceByID(ticketType.PerformanceID)
+	if err != nil || performance == nil {
+		return nil, ErrPerformanceNotFound
+	}
+
+	if performance.RequireRealName == 1 && len(attendeeIDs) == 0 {
+		return nil, ErrRealNameRequired
+	}
+	if _, err := loadOrderAttendees(userID, []OrderLine{{
+		TicketTypeID: ticketTypeID,
+		Quantity:     quantity,
+		AttendeeIDs:  attendeeIDs,
+	}}); err != nil {
+		return nil, err
+	}
+
+	if model.HasActiveWaitlistEntry(ticketTypeID, userID) {
+		return nil, ErrWaitlistEntryExists
+	}
+
+	entry := &model.WaitlistEntry{
+		TicketTypeID: ticketTypeID,
+		UserID:       userID,
+		Quantity:     quantity,
+		AttendeeIDs:  joinIDs(attendeeIDs),
+		Status:       model.WaitlistWaiting,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if err := model.CreateWaitlistEntry(entry); err != nil {
+		// 并发加入时由唯一约束保证每人每个票种只有一条未结束的候补
+		if util.IsDuplicateKeyError(err) {
+			return nil, ErrWaitlistEntryExists
+		}
+		return nil, err
+	}
+	entry.Position = model.GetWaitlistPosition(entry)
+	return entry, nil
+}
+
+// Leave 退出候补队列，已获得候补订单的需取消订单
+func (s *WaitlistService) Leave(userID, entryID int) error {
+	entry, err := model.GetWaitlistEntryByID(entryID)
+	if err != nil || entry.UserID != userID {
+		return ErrWaitlistEntryNotFound
+	}
+
+	ok, err := model.LeaveWaitlist(entry.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrWaitlistNotWaiting
+	}
+	return nil
+}
+
+// OfferReleasedStock 按排队顺序为候补用户创建限时支付的专属订单，候补在下单事务中标记为待支付。
+// 排在最前的候补所需数量超过当前库存时停止分配，不跳过，保证先到先得。
+// 无法下单的候补（如观演人已持票）标记为失效后顺延给下一位，下单前已退出的候补直接跳过。
+func (s *WaitlistService) OfferReleasedStock(ctx context.Context, ticketTypeID int) {
+	expireMinutes := util.GetConfig().Waitlist.OfferMinutes
+	orderService := NewOrderService()
+
+	for i := 0; i < maxOffersPerRun; i++ {
+		ticketType, err := model.GetTicketTypeByID(ticketTypeID)
+		if err != nil || ticketType.Stock <= 0 {
+			return
+		}
+
+		entry, err := model.GetNextWaitlistEntry(ticketTypeID)
+		if err != nil || entry.Quantity > ticketType.Stock {
+			return
+		}
+
+		expireTime := time.Now().Add(time.Duration(expireMinutes) * time.Minute)
+		_, err = orderService.placeOrder(ctx, entry.UserID, []OrderLine{{
+			TicketTypeID: ticketTypeID,
+			Quantity:     entry.Quantity,
+			AttendeeIDs:  splitIDs(entry.AttendeeIDs),
+		}}, orderOptions{WaitlistEntryID: entry.ID, ExpireTime: expireTime})
+		switch err {
+		case nil, ErrWaitlistNotWaiting:
+			// 已下单，或候补在下单前已退出、订单已回滚，继续分配给下一位
+		case ErrStockInsufficient, ErrLockAcquireFailed, ErrSeckillFailed:
+			// 库存被抢占或系统繁忙，等待下次扫描
+			return
+		default:
+			log.Printf("候补下单失败: entry=%d, err=%v", entry.ID, err)
+			if err := model.LapseWaitlistEntry(entry.ID); err != nil {
+				// 候补仍为排队中，继续分配会再次为其下单
+				log.Printf("标记候补失效失败: entry=%d, err=%v", entry.ID, err)
+				return
+			}
+		}
+	}
+}
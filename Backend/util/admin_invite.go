@@ -0,0 +1,35 @@
+package util
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrAdminInviteTokenInvalid = errors.New("邀请链接无效或已过期")
+
+// 邀请令牌中包含邀请ID和随机数。随机数保存在邀请记录中，撤销或接受邀请后记录不再处于待接受状态，令牌随之失效
+const adminInviteTokenPurpose = "admin-invite"
+
+// NewAdminInviteNonce 生成邀请记录中保存的随机数
+func NewAdminInviteNonce() (string, error) {
+	return randomToken(16)
+}
+
+// SignAdminInviteToken 为邀请签发令牌
+func SignAdminInviteToken(invitationID int, expiresAt time.Time, nonce string) string {
+	return issueSignedToken(adminInviteTokenPurpose, expiresAt, strconv.Itoa(invitationID), nonce)
+}
+
+// ParseAdminInviteToken 校验签名和有效期，返回邀请ID和随机数，随机数须与邀请记录中的一致
+func ParseAdminInviteToken(token string) (int, string, error) {
+	fields, ok := parseSignedToken(adminInviteTokenPurpose, token, 2)
+	if !ok {
+		return 0, "", ErrAdminInviteTokenInvalid
+	}
+	invitationID, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, "", ErrAdminInviteTokenInvalid
+	}
+	return invitationID, fields[1], nil
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 管理员的有效权限缓存在 Redis 中，角色或管理员变更时主动清除，过期时间兜底
+const adminPermissionCacheExpiration = 5 * time.Minute
+
+func adminPermissionKey(adminID int) string {
+	return "admin:permissions:" + strconv.Itoa(adminID)
+}
+
+// GetCachedAdminPermissions 读取缓存的权限，found 为 false 表示未缓存
+func GetCachedAdminPermissions(adminID int) (permissions []string, found bool, err error) {
+	value, err := RedisClient.Get(ctx, adminPermissionKey(adminID)).Result()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if value == "" {
+		return []string{}, true, nil
+	}
+	return strings.Split(value, ","), true, nil
+}
+
+// CacheAdminPermissions 缓存管理员的有效权限，没有任何权限时同样缓存
+func CacheAdminPermissions(adminID int, permissions []string) error {
+	return RedisClient.Set(ctx, adminPermissionKey(adminID), strings.Join(permissions, ","), adminPermissionCacheExpiration).Err()
+}
+
+// InvalidateAdminPermissions 清除管理员的权限缓存，下次请求时重新加载
+func InvalidateAdminPermissions(adminIDs ...int) error {
+	if len(adminIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, len(adminIDs))
+	for i, id := range adminIDs {
+		keys[i] = adminPermissionKey(id)
+	}
+	return RedisClient.Del(ctx, keys...).Err()
+}
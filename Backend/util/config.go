@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -17,6 +18,15 @@ type Config struct {
 	CORS     CORSConfig
 	Upload   UploadConfig
 	Seckill  SeckillConfig
+	Security SecurityConfig
+	Waitlist WaitlistConfig
+	Export   DataExportConfig
+	Account  AccountConfig
+	Pricing  PricingConfig
+	Invoice  InvoiceConfig
+	Mail     MailConfig
+	SMS      SMSConfig
+	OIDC     OIDCConfig
 }
 
 type ServerConfig struct {
@@ -49,8 +59,11 @@ type RedisConfig struct {
 }
 
 type JWTConfig struct {
-	Secret string
-	Expire int64
+	Secret        string
+	Expire        int64
+	AccessExpire  int64
+	RefreshExpire int64
+	KeySetFile    string
 }
 
 type CORSConfig struct {
@@ -64,8 +77,88 @@ type UploadConfig struct {
 }
 
 type SeckillConfig struct {
-	OrderExpireMinutes int
-	MaxQuantityPerUser int
+	OrderExpireMinutes     int
+	MaxQuantityPerUser     int
+	RequireVerifiedContact bool // 抢票前须验证手机号或邮箱
+}
+
+type SecurityConfig struct {
+	DataKey          string
+	ResetPasswordURL string // 前端重置密码页面地址，重置令牌以 token 参数附加在后面
+	TOTPIssuer       string // 验证器 App 中显示的服务名称
+	AdminInviteURL   string // 前端接受管理员邀请的页面地址，邀请令牌以 token 参数附加在后面
+	AdminInviteHours int    // 管理员邀请链接的有效期（小时）
+
+	// 登录防暴力破解
+	LoginMaxFailures    int // 窗口期内账号失败达到该次数后锁定
+	LoginIPMaxFailures  int // 窗口期内同一 IP 失败达到该次数后暂停该 IP 登录
+	LoginFailureWindow  int // 失败计数窗口（秒）
+	LoginLockoutSeconds int // 首次锁定时长（秒），24 小时内再次锁定时翻倍
+}
+
+type WaitlistConfig struct {
+	OfferMinutes        int
+	ScanIntervalSeconds int
+}
+
+// AccountConfig 用户账号注销
+type AccountConfig struct {
+	DeletionGraceDays int // 申请注销后的冷静期（天），期间可撤销，期满后匿名化账号
+}
+
+// DataExportConfig 用户个人数据导出
+type DataExportConfig struct {
+	Dir             string // 导出文件的存放目录，不能位于对外公开的上传目录中
+	ExpireHours     int    // 导出文件的下载有效期（小时），过期后删除
+	CooldownMinutes int    // 同一用户两次申请导出的最小间隔（分钟）
+}
+
+type PricingConfig struct {
+	Currency string
+}
+
+// InvoiceConfig 开票方信息，印在发票上
+type InvoiceConfig struct {
+	CompanyName string
+	TaxID       string
+	Address     string
+	Phone       string
+	Prefix      string // 发票号前缀
+}
+
+// MailConfig 邮件发送配置，Driver 为 smtp、log 或 file，未配置 SMTP 时默认不发送
+type MailConfig struct {
+	Driver     string
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	From       string
+	OutboxFile string // file 驱动写入的文件
+}
+
+// SMSConfig 短信发送配置，Driver 为 webhook、log 或 file，未配置时不发送
+type SMSConfig struct {
+	Driver       string
+	WebhookURL   string // 短信网关地址，POST JSON {"phone","content"}
+	WebhookToken string
+	OutboxFile   string
+}
+
+// OIDCConfig 第三方登录服务商（OpenID Connect），每个服务商单独配置
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig
+}
+
+// OIDCProviderConfig 单个服务商的配置，ClientSecret 可用环境变量 OIDC_<NAME>_CLIENT_SECRET 覆盖
+type OIDCProviderConfig struct {
+	Name         string   `mapstructure:"name" json:"name"`                 // 接口路径中的标识，如 google
+	DisplayName  string   `mapstructure:"display_name" json:"display_name"` // 登录按钮上显示的名称
+	Issuer       string   `mapstructure:"issuer" json:"-"`
+	ClientID     string   `mapstructure:"client_id" json:"-"`
+	ClientSecret string   `mapstructure:"client_secret" json:"-"` // 公共客户端（只用 PKCE）可为空
+	RedirectURL  string   `mapstructure:"redirect_url" json:"-"`  // 前端回调页面，需在服务商处登记
+	Scopes       []string `mapstructure:"scopes" json:"-"`
 }
 
 var AppConfig *Config
@@ -135,6 +228,9 @@ func InitConfig() error {
 		log.Fatal("JWT_SECRET 环境变量未设置，请设置 JWT_SECRET 环境变量")
 	}
 	cfg.JWT.Expire = int64(viperGetInt("jwt.expire", 7200))
+	cfg.JWT.AccessExpire = int64(viperGetInt("jwt.access_expire", 900))
+	cfg.JWT.RefreshExpire = int64(viperGetInt("jwt.refresh_expire", 2592000))
+	cfg.JWT.KeySetFile = viperGetString("jwt.keyset_file", "")
 
 	corsOrigins := viperGetString("cors.allowed_origins", "http://localhost:3000,http://localhost")
 	cfg.CORS.AllowedOrigins = strings.Split(corsOrigins, ",")
@@ -144,6 +240,72 @@ func InitConfig() error {
 
 	cfg.Seckill.OrderExpireMinutes = viperGetInt("seckill.order_expire_minutes", 30)
 	cfg.Seckill.MaxQuantityPerUser = viperGetInt("seckill.max_quantity_per_user", 5)
+	cfg.Seckill.RequireVerifiedContact = viperGetBool("seckill.require_verified_contact", false)
+
+	cfg.Security.DataKey = viperGetString("security.data_key", "")
+	if cfg.Security.DataKey == "" {
+		log.Println("security.data_key 未设置，敏感数据将使用 JWT_SECRET 派生的密钥加密")
+		cfg.Security.DataKey = cfg.JWT.Secret
+	}
+	cfg.Security.ResetPasswordURL = viperGetString("security.reset_password_url", "http://localhost:3000/reset-password")
+	cfg.Security.TOTPIssuer = viperGetString("security.totp_issuer", "TicketSystem")
+	cfg.Security.AdminInviteURL = viperGetString("security.admin_invite_url", "http://localhost:3000/admin/accept-invite")
+	cfg.Security.AdminInviteHours = viperGetInt("security.admin_invite_hours", 72)
+	cfg.Security.LoginMaxFailures = viperGetInt("security.login_max_failures", 5)
+	cfg.Security.LoginIPMaxFailures = viperGetInt("security.login_ip_max_failures", 30)
+	cfg.Security.LoginFailureWindow = viperGetInt("security.login_failure_window", 900)
+	cfg.Security.LoginLockoutSeconds = viperGetInt("security.login_lockout_seconds", 900)
+
+	cfg.Waitlist.OfferMinutes = viperGetInt("waitlist.offer_minutes", 15)
+	cfg.Waitlist.ScanIntervalSeconds = viperGetInt("waitlist.scan_interval_seconds", 30)
+
+	cfg.Export.Dir = viperGetString("export.dir", "./exports")
+	cfg.Export.ExpireHours = viperGetInt("export.expire_hours", 24)
+	cfg.Export.CooldownMinutes = viperGetInt("export.cooldown_minutes", 1440)
+
+	cfg.Account.DeletionGraceDays = viperGetInt("account.deletion_grace_days", 14)
+
+	cfg.Pricing.Currency = strings.ToUpper(viperGetString("pricing.currency", "CNY"))
+	if !supportedCurrencies[cfg.Pricing.Currency] {
+		log.Printf("不支持的结算币种 %s，使用 CNY", cfg.Pricing.Currency)
+		cfg.Pricing.Currency = "CNY"
+	}
+
+	cfg.Invoice.CompanyName = viperGetString("invoice.company_name", "票务系统")
+	cfg.Invoice.TaxID = viperGetString("invoice.tax_id", "")
+	cfg.Invoice.Address = viperGetString("invoice.address", "")
+	cfg.Invoice.Phone = viperGetString("invoice.phone", "")
+	cfg.Invoice.Prefix = viperGetString("invoice.prefix", "INV")
+
+	cfg.Mail.Host = viperGetString("mail.host", "")
+	cfg.Mail.Port = viperGetInt("mail.port", 465)
+	cfg.Mail.Username = viperGetString("mail.username", "")
+	cfg.Mail.Password = viperGetString("mail.password", "")
+	cfg.Mail.From = viperGetString("mail.from", cfg.Mail.Username)
+	defaultMailDriver := ""
+	if cfg.Mail.Host != "" {
+		defaultMailDriver = "smtp"
+	}
+	cfg.Mail.Driver = viperGetString("mail.driver", defaultMailDriver)
+	cfg.Mail.OutboxFile = viperGetString("mail.outbox_file", "./outbox/mail.jsonl")
+
+	cfg.SMS.Driver = viperGetString("sms.driver", "")
+	cfg.SMS.WebhookURL = viperGetString("sms.webhook_url", "")
+	cfg.SMS.WebhookToken = viperGetString("sms.webhook_token", "")
+	cfg.SMS.OutboxFile = viperGetString("sms.outbox_file", "./outbox/sms.jsonl")
+
+	if err := viper.UnmarshalKey("oidc.providers", &cfg.OIDC.Providers); err != nil {
+		log.Printf("第三方登录配置解析失败: %v", err)
+	}
+	for i := range cfg.OIDC.Providers {
+		p := &cfg.OIDC.Providers[i]
+		if secret := os.Getenv("OIDC_" + strings.ToUpper(p.Name) + "_CLIENT_SECRET"); secret != "" {
+			p.ClientSecret = secret
+		}
+		if p.DisplayName == "" {
+			p.DisplayName = p.Name
+		}
+	}
 
 	AppConfig = cfg
 	log.Println("配置加载成功")
@@ -161,6 +323,18 @@ func viperGetString(key, defaultValue string) string {
 	return defaultValue
 }
 
+func viperGetBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(strings.ReplaceAll(key, ".", "_")); value != "" {
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	if viper.IsSet(key) {
+		return viper.GetBool(key)
+	}
+	return defaultValue
+}
+
 func viperGetInt(key string, defaultValue int) int {
 	if value := os.Getenv(strings.ReplaceAll(key, ".", "_")); value != "" {
 		if v, err := fmt.Sscanf(value, "%d", &defaultValue); err == nil && v > 0 {
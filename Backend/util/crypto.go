@@ -0,0 +1,98 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+)
+
+var ErrDecryptFailed = errors.New("数据解密失败")
+
+func dataKey() []byte {
+	sum := sha256.Sum256([]byte(GetConfig().Security.DataKey))
+	return sum[:]
+}
+
+// EncryptString 使用 AES-256-GCM 加密敏感字段，返回 base64(nonce|密文)
+func EncryptString(plain string) (string, error) {
+	block, err := aes.NewCipher(dataKey())
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString 解密 EncryptString 的结果
+func DecryptString(encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrDecryptFailed
+	}
+
+	block, err := aes.NewCipher(dataKey())
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return "", ErrDecryptFailed
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrDecryptFailed
+	}
+	return string(plain), nil
+}
+
+// HashSensitive 计算敏感字段的 HMAC-SHA256，用于在不解密的情况下做等值查询
+func HashSensitive(value string) string {
+	mac := hmac.New(sha256.New, dataKey())
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignTicketCode 生成电子票二维码内容：票号.签名，签名为 HMAC-SHA256 的前 16 字节，防止伪造票号
+func SignTicketCode(ticketNo string) string {
+	return ticketNo + "." + ticketSignature(ticketNo)
+}
+
+// VerifyTicketCode 校验二维码内容的签名，返回其中的票号
+func VerifyTicketCode(code string) (string, bool) {
+	i := strings.LastIndexByte(code, '.')
+	if i <= 0 {
+		return "", false
+	}
+	ticketNo := code[:i]
+	if !hmac.Equal([]byte(code[i+1:]), []byte(ticketSignature(ticketNo))) {
+		return "", false
+	}
+	return ticketNo, true
+}
+
+func ticketSignature(ticketNo string) string {
+	mac := hmac.New(sha256.New, dataKey())
+	mac.Write([]byte("ticket:" + ticketNo))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
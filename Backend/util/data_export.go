@@ -0,0 +1,57 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var ErrDataExportTokenInvalid = errors.New("下载链接无效或已过期")
+
+func dataExportCooldownKey(userID int) string {
+	return fmt.Sprintf("user:data_export:cooldown:%d", userID)
+}
+
+// AllowDataExportRequest 同一用户在冷却时间内只能申请一次导出，被限制时返回剩余等待时间
+func AllowDataExportRequest(userID int) (bool, time.Duration, error) {
+	cooldown := time.Duration(GetConfig().Export.CooldownMinutes) * time.Minute
+	if cooldown <= 0 {
+		return true, 0, nil
+	}
+	ok, err := RedisClient.SetNX(ctx, dataExportCooldownKey(userID), 1, cooldown).Result()
+	if err != nil || ok {
+		return ok, 0, err
+	}
+	ttl, err := RedisClient.PTTL(ctx, dataExportCooldownKey(userID)).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	return false, ttl, nil
+}
+
+// ClearDataExportCooldown 申请未能成功创建导出任务时清除冷却，允许用户立即重试
+func ClearDataExportCooldown(userID int) error {
+	return RedisClient.Del(ctx, dataExportCooldownKey(userID)).Err()
+}
+
+const dataExportTokenPurpose = "data-export"
+
+// SignDataExportToken 为导出文件签发下载令牌，令牌中包含导出ID和用户ID，下载时还须以该用户身份登录
+func SignDataExportToken(exportID, userID int, expiresAt time.Time) string {
+	return issueSignedToken(dataExportTokenPurpose, expiresAt, strconv.Itoa(exportID), strconv.Itoa(userID))
+}
+
+// ParseDataExportToken 校验签名和有效期，返回导出ID和所属用户ID
+func ParseDataExportToken(token string) (int, int, error) {
+	fields, ok := parseSignedToken(dataExportTokenPurpose, token, 2)
+	if !ok {
+		return 0, 0, ErrDataExportTokenInvalid
+	}
+	exportID, err1 := strconv.Atoi(fields[0])
+	userID, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil {
+		return 0, 0, ErrDataExportTokenInvalid
+	}
+	return exportID, userID, nil
+}
@@ -0,0 +1,83 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+// ICSEvent 日历事件
+type ICSEvent struct {
+	UID         string
+	Summary     string
+	Location    string
+	Description string
+	Start       time.Time
+	End         time.Time
+	Updated     time.Time
+}
+
+// BuildICS 按 RFC 5545 生成 iCalendar 文件，时间统一输出为 UTC
+func BuildICS(name string, events []ICSEvent) []byte {
+	var buf bytes.Buffer
+	writeICSLine(&buf, "BEGIN:VCALENDAR")
+	writeICSLine(&buf, "VERSION:2.0")
+	writeICSLine(&buf, "PRODID:-//ticket-system//calendar//CN")
+	writeICSLine(&buf, "CALSCALE:GREGORIAN")
+	writeICSLine(&buf, "METHOD:PUBLISH")
+	writeICSLine(&buf, "X-WR-CALNAME:"+escapeICSText(name))
+	// 订阅源建议的刷新间隔
+	writeICSLine(&buf, "REFRESH-INTERVAL;VALUE=DURATION:PT6H")
+	writeICSLine(&buf, "X-PUBLISHED-TTL:PT6H")
+
+	stamp := formatICSTime(time.Now())
+	for _, event := range events {
+		writeICSLine(&buf, "BEGIN:VEVENT")
+		writeICSLine(&buf, "UID:"+event.UID)
+		writeICSLine(&buf, "DTSTAMP:"+stamp)
+		if !event.Updated.IsZero() {
+			writeICSLine(&buf, "LAST-MODIFIED:"+formatICSTime(event.Updated))
+		}
+		writeICSLine(&buf, "DTSTART:"+formatICSTime(event.Start))
+		writeICSLine(&buf, "DTEND:"+formatICSTime(event.End))
+		writeICSLine(&buf, "SUMMARY:"+escapeICSText(event.Summary))
+		if event.Location != "" {
+			writeICSLine(&buf, "LOCATION:"+escapeICSText(event.Location))
+		}
+		if event.Description != "" {
+			writeICSLine(&buf, "DESCRIPTION:"+escapeICSText(event.Description))
+		}
+		writeICSLine(&buf, "STATUS:CONFIRMED")
+		writeICSLine(&buf, "END:VEVENT")
+	}
+	writeICSLine(&buf, "END:VCALENDAR")
+	return buf.Bytes()
+}
+
+func formatICSTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func escapeICSText(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\\`)
+	text = strings.ReplaceAll(text, ";", `\;`)
+	text = strings.ReplaceAll(text, ",", `\,`)
+	text = strings.ReplaceAll(text, "\r\n", `\n`)
+	text = strings.ReplaceAll(text, "\n", `\n`)
+	return text
+}
+
+// writeICSLine 写入一行内容，超过 75 字节时折行，且不拆分多字节字符
+func writeICSLine(buf *bytes.Buffer, line string) {
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			buf.WriteString("\r\n ")
+			width = 1
+		}
+		buf.WriteRune(r)
+		width += size
+	}
+	buf.WriteString("\r\n")
+}
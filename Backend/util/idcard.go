@@ -0,0 +1,50 @@
+package util
+
+import (
+	"strings"
+	"time"
+)
+
+var idCardWeights = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+const idCardCheckCodes = "10X98765432"
+
+// NormalizeIDNumber 去除空白并统一校验位为大写
+func NormalizeIDNumber(idNumber string) string {
+	return strings.ToUpper(strings.TrimSpace(idNumber))
+}
+
+// ValidateIDNumber 校验18位居民身份证号码的出生日期和校验位
+func ValidateIDNumber(idNumber string) bool {
+	if len(idNumber) != 18 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 17; i++ {
+		c := idNumber[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		sum += int(c-'0') * idCardWeights[i]
+	}
+
+	if idCardCheckCodes[sum%11] != idNumber[17] {
+		return false
+	}
+
+	birth, err := time.Parse("20060102", idNumber[6:14])
+	if err != nil || birth.After(time.Now()) {
+		return false
+	}
+
+	return true
+}
+
+// MaskIDNumber 证件号脱敏，仅保留前3位和后4位
+func MaskIDNumber(idNumber string) string {
+	if len(idNumber) <= 7 {
+		return strings.Repeat("*", len(idNumber))
+	}
+	return idNumber[:3] + strings.Repeat("*", len(idNumber)-7) + idNumber[len(idNumber)-4:]
+}
@@ -3,11 +3,25 @@ package util
 import (
 	"errors"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// 用户令牌和管理员令牌使用不同的受众，中间件只接受各自受众的令牌
+const (
+	TokenIssuer        = "ticket-system"
+	TokenAudienceUser  = "ticket-system-user"
+	TokenAudienceAdmin = "ticket-system-admin"
+	TokenTypeAccess    = "access"
+
+	// 管理员通过密码校验后的临时令牌，只能用于完成两步验证或首次绑定验证器
+	TokenTypeTwoFactorVerify = "2fa_verify"
+	TokenTypeTwoFactorSetup  = "2fa_setup"
+	TwoFactorTokenExpiration = 5 * time.Minute
+)
+
 func getJWTSecret() string {
 	if secret := os.Getenv("JWT_SECRET"); secret != "" {
 		return secret
@@ -16,50 +30,50 @@ func getJWTSecret() string {
 }
 
 type CustomClaims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
+	UserID    int    `json:"user_id"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid"`
+	TokenType string `json:"typ"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID int, username string) (string, error) {
+// GenerateToken 生成用户访问令牌，令牌绑定登录会话，会话注销后立即失效
+func GenerateToken(userID int, username, sessionID string) (string, error) {
 	cfg := GetConfig()
 
 	claims := CustomClaims{
-		UserID:   userID,
-		Username: username,
+		UserID:    userID,
+		Username:  username,
+		SessionID: sessionID,
+		TokenType: TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.JWT.Expire) * time.Second)),
+			Issuer:    TokenIssuer,
+			Subject:   strconv.Itoa(userID),
+			Audience:  jwt.ClaimStrings{TokenAudienceUser},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.JWT.AccessExpire) * time.Second)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(getJWTSecret()))
+	return signToken(claims)
 }
 
 func ParseToken(tokenString string) (*CustomClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(getJWTSecret()), nil
-	})
-
-	if err != nil {
+	claims := &CustomClaims{}
+	if err := parseToken(tokenString, claims); err != nil {
 		return nil, err
 	}
-
-	if claims, ok := token.Claims.(*CustomClaims); ok && token.Valid {
-		return claims, nil
+	if !claims.VerifyIssuer(TokenIssuer, true) || !claims.VerifyAudience(TokenAudienceUser, true) || claims.TokenType != TokenTypeAccess {
+		return nil, errors.New("invalid token")
 	}
-
-	return nil, errors.New("invalid token")
+	return claims, nil
 }
 
 type AdminClaims struct {
-	AdminID  int    `json:"admin_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	AdminID   int    `json:"admin_id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	TokenType string `json:"typ"`
 	jwt.RegisteredClaims
 }
 
@@ -67,34 +81,102 @@ func GenerateAdminToken(adminID int, username, role string) (string, error) {
 	cfg := GetConfig()
 
 	claims := AdminClaims{
-		AdminID:  adminID,
-		Username: username,
-		Role:     role,
+		AdminID:   adminID,
+		Username:  username,
+		Role:      role,
+		TokenType: TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			Subject:   strconv.Itoa(adminID),
+			Audience:  jwt.ClaimStrings{TokenAudienceAdmin},
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(cfg.JWT.Expire) * time.Second)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(getJWTSecret()))
+	return signToken(claims)
 }
 
 func ParseAdminToken(tokenString string) (*AdminClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &AdminClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(getJWTSecret()), nil
-	})
+	claims := &AdminClaims{}
+	if err := parseToken(tokenString, claims); err != nil {
+		return nil, err
+	}
+	if !claims.VerifyIssuer(TokenIssuer, true) || !claims.VerifyAudience(TokenAudienceAdmin, true) || claims.TokenType != TokenTypeAccess || claims.AdminID == 0 {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
 
-	if err != nil {
+// GenerateAdminTwoFactorToken 签发两步验证阶段的临时令牌，不能用于访问管理接口
+func GenerateAdminTwoFactorToken(adminID int, username, role, tokenType string) (string, error) {
+	claims := AdminClaims{
+		AdminID:   adminID,
+		Username:  username,
+		Role:      role,
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			Subject:   strconv.Itoa(adminID),
+			Audience:  jwt.ClaimStrings{TokenAudienceAdmin},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TwoFactorTokenExpiration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return signToken(claims)
+}
+
+// ParseAdminTwoFactorToken 解析两步验证阶段的临时令牌，令牌类型必须与预期一致
+func ParseAdminTwoFactorToken(tokenString, tokenType string) (*AdminClaims, error) {
+	claims := &AdminClaims{}
+	if err := parseToken(tokenString, claims); err != nil {
 		return nil, err
 	}
+	if !claims.VerifyIssuer(TokenIssuer, true) || !claims.VerifyAudience(TokenAudienceAdmin, true) || claims.TokenType != tokenType || claims.AdminID == 0 {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// signToken 使用密钥集中当前的签名密钥签发令牌，并在头部写入 kid
+func signToken(claims jwt.Claims) (string, error) {
+	ks, err := getKeySet()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(ks.active.method, claims)
+	token.Header["kid"] = ks.active.kid
+	return token.SignedString(ks.active.private)
+}
 
-	if claims, ok := token.Claims.(*AdminClaims); ok && token.Valid {
-		return claims, nil
+// parseToken 按头部的 kid 选择公钥验证签名，签名算法必须与密钥类型一致
+func parseToken(tokenString string, claims jwt.Claims) error {
+	ks, err := getKeySet()
+	if err != nil {
+		return err
 	}
 
-	return nil, errors.New("invalid token")
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{
+		jwt.SigningMethodEdDSA.Alg(),
+		jwt.SigningMethodRS256.Alg(),
+	}))
+	token, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.public, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+	return nil
 }
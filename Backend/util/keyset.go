@@ -0,0 +1,286 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 签名密钥集文件格式（JSON），私钥/公钥为 PEM 文件，相对路径以密钥集文件所在目录为准：
+//
+//	{
+//	  "active_kid": "2026-10",
+//	  "keys": [
+//	    {"kid": "2026-10", "private_key_file": "jwt-2026-10.pem"},
+//	    {"kid": "2026-04", "public_key_file": "jwt-2026-04.pub.pem"}
+//	  ]
+//	}
+//
+// active_kid 用于签发新令牌，其余密钥只用于验证尚未过期的旧令牌。轮换时先加入新密钥并切换
+// active_kid，待旧令牌全部过期后再移除旧密钥。文件修改后无需重启，最迟 keySetCheckInterval 后生效。
+type keySetFile struct {
+	ActiveKID string `json:"active_kid"`
+	Keys      []struct {
+		KID            string `json:"kid"`
+		PrivateKeyFile string `json:"private_key_file"`
+		PublicKeyFile  string `json:"public_key_file"`
+	} `json:"keys"`
+}
+
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+type keySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+const keySetCheckInterval = 30 * time.Second
+
+var (
+	keySetMu        sync.Mutex
+	currentKeySet   *keySet
+	keySetModTime   time.Time
+	keySetCheckedAt time.Time
+)
+
+// InitJWTKeys 启动时加载签名密钥，配置错误时尽早失败
+func InitJWTKeys() error {
+	_, err := getKeySet()
+	return err
+}
+
+// getKeySet 返回当前密钥集，密钥集文件变化时自动重新加载；重新加载失败时继续使用旧密钥集
+func getKeySet() (*keySet, error) {
+	keySetMu.Lock()
+	defer keySetMu.Unlock()
+
+	path := GetConfig().JWT.KeySetFile
+	if path == "" {
+		if currentKeySet == nil {
+			ks, err := derivedKeySet()
+			if err != nil {
+				return nil, err
+			}
+			log.Println("jwt.keyset_file 未设置，使用 JWT_SECRET 派生的 Ed25519 签名密钥，生产环境请配置密钥集以支持轮换")
+			currentKeySet = ks
+		}
+		return currentKeySet, nil
+	}
+
+	if currentKeySet != nil && time.Since(keySetCheckedAt) < keySetCheckInterval {
+		return currentKeySet, nil
+	}
+	keySetCheckedAt = time.Now()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if currentKeySet != nil {
+			log.Printf("读取JWT密钥集失败，继续使用已加载的密钥: %v", err)
+			return currentKeySet, nil
+		}
+		return nil, fmt.Errorf("读取JWT密钥集失败: %w", err)
+	}
+	if currentKeySet != nil && info.ModTime().Equal(keySetModTime) {
+		return currentKeySet, nil
+	}
+
+	ks, err := loadKeySet(path)
+	if err != nil {
+		if currentKeySet != nil {
+			log.Printf("重新加载JWT密钥集失败，继续使用已加载的密钥: %v", err)
+			return currentKeySet, nil
+		}
+		return nil, err
+	}
+	currentKeySet = ks
+	keySetModTime = info.ModTime()
+	return currentKeySet, nil
+}
+
+func loadKeySet(path string) (*keySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取JWT密钥集失败: %w", err)
+	}
+	var file keySetFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析JWT密钥集失败: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(name string) string {
+		if filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(dir, name)
+	}
+
+	ks := &keySet{keys: make(map[string]*signingKey)}
+	for _, entry := range file.Keys {
+		if entry.KID == "" {
+			return nil, errors.New("JWT密钥缺少 kid")
+		}
+		if _, exists := ks.keys[entry.KID]; exists {
+			return nil, fmt.Errorf("JWT密钥 kid 重复: %s", entry.KID)
+		}
+		key := &signingKey{kid: entry.KID}
+		switch {
+		case entry.PrivateKeyFile != "":
+			pemData, err := os.ReadFile(resolve(entry.PrivateKeyFile))
+			if err != nil {
+				return nil, fmt.Errorf("读取JWT私钥 %s 失败: %w", entry.KID, err)
+			}
+			if key.private, key.public, err = parsePrivateKeyPEM(pemData); err != nil {
+				return nil, fmt.Errorf("解析JWT私钥 %s 失败: %w", entry.KID, err)
+			}
+		case entry.PublicKeyFile != "":
+			pemData, err := os.ReadFile(resolve(entry.PublicKeyFile))
+			if err != nil {
+				return nil, fmt.Errorf("读取JWT公钥 %s 失败: %w", entry.KID, err)
+			}
+			if key.public, err = parsePublicKeyPEM(pemData); err != nil {
+				return nil, fmt.Errorf("解析JWT公钥 %s 失败: %w", entry.KID, err)
+			}
+		default:
+			return nil, fmt.Errorf("JWT密钥 %s 未指定密钥文件", entry.KID)
+		}
+		key.method = signingMethodFor(key.public)
+		ks.keys[entry.KID] = key
+	}
+
+	ks.active = ks.keys[file.ActiveKID]
+	if ks.active == nil || ks.active.private == nil {
+		return nil, fmt.Errorf("JWT密钥集中没有可用于签名的 active_kid: %q", file.ActiveKID)
+	}
+	return ks, nil
+}
+
+// derivedKeySet 未配置密钥集时由 JWT_SECRET 派生固定的 Ed25519 密钥，多实例部署和重启后保持一致
+func derivedKeySet() (*keySet, error) {
+	secret := getJWTSecret()
+	if secret == "" {
+		return nil, errors.New("JWT_SECRET 未设置")
+	}
+	seed := sha256.Sum256([]byte("jwt-signing-key:" + secret))
+	private := ed25519.NewKeyFromSeed(seed[:])
+	public := private.Public().(ed25519.PublicKey)
+	fingerprint := sha256.Sum256(public)
+
+	key := &signingKey{
+		kid:     hex.EncodeToString(fingerprint[:8]),
+		method:  jwt.SigningMethodEdDSA,
+		private: private,
+		public:  public,
+	}
+	return &keySet{active: key, keys: map[string]*signingKey{key.kid: key}}, nil
+}
+
+func parsePrivateKeyPEM(data []byte) (interface{}, interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("不是有效的 PEM 文件")
+	}
+	var key interface{}
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		return k, k.Public(), nil
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, nil, errors.New("RSA 密钥长度不能小于 2048 位")
+		}
+		return k, &k.PublicKey, nil
+	default:
+		return nil, nil, errors.New("只支持 Ed25519 和 RSA 密钥")
+	}
+}
+
+func parsePublicKeyPEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("不是有效的 PEM 文件")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case ed25519.PublicKey, *rsa.PublicKey:
+		return key, nil
+	default:
+		return nil, errors.New("只支持 Ed25519 和 RSA 密钥")
+	}
+}
+
+func signingMethodFor(public interface{}) jwt.SigningMethod {
+	if _, ok := public.(*rsa.PublicKey); ok {
+		return jwt.SigningMethodRS256
+	}
+	return jwt.SigningMethodEdDSA
+}
+
+// JWKS 返回密钥集中全部公钥的 JWK Set，供其他服务验证令牌
+func JWKS() (map[string]interface{}, error) {
+	ks, err := getKeySet()
+	if err != nil {
+		return nil, err
+	}
+
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := make([]map[string]interface{}, 0, len(kids))
+	for _, kid := range kids {
+		key := ks.keys[kid]
+		jwk := map[string]interface{}{
+			"kid": key.kid,
+			"use": "sig",
+			"alg": key.method.Alg(),
+		}
+		switch public := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		}
+		keys = append(keys, jwk)
+	}
+	return map[string]interface{}{"keys": keys}, nil
+}
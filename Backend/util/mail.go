@@ -0,0 +1,107 @@
+package util
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrMailRecipientInvalid = errors.New("收件人地址无效")
+
+// SendMail 通过配置的邮件发送器发送纯文本邮件
+func SendMail(to, subject, body string) error {
+	if to == "" || strings.ContainsAny(to, "\r\n") || !strings.Contains(to, "@") {
+		return ErrMailRecipientInvalid
+	}
+	return MailSender().Send(&Message{Channel: ChannelEmail, To: to, Subject: subject, Body: body})
+}
+
+// SendMailAsync 在后台发送邮件，失败只记录日志，不影响主流程
+func SendMailAsync(to, subject, body string) {
+	go func() {
+		if err := SendMail(to, subject, body); err != nil {
+			logSendFailure(ChannelEmail, to, err)
+		}
+	}()
+}
+
+// SMTPSender 通过 SMTP 发送邮件
+type SMTPSender struct {
+	Config MailConfig
+}
+
+func (s *SMTPSender) Send(msg *Message) error {
+	cfg := s.Config
+	data := buildMailMessage(cfg.From, msg.To, msg.Subject, msg.Body)
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	if cfg.Port != 465 {
+		// smtp.SendMail 在服务器支持时自动使用 STARTTLS
+		return smtp.SendMail(addr, auth, cfg.From, []string{msg.To}, data)
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: cfg.Host})
+	if err != nil {
+		return fmt.Errorf("连接邮件服务器失败: %w", err)
+	}
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("连接邮件服务器失败: %w", err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("邮件服务器认证失败: %w", err)
+		}
+	}
+	if err := client.Mail(cfg.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func buildMailMessage(from, to, subject, body string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from + "\r\n")
+	buf.WriteString("To: " + to + "\r\n")
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", strings.NewReplacer("\r", "", "\n", "").Replace(subject)) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,217 @@
+package util
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 以最小货币单位（分）存储的金额，避免浮点数累计误差。
+// 接口中仍以两位小数的元表示，如 12.50；解析时按十进制文本精确换算，不经过浮点数。
+// 只支持两位小数的币种，币种代码见 DefaultCurrency。
+type Money int64
+
+// 百分比按万分比（基点）表示，10000 即 100%
+const BasisPoints = 10000
+
+var ErrInvalidMoney = errors.New("金额格式错误，最多两位小数")
+
+// supportedCurrencies 支持的币种，均以分为最小单位
+var supportedCurrencies = map[string]bool{
+	"CNY": true,
+	"HKD": true,
+	"USD": true,
+	"EUR": true,
+	"GBP": true,
+}
+
+// DefaultCurrency 返回系统结算币种，未配置或不支持时为 CNY
+func DefaultCurrency() string {
+	if AppConfig != nil && AppConfig.Pricing.Currency != "" {
+		return AppConfig.Pricing.Currency
+	}
+	return "CNY"
+}
+
+// ParseMoney 将 "12"、"12.5"、"-0.05" 等十进制文本换算为分，超过两位小数时报错。
+// 只接受开头一个可选的负号，整数和小数部分只能是数字
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if len(fracPart) > 2 || (intPart == "" && fracPart == "") || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, ErrInvalidMoney
+	}
+	for len(fracPart) < 2 {
+		fracPart += "0"
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	yuan, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidMoney
+	}
+	fen, _ := strconv.ParseInt(fracPart, 10, 64)
+	if yuan > (math.MaxInt64-fen)/100 {
+		return 0, ErrInvalidMoney
+	}
+	m := Money(yuan*100 + fen)
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Mul 单价乘以数量
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// MulRate 按万分比计算金额，结果四舍五入到分（半数远离零）
+func (m Money) MulRate(basisPoints int64) Money {
+	return Money(divRound(int64(m)*basisPoints, BasisPoints))
+}
+
+// Div 平均分摊到 n 份，结果四舍五入到分
+func (m Money) Div(n int) Money {
+	if n <= 0 {
+		return 0
+	}
+	return Money(divRound(int64(m), int64(n)))
+}
+
+// Min 返回较小的金额
+func (m Money) Min(other Money) Money {
+	if other < m {
+		return other
+	}
+	return m
+}
+
+// String 格式化为两位小数的元，如 12.50
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// MarshalJSON 以两位小数的数字输出，兼容原先的金额字段
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 接受数字或字符串形式的金额
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" || s == "" {
+		*m = 0
+		return nil
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value 数据库中以 BIGINT 存储分
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// Scan 读取 BIGINT 或 SUM 等聚合结果
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("无法将 %T 转换为金额", value)
+	}
+	return nil
+}
+
+// scanString 处理 MySQL 对整数列求和返回的 DECIMAL 文本，如 "12345" 或 "12345.0000"
+func (m *Money) scanString(s string) error {
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		if strings.Trim(s[i+1:], "0") != "" {
+			return fmt.Errorf("金额 %s 不是整数分", s)
+		}
+		s = s[:i]
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*m = Money(v)
+	return nil
+}
+
+// divRound 整数除法，四舍五入（半数远离零）
+func divRound(a, b int64) int64 {
+	if b < 0 {
+		a, b = -a, -b
+	}
+	if a >= 0 {
+		return (a + b/2) / b
+	}
+	return -((-a + b/2) / b)
+}
+
+// Rate 以万分比存储的费率，接口中以两位小数的百分比表示，如 6.00 即 6%
+type Rate int64
+
+// Of 按费率计算金额，结果四舍五入到分
+func (r Rate) Of(m Money) Money {
+	return m.MulRate(int64(r))
+}
+
+// IncludedIn 从含税金额中拆出的税额，即 m × r / (1 + r)，结果四舍五入到分
+func (r Rate) IncludedIn(m Money) Money {
+	return Money(divRound(int64(m)*int64(r), BasisPoints+int64(r)))
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return Money(r).MarshalJSON()
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	return (*Money)(r).UnmarshalJSON(data)
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return int64(r), nil
+}
+
+func (r *Rate) Scan(value interface{}) error {
+	return (*Money)(r).Scan(value)
+}
@@ -0,0 +1,42 @@
+package util
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+		ok   bool
+	}{
+		{"12", 1200, true},
+		{"12.5", 1250, true},
+		{"12.50", 1250, true},
+		{" 0.05 ", 5, true},
+		{".5", 50, true},
+		{"3.", 300, true},
+		{"-0.05", -5, true},
+		{"92233720368547758.07", 9223372036854775807, true},
+		{"", 0, false},
+		{"-", 0, false},
+		{".", 0, false},
+		{"1.234", 0, false},
+		{"1.-5", 0, false},
+		{"1.+5", 0, false},
+		{"--5", 0, false},
+		{"+5", 0, false},
+		{"1-5", 0, false},
+		{"1,000", 0, false},
+		{"1e3", 0, false},
+		{"92233720368547758.08", 0, false},
+		{"100000000000000000", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("ParseMoney(%q) = %v, %v，期望 %v", tt.in, got, err, tt.want)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("ParseMoney(%q) = %v，期望报错", tt.in, got)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 )
@@ -39,3 +40,11 @@ func CloseDB() {
 		}
 	}
 }
+
+// IsDuplicateKeyError 判断是否为唯一索引冲突
+func IsDuplicateKeyError(err error) bool {
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+		return mysqlErr.Number == 1062
+	}
+	return false
+}
@@ -0,0 +1,445 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// OpenID Connect 客户端：授权码模式 + PKCE (S256)。服务商的端点通过
+// {issuer}/.well-known/openid-configuration 自动发现，ID Token 使用服务商公布的 JWKS 验证签名。
+var (
+	ErrOIDCProviderNotFound = errors.New("不支持的登录方式")
+	ErrOIDCStateInvalid     = errors.New("登录请求无效或已过期，请重新发起")
+	ErrOIDCTokenInvalid     = errors.New("第三方身份令牌无效")
+)
+
+const (
+	OIDCStateExpiration    = 10 * time.Minute
+	oidcDiscoveryCacheTTL  = time.Hour
+	oidcJWKSRefreshBackoff = time.Minute // kid 未知时重新拉取 JWKS 的最小间隔，防止被伪造的 kid 刷爆
+)
+
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// OIDCState 发起授权时保存的上下文，回调时按 state 取回并立即删除
+type OIDCState struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+	UserID       int    `json:"user_id"` // 大于 0 时为已登录用户绑定第三方账号，否则为登录
+}
+
+// OIDCIdentity ID Token 中与账号关联相关的声明
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcProviderCache struct {
+	discovery   *oidcDiscovery
+	fetchedAt   time.Time
+	keys        map[string]interface{}
+	keysFetched time.Time
+}
+
+var (
+	oidcCacheMu sync.Mutex
+	oidcCache   = map[string]*oidcProviderCache{}
+)
+
+// GetOIDCProvider 按名称查找配置的服务商
+func GetOIDCProvider(name string) (*OIDCProviderConfig, error) {
+	for i := range GetConfig().OIDC.Providers {
+		if p := &GetConfig().OIDC.Providers[i]; p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, ErrOIDCProviderNotFound
+}
+
+// NewOIDCState 生成 state、nonce 和 PKCE 校验码并保存到 Redis，返回 state 和 code_challenge
+func NewOIDCState(provider string, userID int) (string, *OIDCState, string, error) {
+	state, err := randomURLToken(24)
+	if err != nil {
+		return "", nil, "", err
+	}
+	verifier, err := randomURLToken(32)
+	if err != nil {
+		return "", nil, "", err
+	}
+	nonce, err := randomURLToken(16)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	data := &OIDCState{Provider: provider, CodeVerifier: verifier, Nonce: nonce, UserID: userID}
+	payload, _ := json.Marshal(data)
+	if err := RedisClient.Set(ctx, "auth:oidc_state:"+state, payload, OIDCStateExpiration).Err(); err != nil {
+		return "", nil, "", err
+	}
+
+	return state, data, oidcCodeChallenge(verifier), nil
+}
+
+// oidcCodeChallenge 按 S256 方法由 code_verifier 计算 code_challenge
+func oidcCodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ConsumeOIDCState 取出并删除 state，同一 state 只能使用一次
+func ConsumeOIDCState(state string) (*OIDCState, error) {
+	script := `
+		local value = redis.call("GET", KEYS[1])
+		if value then
+			redis.call("DEL", KEYS[1])
+			return value
+		end
+		return ""`
+	value, err := RedisClient.Eval(ctx, script, []string{"auth:oidc_state:" + state}).Text()
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, ErrOIDCStateInvalid
+	}
+	var data OIDCState
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return nil, ErrOIDCStateInvalid
+	}
+	return &data, nil
+}
+
+// OIDCAuthorizationURL 生成跳转到服务商的授权地址
+func OIDCAuthorizationURL(p *OIDCProviderConfig, state, nonce, codeChallenge string) (string, error) {
+	discovery, err := oidcDiscover(p)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.ClientID)
+	params.Set("redirect_uri", p.RedirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return discovery.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// OIDCExchangeCode 用授权码换取 ID Token 并验证签名、签发方、受众、有效期和 nonce
+func OIDCExchangeCode(p *OIDCProviderConfig, code string, state *OIDCState) (*OIDCIdentity, error) {
+	discovery, err := oidcDiscover(p)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("code_verifier", state.CodeVerifier)
+	form.Set("client_id", p.ClientID)
+
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.ClientSecret != "" {
+		// client_secret_basic：规范要求先对 ID 和密钥做 URL 编码
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求令牌端点失败: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("令牌端点返回 %d: %s", resp.StatusCode, truncateForLog(body))
+	}
+
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil || tokenResp.IDToken == "" {
+		return nil, ErrOIDCTokenInvalid
+	}
+	return verifyOIDCIDToken(p, discovery, tokenResp.IDToken, state.Nonce)
+}
+
+type oidcIDTokenClaims struct {
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"` // 部分服务商返回字符串 "true"
+	Name          string      `json:"name"`
+	AuthorizedBy  string      `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+func verifyOIDCIDToken(p *OIDCProviderConfig, discovery *oidcDiscovery, idToken, nonce string) (*OIDCIdentity, error) {
+	claims := &oidcIDTokenClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "ES512", "EdDSA"}))
+	_, err := parser.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return oidcSigningKey(p, discovery, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCTokenInvalid, err)
+	}
+
+	if claims.Issuer != discovery.Issuer || !claims.VerifyAudience(p.ClientID, true) || claims.Subject == "" {
+		return nil, ErrOIDCTokenInvalid
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedBy != p.ClientID {
+		return nil, ErrOIDCTokenInvalid
+	}
+	if claims.ExpiresAt == nil || claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, ErrOIDCTokenInvalid
+	}
+
+	identity := &OIDCIdentity{
+		Subject: claims.Subject,
+		Email:   strings.ToLower(strings.TrimSpace(claims.Email)),
+		Name:    claims.Name,
+	}
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		identity.EmailVerified = v
+	case string:
+		identity.EmailVerified = v == "true"
+	}
+	return identity, nil
+}
+
+// oidcDiscover 读取服务商的发现文档，缓存一小时；文档中的 issuer 必须与配置一致
+func oidcDiscover(p *OIDCProviderConfig) (*oidcDiscovery, error) {
+	oidcCacheMu.Lock()
+	cache := oidcCache[p.Name]
+	if cache != nil && cache.discovery != nil && time.Since(cache.fetchedAt) < oidcDiscoveryCacheTTL {
+		oidcCacheMu.Unlock()
+		return cache.discovery, nil
+	}
+	oidcCacheMu.Unlock()
+
+	issuer := strings.TrimRight(p.Issuer, "/")
+	var discovery oidcDiscovery
+	if err := oidcGetJSON(issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("发现文档中的 issuer 与配置不一致: %s", discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("发现文档缺少必要的端点")
+	}
+
+	oidcCacheMu.Lock()
+	defer oidcCacheMu.Unlock()
+	if oidcCache[p.Name] == nil {
+		oidcCache[p.Name] = &oidcProviderCache{}
+	}
+	oidcCache[p.Name].discovery = &discovery
+	oidcCache[p.Name].fetchedAt = time.Now()
+	return &discovery, nil
+}
+
+// oidcSigningKey 按 kid 查找服务商的签名公钥，找不到时（服务商轮换了密钥）重新拉取 JWKS
+func oidcSigningKey(p *OIDCProviderConfig, discovery *oidcDiscovery, kid string) (interface{}, error) {
+	oidcCacheMu.Lock()
+	cache := oidcCache[p.Name]
+	if cache == nil {
+		cache = &oidcProviderCache{}
+		oidcCache[p.Name] = cache
+	}
+	key, ok := lookupOIDCKey(cache.keys, kid)
+	refresh := !ok && time.Since(cache.keysFetched) >= oidcJWKSRefreshBackoff
+	oidcCacheMu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !refresh {
+		return nil, errors.New("unknown signing key")
+	}
+
+	var jwks struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := oidcGetJSON(discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if use, _ := jwk["use"].(string); use != "" && use != "sig" {
+			continue
+		}
+		parsed, err := parseJWK(jwk)
+		if err != nil {
+			continue
+		}
+		id, _ := jwk["kid"].(string)
+		keys[id] = parsed
+	}
+
+	oidcCacheMu.Lock()
+	cache.keys = keys
+	cache.keysFetched = time.Now()
+	oidcCacheMu.Unlock()
+
+	if key, ok := lookupOIDCKey(keys, kid); ok {
+		return key, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// lookupOIDCKey 令牌未带 kid 时，只有 JWKS 中恰好一个密钥才能使用
+func lookupOIDCKey(keys map[string]interface{}, kid string) (interface{}, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+// parseJWK 解析 RSA、EC (P-256/384/521) 和 Ed25519 公钥
+func parseJWK(jwk map[string]interface{}) (interface{}, error) {
+	field := func(name string) ([]byte, error) {
+		s, _ := jwk[name].(string)
+		if s == "" {
+			return nil, fmt.Errorf("JWK 缺少 %s", name)
+		}
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+
+	switch jwk["kty"] {
+	case "RSA":
+		n, err := field("n")
+		if err != nil {
+			return nil, err
+		}
+		e, err := field("e")
+		if err != nil {
+			return nil, err
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("RSA 密钥长度不足 2048 位")
+		}
+		return key, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk["crv"] {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("不支持的椭圆曲线")
+		}
+		x, err := field("x")
+		if err != nil {
+			return nil, err
+		}
+		y, err := field("y")
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("无效的 EC 公钥")
+		}
+		return key, nil
+	case "OKP":
+		if jwk["crv"] != "Ed25519" {
+			return nil, errors.New("不支持的曲线")
+		}
+		x, err := field("x")
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("无效的 Ed25519 公钥")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, errors.New("不支持的密钥类型")
+}
+
+func oidcGetJSON(target string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 %s 失败: %w", target, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求 %s 返回 %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func randomURLToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func truncateForLog(body []byte) string {
+	if len(body) > 200 {
+		body = body[:200]
+	}
+	return string(body)
+}
@@ -0,0 +1,182 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mockOIDCIssuer 本地模拟的 OIDC 服务商，提供发现文档、JWKS 和令牌端点。
+// 授权端点不经过浏览器，测试直接调用 authorize 取得授权码。
+type mockOIDCIssuer struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	kid      string
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]mockOIDCGrant
+
+	// claims 签发 ID Token 前调用，可用于篡改声明测试校验逻辑
+	claims func(claims jwt.MapClaims)
+	// forgedKey 不为空时用它代替 JWKS 中公布的密钥签名，模拟伪造的 ID Token
+	forgedKey *rsa.PrivateKey
+}
+
+type mockOIDCGrant struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+	subject     string
+}
+
+func newMockOIDCIssuer(t *testing.T, clientID string) *mockOIDCIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockOIDCIssuer{key: key, kid: "mock-key", clientID: clientID, codes: map[string]mockOIDCGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.handleDiscovery)
+	mux.HandleFunc("/jwks", m.handleJWKS)
+	mux.HandleFunc("/token", m.handleToken)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockOIDCIssuer) issuer() string {
+	return m.server.URL
+}
+
+// provider 指向模拟服务商的配置，每个测试使用不同的名称以免共用发现文档和 JWKS 缓存
+func (m *mockOIDCIssuer) provider(t *testing.T) *OIDCProviderConfig {
+	return &OIDCProviderConfig{
+		Name:        t.Name(),
+		Issuer:      m.issuer(),
+		ClientID:    m.clientID,
+		RedirectURL: "http://localhost/callback",
+	}
+}
+
+// authorize 模拟用户打开授权地址并同意授权，记录其中的 code_challenge、nonce 和回调地址，返回授权码
+func (m *mockOIDCIssuer) authorize(t *testing.T, authURL, subject string) string {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if parsed.Path != "/authorize" || query.Get("response_type") != "code" || query.Get("client_id") != m.clientID {
+		t.Fatalf("授权地址无效: %s", authURL)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("授权地址缺少 PKCE 参数: %s", authURL)
+	}
+
+	code, err := randomURLToken(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Lock()
+	m.codes[code] = mockOIDCGrant{
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		redirectURI: query.Get("redirect_uri"),
+		subject:     subject,
+	}
+	m.mu.Unlock()
+	return code
+}
+
+func (m *mockOIDCIssuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeMockJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 m.issuer(),
+		"authorization_endpoint": m.issuer() + "/authorize",
+		"token_endpoint":         m.issuer() + "/token",
+		"jwks_uri":               m.issuer() + "/jwks",
+	})
+}
+
+func (m *mockOIDCIssuer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := m.key.PublicKey
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": m.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// handleToken 授权码只能使用一次，code_verifier 须与授权时的 code_challenge 匹配
+func (m *mockOIDCIssuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	code := r.PostForm.Get("code")
+	m.mu.Lock()
+	grant, ok := m.codes[code]
+	delete(m.codes, code)
+	m.mu.Unlock()
+
+	if !ok || oidcCodeChallenge(r.PostForm.Get("code_verifier")) != grant.challenge ||
+		r.PostForm.Get("redirect_uri") != grant.redirectURI || r.PostForm.Get("client_id") != m.clientID {
+		writeMockJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            m.issuer(),
+		"sub":            grant.subject,
+		"aud":            m.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          grant.nonce,
+		"email":          "Mock.User@Example.com",
+		"email_verified": true,
+		"name":           "Mock User",
+	}
+	if m.claims != nil {
+		m.claims(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	key := m.key
+	if m.forgedKey != nil {
+		key = m.forgedKey
+	}
+	idToken, err := token.SignedString(key)
+	if err != nil {
+		writeMockJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeMockJSON(w, http.StatusOK, map[string]string{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,142 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// startOIDCLogin 按 NewOIDCState 的方式生成授权上下文（不经过 Redis），在模拟服务商处完成授权并返回授权码
+func startOIDCLogin(t *testing.T, m *mockOIDCIssuer, p *OIDCProviderConfig) (*OIDCState, string) {
+	t.Helper()
+	verifier, err := randomURLToken(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err := randomURLToken(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &OIDCState{Provider: p.Name, CodeVerifier: verifier, Nonce: nonce}
+
+	authURL, err := OIDCAuthorizationURL(p, "test-state", nonce, oidcCodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("生成授权地址失败: %v", err)
+	}
+	return state, m.authorize(t, authURL, "mock-subject")
+}
+
+func TestOIDCExchangeCode(t *testing.T) {
+	m := newMockOIDCIssuer(t, "ticket-client")
+	p := m.provider(t)
+	state, code := startOIDCLogin(t, m, p)
+
+	identity, err := OIDCExchangeCode(p, code, state)
+	if err != nil {
+		t.Fatalf("换取身份失败: %v", err)
+	}
+	if identity.Subject != "mock-subject" || identity.Email != "mock.user@example.com" || !identity.EmailVerified || identity.Name != "Mock User" {
+		t.Fatalf("身份信息不符: %+v", identity)
+	}
+
+	if _, err := OIDCExchangeCode(p, code, state); err == nil {
+		t.Fatal("授权码重复使用应失败")
+	}
+}
+
+func TestOIDCExchangeCodePKCE(t *testing.T) {
+	m := newMockOIDCIssuer(t, "ticket-client")
+	p := m.provider(t)
+	state, code := startOIDCLogin(t, m, p)
+
+	wrong := *state
+	wrong.CodeVerifier = strings.Repeat("a", 43)
+	if _, err := OIDCExchangeCode(p, code, &wrong); err == nil {
+		t.Fatal("code_verifier 与 code_challenge 不匹配时应失败")
+	}
+}
+
+func TestOIDCExchangeCodeNonce(t *testing.T) {
+	m := newMockOIDCIssuer(t, "ticket-client")
+	p := m.provider(t)
+
+	state, code := startOIDCLogin(t, m, p)
+	other := *state
+	other.Nonce = "other-nonce"
+	if _, err := OIDCExchangeCode(p, code, &other); !errors.Is(err, ErrOIDCTokenInvalid) {
+		t.Fatalf("nonce 不一致时应返回 ErrOIDCTokenInvalid，实际为 %v", err)
+	}
+
+	m.claims = func(claims jwt.MapClaims) { delete(claims, "nonce") }
+	state, code = startOIDCLogin(t, m, p)
+	if _, err := OIDCExchangeCode(p, code, state); !errors.Is(err, ErrOIDCTokenInvalid) {
+		t.Fatalf("缺少 nonce 时应返回 ErrOIDCTokenInvalid，实际为 %v", err)
+	}
+}
+
+func TestOIDCExchangeCodeClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims func(claims jwt.MapClaims)
+		valid  bool
+	}{
+		{"签发方不一致", func(c jwt.MapClaims) { c["iss"] = "https://attacker.example.com" }, false},
+		{"受众为其他客户端", func(c jwt.MapClaims) { c["aud"] = "other-client" }, false},
+		{"多个受众且 azp 不是本客户端", func(c jwt.MapClaims) { c["aud"] = []string{"ticket-client", "other-client"} }, false},
+		{"多个受众且 azp 为本客户端", func(c jwt.MapClaims) {
+			c["aud"] = []string{"ticket-client", "other-client"}
+			c["azp"] = "ticket-client"
+		}, true},
+		{"已过期", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, false},
+		{"缺少过期时间", func(c jwt.MapClaims) { delete(c, "exp") }, false},
+		{"缺少 sub", func(c jwt.MapClaims) { delete(c, "sub") }, false},
+	}
+
+	m := newMockOIDCIssuer(t, "ticket-client")
+	p := m.provider(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.claims = tt.claims
+			state, code := startOIDCLogin(t, m, p)
+			_, err := OIDCExchangeCode(p, code, state)
+			if tt.valid && err != nil {
+				t.Fatalf("应校验通过，实际为 %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrOIDCTokenInvalid) {
+				t.Fatalf("应返回 ErrOIDCTokenInvalid，实际为 %v", err)
+			}
+		})
+	}
+}
+
+func TestOIDCExchangeCodeForgedSignature(t *testing.T) {
+	m := newMockOIDCIssuer(t, "ticket-client")
+	p := m.provider(t)
+	state, code := startOIDCLogin(t, m, p)
+
+	// 沿用服务商的 kid，但用 JWKS 中没有的密钥签名
+	forged, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.forgedKey = forged
+	if _, err := OIDCExchangeCode(p, code, state); !errors.Is(err, ErrOIDCTokenInvalid) {
+		t.Fatalf("伪造签名的令牌应返回 ErrOIDCTokenInvalid，实际为 %v", err)
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	m := newMockOIDCIssuer(t, "ticket-client")
+	p := m.provider(t)
+	// 同一服务以另一个地址访问时，发现文档中的 issuer 与配置不一致
+	p.Issuer = strings.Replace(m.issuer(), "127.0.0.1", "localhost", 1)
+
+	if _, err := OIDCAuthorizationURL(p, "state", "nonce", "challenge"); err == nil {
+		t.Fatal("发现文档的 issuer 与配置不一致时应失败")
+	}
+}
@@ -0,0 +1,124 @@
+package util
+
+import (
+	_ "embed"
+	"image"
+
+	"github.com/signintech/gopdf"
+)
+
+// A4 纸张尺寸，单位为 PDF 点（1/72 英寸）
+const (
+	PDFPageWidth  = 595.28
+	PDFPageHeight = 841.89
+)
+
+// pdfFont 文泉驿微米黑（Apache-2.0，许可证见 fonts/LICENSE），输出时只嵌入文档中用到的字形子集，阅读器无需安装中文字体
+//
+//go:embed fonts/wqy-microhei.ttf
+var pdfFont []byte
+
+const pdfFontFamily = "wqy-microhei"
+
+// PDFDocument 基于 gopdf 的 PDF 生成器，支持文本、线条、二维码和图片，用于发票、电子票等固定版式的单据。
+// 坐标以页面左上角为原点，单位为点。绘制过程中的错误在 Bytes 中返回。
+type PDFDocument struct {
+	pdf *gopdf.GoPdf
+	err error
+}
+
+func NewPDFDocument() *PDFDocument {
+	d := &PDFDocument{pdf: &gopdf.GoPdf{}}
+	d.pdf.Start(gopdf.Config{PageSize: gopdf.Rect{W: PDFPageWidth, H: PDFPageHeight}, Unit: gopdf.UnitPT})
+	d.setErr(d.pdf.AddTTFFontDataWithOption(pdfFontFamily, pdfFont, gopdf.TtfOption{
+		OnGlyphNotFoundSubstitute: func(r rune) rune { return '?' },
+	}))
+	d.AddPage()
+	return d
+}
+
+func (d *PDFDocument) setErr(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// AddPage 新增一页，后续绘制内容写入该页
+func (d *PDFDocument) AddPage() {
+	d.pdf.AddPage()
+}
+
+// Text 在 (x, y) 处绘制文本，y 为文本基线位置，字体中没有的字符显示为问号
+func (d *PDFDocument) Text(x, y, size float64, text string) {
+	if d.err != nil {
+		return
+	}
+	d.setErr(d.pdf.SetFont(pdfFontFamily, "", size))
+	d.pdf.SetXY(x, y)
+	d.setErr(d.pdf.Text(text))
+}
+
+// TextRight 绘制右对齐文本，x 为文本右边缘
+func (d *PDFDocument) TextRight(x, y, size float64, text string) {
+	d.Text(x-d.TextWidth(text, size), y, size, text)
+}
+
+// TextWidth 按字体度量计算文本宽度
+func (d *PDFDocument) TextWidth(text string, size float64) float64 {
+	if d.err != nil {
+		return 0
+	}
+	d.setErr(d.pdf.SetFont(pdfFontFamily, "", size))
+	width, err := d.pdf.MeasureTextWidth(text)
+	d.setErr(err)
+	return width
+}
+
+// Line 绘制直线
+func (d *PDFDocument) Line(x1, y1, x2, y2, width float64) {
+	d.pdf.SetLineWidth(width)
+	d.pdf.Line(x1, y1, x2, y2)
+}
+
+// QRCode 在 (x, y) 处绘制边长为 size 的二维码，四周保留 4 个模块宽的空白区
+func (d *PDFDocument) QRCode(x, y, size float64, qr *QRCode) {
+	module := size / float64(qr.Size+8)
+	d.pdf.SetFillColor(0, 0, 0)
+	for row, modules := range qr.Modules {
+		for col, dark := range modules {
+			if dark {
+				d.pdf.RectFromUpperLeftWithStyle(x+float64(col+4)*module, y+float64(row+4)*module, module, module, "F")
+			}
+		}
+	}
+}
+
+// Image 将图片缩放绘制到 (x, y) 处宽 w、高 h 的区域，超过 800 像素的图片按比例抽样缩小
+func (d *PDFDocument) Image(x, y, w, h float64, img image.Image) {
+	if d.err != nil {
+		return
+	}
+	bounds := img.Bounds()
+	step := 1
+	for bounds.Dx()/step > 800 || bounds.Dy()/step > 800 {
+		step++
+	}
+	if step > 1 {
+		scaled := image.NewRGBA(image.Rect(0, 0, bounds.Dx()/step, bounds.Dy()/step))
+		for py := 0; py < scaled.Rect.Dy(); py++ {
+			for px := 0; px < scaled.Rect.Dx(); px++ {
+				scaled.Set(px, py, img.At(bounds.Min.X+px*step, bounds.Min.Y+py*step))
+			}
+		}
+		img = scaled
+	}
+	d.setErr(d.pdf.ImageFrom(img, x, y, &gopdf.Rect{W: w, H: h}))
+}
+
+// Bytes 输出完整的 PDF 文件
+func (d *PDFDocument) Bytes() ([]byte, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	return d.pdf.GetBytesPdfReturnErr()
+}
@@ -0,0 +1,503 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+var ErrQRCodeTooLong = errors.New("二维码内容过长")
+
+// QRCode 二维码矩阵，Modules[行][列] 为 true 表示深色模块
+type QRCode struct {
+	Size    int
+	Modules [][]bool
+}
+
+// qrVersion 纠错等级 M 下各版本的分块参数
+type qrVersion struct {
+	ecPerBlock int
+	blocks1    int
+	data1      int
+	blocks2    int
+	data2      int
+	alignment  []int
+}
+
+// 版本 1-10，纠错等级 M，可容纳最多 213 字节
+var qrVersions = []qrVersion{
+	{10, 1, 16, 0, 0, nil},
+	{16, 1, 28, 0, 0, []int{6, 18}},
+	{26, 1, 44, 0, 0, []int{6, 22}},
+	{18, 2, 32, 0, 0, []int{6, 26}},
+	{24, 2, 43, 0, 0, []int{6, 30}},
+	{16, 4, 27, 0, 0, []int{6, 34}},
+	{18, 4, 31, 0, 0, []int{6, 22, 38}},
+	{22, 2, 38, 2, 39, []int{6, 24, 42}},
+	{22, 3, 36, 2, 37, []int{6, 26, 46}},
+	{26, 4, 43, 1, 44, []int{6, 28, 50}},
+}
+
+func (v qrVersion) dataCodewords() int {
+	return v.blocks1*v.data1 + v.blocks2*v.data2
+}
+
+// EncodeQRCode 以字节模式、纠错等级 M 编码二维码，自动选择最小版本和罚分最低的掩码
+func EncodeQRCode(content string) (*QRCode, error) {
+	data := []byte(content)
+
+	version := 0
+	for i, v := range qrVersions {
+		countBits := 8
+		if i+1 >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+len(data)*8 <= v.dataCodewords()*8 {
+			version = i + 1
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrQRCodeTooLong
+	}
+	spec := qrVersions[version-1]
+
+	codewords := qrInterleave(spec, qrEncodeData(data, version, spec.dataCodewords()))
+
+	q := newQRBuilder(version)
+	q.drawFunctionPatterns(spec)
+	q.drawCodewords(codewords)
+
+	best, bestPenalty := -1, 0
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if penalty := q.penalty(); best < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormatBits(best)
+
+	return &QRCode{Size: q.size, Modules: q.modules}, nil
+}
+
+// PNG 将二维码渲染为黑白 PNG 图片，scale 为每个模块的像素数，四周保留 4 个模块的空白
+func (qr *QRCode) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		scale = 1
+	}
+	width := (qr.Size + 8) * scale
+	img := image.NewGray(image.Rect(0, 0, width, width))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for row, modules := range qr.Modules {
+		for col, dark := range modules {
+			if !dark {
+				continue
+			}
+			for y := 0; y < scale; y++ {
+				for x := 0; x < scale; x++ {
+					img.SetGray((col+4)*scale+x, (row+4)*scale+y, color.Gray{})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// qrEncodeData 生成数据码字：模式指示、字符计数、数据、终止符及填充
+func qrEncodeData(data []byte, version, capacity int) []byte {
+	var bits []bool
+	appendBits := func(value, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, (value>>uint(i))&1 == 1)
+		}
+	}
+
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	appendBits(0x4, 4)
+	appendBits(len(data), countBits)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+
+	for i := 0; i < 4 && len(bits) < capacity*8; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+
+	result := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << uint(7-j)
+			}
+		}
+		result = append(result, b)
+	}
+	for pad := byte(0xEC); len(result) < capacity; pad ^= 0xEC ^ 0x11 {
+		result = append(result, pad)
+	}
+	return result
+}
+
+// qrInterleave 分块计算纠错码并交错排列
+func qrInterleave(spec qrVersion, data []byte) []byte {
+	var blocks, ecBlocks [][]byte
+	generator := qrGenerator(spec.ecPerBlock)
+	offset := 0
+	for i := 0; i < spec.blocks1+spec.blocks2; i++ {
+		length := spec.data1
+		if i >= spec.blocks1 {
+			length = spec.data2
+		}
+		block := data[offset : offset+length]
+		offset += length
+		blocks = append(blocks, block)
+		ecBlocks = append(ecBlocks, qrRemainder(block, generator))
+	}
+
+	var result []byte
+	maxLength := spec.data1
+	if spec.data2 > maxLength {
+		maxLength = spec.data2
+	}
+	for i := 0; i < maxLength; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < spec.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// GF(256) 乘法，本原多项式 x^8 + x^4 + x^3 + x^2 + 1
+func qrMultiply(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		hi := z & 0x80
+		z <<= 1
+		if hi != 0 {
+			z ^= 0x1D
+		}
+		if (y>>uint(i))&1 == 1 {
+			z ^= x
+		}
+	}
+	return z
+}
+
+// qrGenerator 计算 Reed-Solomon 生成多项式的系数（最高次项系数 1 省略）
+func qrGenerator(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	var root byte = 1
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			result[j] = qrMultiply(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = qrMultiply(root, 0x02)
+	}
+	return result
+}
+
+func qrRemainder(data, generator []byte) []byte {
+	result := make([]byte, len(generator))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= qrMultiply(generator[i], factor)
+		}
+	}
+	return result
+}
+
+type qrBuilder struct {
+	version    int
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+func newQRBuilder(version int) *qrBuilder {
+	size := version*4 + 17
+	q := &qrBuilder{version: version, size: size}
+	q.modules = make([][]bool, size)
+	q.isFunction = make([][]bool, size)
+	for i := range q.modules {
+		q.modules[i] = make([]bool, size)
+		q.isFunction[i] = make([]bool, size)
+	}
+	return q
+}
+
+func (q *qrBuilder) setFunction(row, col int, dark bool) {
+	q.modules[row][col] = dark
+	q.isFunction[row][col] = true
+}
+
+// drawFunctionPatterns 绘制定位图形、时序图形、校正图形和版本信息，并预留格式信息区域
+func (q *qrBuilder) drawFunctionPatterns(spec qrVersion) {
+	for i := 0; i < q.size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+
+	q.drawFinder(3, 3)
+	q.drawFinder(3, q.size-4)
+	q.drawFinder(q.size-4, 3)
+
+	n := len(spec.alignment)
+	for i, row := range spec.alignment {
+		for j, col := range spec.alignment {
+			// 与定位图形重叠的位置不绘制
+			if (i == 0 && j == 0) || (i == 0 && j == n-1) || (i == n-1 && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.setFunction(row+dy, col+dx, qrMaxAbs(dx, dy) != 1)
+				}
+			}
+		}
+	}
+
+	q.drawFormatBits(0)
+	q.drawVersion()
+}
+
+func (q *qrBuilder) drawFinder(centerRow, centerCol int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			row, col := centerRow+dy, centerCol+dx
+			if row < 0 || row >= q.size || col < 0 || col >= q.size {
+				continue
+			}
+			dist := qrMaxAbs(dx, dy)
+			q.setFunction(row, col, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (q *qrBuilder) drawFormatBits(mask int) {
+	// 纠错等级 M 的格式码为 00
+	data := mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.setFunction(i, 8, bit(i))
+	}
+	q.setFunction(7, 8, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(8, 7, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(8, 14-i, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.setFunction(8, q.size-1-i, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(q.size-15+i, 8, bit(i))
+	}
+	q.setFunction(q.size-8, 8, true)
+}
+
+func (q *qrBuilder) drawVersion() {
+	if q.version < 7 {
+		return
+	}
+	rem := q.version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := q.version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>uint(i))&1 == 1
+		a, b := q.size-11+i%3, i/3
+		q.setFunction(b, a, dark)
+		q.setFunction(a, b, dark)
+	}
+}
+
+// drawCodewords 按两列一组、自右向左的蛇形顺序填充码字
+func (q *qrBuilder) drawCodewords(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				col := right - j
+				row := vert
+				if (right+1)&2 == 0 {
+					row = q.size - 1 - vert
+				}
+				if q.isFunction[row][col] || i >= len(codewords)*8 {
+					continue
+				}
+				q.modules[row][col] = (codewords[i>>3]>>uint(7-i&7))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask 对数据区域应用掩码，再次调用可撤销
+func (q *qrBuilder) applyMask(mask int) {
+	for row := 0; row < q.size; row++ {
+		for col := 0; col < q.size; col++ {
+			if q.isFunction[row][col] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (row+col)%2 == 0
+			case 1:
+				invert = row%2 == 0
+			case 2:
+				invert = col%3 == 0
+			case 3:
+				invert = (row+col)%3 == 0
+			case 4:
+				invert = (row/2+col/3)%2 == 0
+			case 5:
+				invert = row*col%2+row*col%3 == 0
+			case 6:
+				invert = (row*col%2+row*col%3)%2 == 0
+			case 7:
+				invert = ((row+col)%2+row*col%3)%2 == 0
+			}
+			if invert {
+				q.modules[row][col] = !q.modules[row][col]
+			}
+		}
+	}
+}
+
+// penalty 按标准的四条规则计算掩码罚分
+func (q *qrBuilder) penalty() int {
+	score := 0
+	dark := 0
+	at := func(row, col int, vertical bool) bool {
+		if vertical {
+			return q.modules[col][row]
+		}
+		return q.modules[row][col]
+	}
+	finderLike := []bool{true, false, true, true, true, false, true}
+
+	for _, vertical := range []bool{false, true} {
+		for row := 0; row < q.size; row++ {
+			run := 1
+			for col := 1; col <= q.size; col++ {
+				if col < q.size && at(row, col, vertical) == at(row, col-1, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+
+			for col := 0; col+7 <= q.size; col++ {
+				match := true
+				for k, v := range finderLike {
+					if at(row, col+k, vertical) != v {
+						match = false
+						break
+					}
+				}
+				if !match {
+					continue
+				}
+				if q.lightRun(row, col-4, col, vertical) || q.lightRun(row, col+7, col+11, vertical) {
+					score += 40
+				}
+			}
+		}
+	}
+
+	for row := 0; row < q.size; row++ {
+		for col := 0; col < q.size; col++ {
+			if q.modules[row][col] {
+				dark++
+			}
+			if row+1 < q.size && col+1 < q.size {
+				c := q.modules[row][col]
+				if c == q.modules[row+1][col] && c == q.modules[row][col+1] && c == q.modules[row+1][col+1] {
+					score += 3
+				}
+			}
+		}
+	}
+
+	total := q.size * q.size
+	deviation := dark*100/total - 50
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	score += deviation / 5 * 10
+	return score
+}
+
+// lightRun 检查 [from, to) 范围是否全为浅色，超出边界的部分视为浅色
+func (q *qrBuilder) lightRun(row, from, to int, vertical bool) bool {
+	for col := from; col < to; col++ {
+		if col < 0 || col >= q.size {
+			continue
+		}
+		if (vertical && q.modules[col][row]) || (!vertical && q.modules[row][col]) {
+			return false
+		}
+	}
+	return true
+}
+
+func qrMaxAbs(a, b int) int {
+	if a < 0 {
+		a = -a
+	}
+	if b < 0 {
+		b = -b
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
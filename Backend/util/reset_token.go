@@ -0,0 +1,86 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var ErrResetTokenInvalid = errors.New("重置链接无效或已过期")
+
+const (
+	PasswordResetExpiration = 30 * time.Minute
+	passwordResetCooldown   = 60 * time.Second
+)
+
+func passwordResetKey(userID int) string {
+	return fmt.Sprintf("auth:password_reset:%d", userID)
+}
+
+// 重置令牌中包含用户ID和随机数。签名用途绑定用户当前的密码哈希，密码一旦修改旧令牌即失效；
+// 随机数保存在 Redis，每个用户同一时间只有最新的令牌有效，使用后立即删除。
+func passwordResetTokenPurpose(passwordHash string) string {
+	return "password-reset:" + passwordHash
+}
+
+// AllowPasswordResetRequest 同一手机号/邮箱在冷却时间内只能申请一次重置，无论账号是否存在
+func AllowPasswordResetRequest(target string) (bool, error) {
+	return RedisClient.SetNX(ctx, "auth:password_reset:cooldown:"+target, 1, passwordResetCooldown).Result()
+}
+
+// IssuePasswordResetToken 为用户签发一次性的重置令牌，之前签发的令牌随之失效
+func IssuePasswordResetToken(userID int, passwordHash string) (string, error) {
+	nonce, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	expiresAt := time.Now().Add(PasswordResetExpiration)
+
+	if err := RedisClient.Set(ctx, passwordResetKey(userID), nonce, PasswordResetExpiration).Err(); err != nil {
+		return "", fmt.Errorf("保存重置令牌失败: %w", err)
+	}
+	return issueSignedToken(passwordResetTokenPurpose(passwordHash), expiresAt, strconv.Itoa(userID), nonce), nil
+}
+
+// ParsePasswordResetToken 解析令牌中的用户ID并检查是否过期，签名须再用 ConsumePasswordResetToken 校验
+func ParsePasswordResetToken(token string) (int, error) {
+	fields, ok := peekSignedToken(token, 2)
+	if !ok {
+		return 0, ErrResetTokenInvalid
+	}
+	userID, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, ErrResetTokenInvalid
+	}
+	return userID, nil
+}
+
+// ConsumePasswordResetToken 校验令牌签名并使其失效，令牌只能使用一次
+func ConsumePasswordResetToken(token, passwordHash string) error {
+	fields, ok := parseSignedToken(passwordResetTokenPurpose(passwordHash), token, 2)
+	if !ok {
+		return ErrResetTokenInvalid
+	}
+	userID, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return ErrResetTokenInvalid
+	}
+	nonce := fields[1]
+
+	script := `
+		if redis.call("get", KEYS[1]) == ARGV[1] then
+			return redis.call("del", KEYS[1])
+		else
+			return 0
+		end
+	`
+	result, err := RedisClient.Eval(ctx, script, []string{passwordResetKey(userID)}, nonce).Int64()
+	if err != nil {
+		return fmt.Errorf("校验重置令牌失败: %w", err)
+	}
+	if result == 0 {
+		return ErrResetTokenInvalid
+	}
+	return nil
+}
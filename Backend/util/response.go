@@ -14,6 +14,7 @@ const (
 	StatusCodeUnauthorized      = 401
 	StatusCodeForbidden         = 403
 	StatusCodeNotFound          = 404
+	StatusCodeTooManyRequests   = 429
 	StatusCodeInternalError     = 500
 	StatusCodeUserExist         = 1001
 	StatusCodeUserPasswordError = 1002
@@ -23,17 +24,48 @@ const (
 	StatusCodeUserDataExportError = 1006
 	StatusCodeUserDataDeleteError = 1007
 	StatusCodePrivacySettingsError = 1008
+	StatusCodeAttendeeNotExist  = 1009
+	StatusCodeAttendeeInvalid   = 1010
+	StatusCodeAttendeeExist     = 1011
+	StatusCodeVerifyCodeInvalid = 1012
+	StatusCodeVerifyCodeTooFrequent = 1013
+	StatusCodeResetTokenInvalid = 1014
+	StatusCodeContactNotVerified = 1015
+	StatusCodeAccountLocked     = 1016
+	StatusCodeOIDCLoginFailed   = 1017
+	StatusCodeIdentityConflict  = 1018
 	StatusCodePerformanceNotExist    = 2001
 	StatusCodePerformanceNotOnSale   = 2002
 	StatusCodeTicketNotExist    = 3001
 	StatusCodeTicketStockInsufficient = 3002
 	StatusCodeTicketSeckillFailed     = 3003
 	StatusCodeTicketLimitExceeded     = 3004
+	StatusCodeAttendeeTicketed        = 3005
+	StatusCodeTicketCheckInFailed     = 3006
+	StatusCodeBallotOnly              = 3007
+	StatusCodeBallotClosed            = 3008
+	StatusCodeBallotEntryExist        = 3009
+	StatusCodeBallotEntryNotExist     = 3010
+	StatusCodeNotBallot               = 3011
+	StatusCodeWaitlistNotSoldOut      = 3012
+	StatusCodeWaitlistEntryExist      = 3013
+	StatusCodeWaitlistEntryNotExist   = 3014
+	StatusCodeWaitlistNotWaiting      = 3015
+	StatusCodeAccessCodeInvalid       = 3016
 	StatusCodeOrderNotExist     = 4001
 	StatusCodeOrderExpired      = 4002
 	StatusCodeOrderStatusError  = 4003
 	StatusCodeOrderDuplicate    = 4004
 	StatusCodePaymentError      = 4005
+	StatusCodeCartEmpty         = 4006
+	StatusCodeCartItemNotExist  = 4007
+	StatusCodeOrderMixPerformances = 4008
+	StatusCodeCouponInvalid        = 4009
+	StatusCodeCouponNotApplicable  = 4010
+	StatusCodeCouponLimitExceeded  = 4011
+	StatusCodeInvoiceExist         = 4012
+	StatusCodeInvoiceUnavailable   = 4013
+	StatusCodeInvoiceNotExist      = 4014
 )
 
 // StatusMessage 状态码对应的消息
@@ -43,6 +75,7 @@ var StatusMessage = map[int]string{
 	StatusCodeUnauthorized:      "未认证(未登录或token过期)",
 	StatusCodeForbidden:         "权限不足",
 	StatusCodeNotFound:          "资源不存在",
+	StatusCodeTooManyRequests:   "请求过于频繁，请稍后再试",
 	StatusCodeInternalError:     "服务器内部错误",
 	StatusCodeUserExist:         "用户已存在",
 	StatusCodeUserPasswordError: "用户名或密码错误",
@@ -52,17 +85,48 @@ var StatusMessage = map[int]string{
 	StatusCodeUserDataExportError: "数据导出失败",
 	StatusCodeUserDataDeleteError: "数据删除失败",
 	StatusCodePrivacySettingsError: "隐私设置错误",
+	StatusCodeAttendeeNotExist:  "观演人不存在",
+	StatusCodeAttendeeInvalid:   "观演人信息错误",
+	StatusCodeAttendeeExist:     "该证件号已添加",
+	StatusCodeVerifyCodeInvalid: "验证码错误或已过期",
+	StatusCodeVerifyCodeTooFrequent: "验证码发送过于频繁，请稍后再试",
+	StatusCodeResetTokenInvalid: "重置链接无效或已过期",
+	StatusCodeContactNotVerified: "请先验证手机号或邮箱",
+	StatusCodeAccountLocked:     "登录失败次数过多，账号已临时锁定",
+	StatusCodeOIDCLoginFailed:   "第三方登录失败",
+	StatusCodeIdentityConflict:  "第三方账号绑定冲突",
 	StatusCodePerformanceNotExist:    "演出不存在",
 	StatusCodePerformanceNotOnSale:   "演出未开售",
 	StatusCodeTicketNotExist:    "票种不存在",
 	StatusCodeTicketStockInsufficient: "库存不足",
 	StatusCodeTicketSeckillFailed:     "抢票失败，请重试",
 	StatusCodeTicketLimitExceeded:     "每人限购5张票",
+	StatusCodeAttendeeTicketed:        "观演人已购买该场演出的门票",
+	StatusCodeTicketCheckInFailed:     "检票失败",
+	StatusCodeBallotOnly:              "该票种仅支持抽签购买",
+	StatusCodeBallotClosed:            "不在抽签登记时间内",
+	StatusCodeBallotEntryExist:        "已登记该票种的抽签",
+	StatusCodeBallotEntryNotExist:     "抽签登记不存在",
+	StatusCodeNotBallot:               "该票种不支持抽签",
+	StatusCodeWaitlistNotSoldOut:      "票种尚有库存，请直接购买",
+	StatusCodeWaitlistEntryExist:      "已在该票种的候补队列中",
+	StatusCodeWaitlistEntryNotExist:   "候补登记不存在",
+	StatusCodeWaitlistNotWaiting:      "候补已获得订单，请在订单中取消",
+	StatusCodeAccessCodeInvalid:       "兑换码无效或已用完",
 	StatusCodeOrderNotExist:     "订单不存在",
 	StatusCodeOrderExpired:      "订单已过期",
 	StatusCodeOrderStatusError:  "订单状态错误",
 	StatusCodeOrderDuplicate:    "不能重复创建订单",
 	StatusCodePaymentError:      "支付失败",
+	StatusCodeCartEmpty:         "购物车为空",
+	StatusCodeCartItemNotExist:  "购物车条目不存在",
+	StatusCodeOrderMixPerformances: "一个订单只能包含同一场演出的票种",
+	StatusCodeCouponInvalid:        "优惠码无效",
+	StatusCodeCouponNotApplicable:  "优惠券不适用于当前订单",
+	StatusCodeCouponLimitExceeded:  "优惠券使用次数已达上限",
+	StatusCodeInvoiceExist:         "该订单已开具发票",
+	StatusCodeInvoiceUnavailable:   "只有已支付的订单可以开具发票",
+	StatusCodeInvoiceNotExist:      "发票不存在",
 }
 
 // SuccessResponse 创建成功响应
@@ -0,0 +1,223 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	ChannelSMS   = "sms"
+	ChannelEmail = "email"
+)
+
+var (
+	ErrPhoneInvalid        = errors.New("手机号格式不正确")
+	ErrSenderNotConfigured = errors.New("消息发送方式未配置")
+)
+
+var phonePattern = regexp.MustCompile(`^1[3-9][0-9]{9}$`)
+
+// Message 一条待发送的短信或邮件
+type Message struct {
+	Channel string    `json:"channel"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject,omitempty"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Sender 短信、邮件发送器，具体实现由配置中的 driver 决定
+type Sender interface {
+	Send(msg *Message) error
+}
+
+var (
+	senderMu    sync.Mutex
+	mailSender  Sender
+	smsSender   Sender
+	outboxLocks = map[string]*sync.Mutex{}
+)
+
+// MailSender 返回配置的邮件发送器
+func MailSender() Sender {
+	senderMu.Lock()
+	defer senderMu.Unlock()
+	if mailSender == nil {
+		cfg := GetConfig().Mail
+		switch cfg.Driver {
+		case "smtp":
+			mailSender = &SMTPSender{Config: cfg}
+		case "file":
+			mailSender = &FileSender{Path: cfg.OutboxFile}
+		case "log":
+			mailSender = newLogSender(ChannelEmail)
+		default:
+			mailSender = &DisabledSender{}
+		}
+	}
+	return mailSender
+}
+
+// SMSSender 返回配置的短信发送器
+func SMSSender() Sender {
+	senderMu.Lock()
+	defer senderMu.Unlock()
+	if smsSender == nil {
+		cfg := GetConfig().SMS
+		switch cfg.Driver {
+		case "webhook":
+			smsSender = &WebhookSMSSender{URL: cfg.WebhookURL, Token: cfg.WebhookToken}
+		case "file":
+			smsSender = &FileSender{Path: cfg.OutboxFile}
+		case "log":
+			smsSender = newLogSender(ChannelSMS)
+		default:
+			smsSender = &DisabledSender{}
+		}
+	}
+	return smsSender
+}
+
+// SetMailSender 替换邮件发送器，用于测试或接入其他服务商
+func SetMailSender(sender Sender) {
+	senderMu.Lock()
+	defer senderMu.Unlock()
+	mailSender = sender
+}
+
+// SetSMSSender 替换短信发送器，用于测试或接入其他服务商
+func SetSMSSender(sender Sender) {
+	senderMu.Lock()
+	defer senderMu.Unlock()
+	smsSender = sender
+}
+
+// ValidatePhone 校验中国大陆手机号
+func ValidatePhone(phone string) bool {
+	return phonePattern.MatchString(phone)
+}
+
+// SendSMS 通过配置的短信发送器发送短信
+func SendSMS(phone, content string) error {
+	if !ValidatePhone(phone) {
+		return ErrPhoneInvalid
+	}
+	return SMSSender().Send(&Message{Channel: ChannelSMS, To: phone, Body: content})
+}
+
+// SendSMSAsync 在后台发送短信，失败只记录日志，不影响主流程
+func SendSMSAsync(phone, content string) {
+	go func() {
+		if err := SendSMS(phone, content); err != nil {
+			logSendFailure(ChannelSMS, phone, err)
+		}
+	}()
+}
+
+func logSendFailure(channel, to string, err error) {
+	log.Printf("消息发送失败: channel=%s to=%s err=%v", channel, to, err)
+}
+
+// newLogSender 日志中会含有验证码和重置链接，只允许在 debug 模式下使用，其他模式下拒绝发送
+func newLogSender(channel string) Sender {
+	if GetConfig().Server.Mode != "debug" {
+		log.Printf("%s 发送方式 log 只能在 debug 模式下使用，消息将不会发送", channel)
+		return &DisabledSender{}
+	}
+	return &LogSender{}
+}
+
+// LogSender 只把消息写入日志，仅用于 debug 模式下的开发调试
+type LogSender struct{}
+
+func (s *LogSender) Send(msg *Message) error {
+	log.Printf("[%s] to=%s subject=%s\n%s", msg.Channel, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// DisabledSender 未配置发送方式时使用，发送一律失败
+type DisabledSender struct{}
+
+func (s *DisabledSender) Send(msg *Message) error {
+	return ErrSenderNotConfigured
+}
+
+// FileSender 把消息按 JSON 行追加到本地文件，供开发调试和自动化测试读取
+type FileSender struct {
+	Path string
+}
+
+func (s *FileSender) Send(msg *Message) error {
+	msg.SentAt = time.Now()
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	senderMu.Lock()
+	lock, ok := outboxLocks[s.Path]
+	if !ok {
+		lock = &sync.Mutex{}
+		outboxLocks[s.Path] = lock
+	}
+	senderMu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// WebhookSMSSender 把短信转发给短信网关，网关负责对接具体的运营商或云服务
+type WebhookSMSSender struct {
+	URL   string
+	Token string
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+func (s *WebhookSMSSender) Send(msg *Message) error {
+	if s.URL == "" {
+		return errors.New("短信网关地址未配置")
+	}
+	payload, err := json.Marshal(map[string]string{"phone": msg.To, "content": msg.Body})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求短信网关失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("短信网关返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期")
+	ErrRefreshTokenReused  = errors.New("刷新令牌已被使用，会话已注销")
+)
+
+// sessionTouchInterval 最近活跃时间的更新间隔，避免每个请求都写 Redis
+const sessionTouchInterval = 60 * time.Second
+
+// knownDeviceExpiration 登录过的设备在此期间内再次登录不视为新设备
+const knownDeviceExpiration = 180 * 24 * time.Hour
+
+// SessionInfo 创建会话时记录的设备信息
+type SessionInfo struct {
+	IP         string
+	UserAgent  string
+	DeviceName string
+}
+
+// Session 用户的登录会话（登录设备）
+type Session struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// 登录会话保存在 Redis 中：访问令牌携带会话ID，会话被删除后访问令牌立即失效；
+// 刷新令牌格式为 会话ID.随机串，每次刷新都会轮换，旧令牌再次出现视为泄露并注销整个会话。
+func sessionKey(sessionID string) string {
+	return "auth:session:" + sessionID
+}
+
+func userSessionsKey(userID int) string {
+	return fmt.Sprintf("auth:user_sessions:%d", userID)
+}
+
+func userDevicesKey(userID int) string {
+	return fmt.Sprintf("auth:user_devices:%d", userID)
+}
+
+func refreshExpiration() time.Duration {
+	return time.Duration(GetConfig().JWT.RefreshExpire) * time.Second
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CreateSession 为用户创建登录会话，返回会话ID和刷新令牌
+func CreateSession(userID int, info SessionInfo) (string, string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	sessionID := hex.EncodeToString(buf)
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	expiration := refreshExpiration()
+	pipe := RedisClient.TxPipeline()
+	now := time.Now().Unix()
+	pipe.HSet(ctx, sessionKey(sessionID), map[string]interface{}{
+		"user_id":      userID,
+		"refresh_hash": HashSensitive(secret),
+		"device_name":  info.DeviceName,
+		"ip":           info.IP,
+		"user_agent":   info.UserAgent,
+		"created_at":   now,
+		"last_seen_at": now,
+	})
+	pipe.Expire(ctx, sessionKey(sessionID), expiration)
+	pipe.SAdd(ctx, userSessionsKey(userID), sessionID)
+	pipe.Expire(ctx, userSessionsKey(userID), expiration)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", "", fmt.Errorf("创建登录会话失败: %w", err)
+	}
+	return sessionID, sessionID + "." + secret, nil
+}
+
+// RotateSession 校验刷新令牌并换发新的刷新令牌，返回用户ID、会话ID和新令牌
+func RotateSession(refreshToken, ip string) (int, string, string, error) {
+	parts := strings.SplitN(refreshToken, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return 0, "", "", ErrRefreshTokenInvalid
+	}
+	sessionID := parts[0]
+	secret, err := randomToken(32)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	// 比较并替换在脚本中完成，同一刷新令牌并发使用时只有一个请求能成功
+	script := `
+		local uid = redis.call("hget", KEYS[1], "user_id")
+		if not uid then
+			return -1
+		end
+		if redis.call("hget", KEYS[1], "refresh_hash") ~= ARGV[1] then
+			redis.call("del", KEYS[1])
+			return 0
+		end
+		redis.call("hset", KEYS[1], "refresh_hash", ARGV[2], "last_seen_at", ARGV[4], "ip", ARGV[5])
+		redis.call("expire", KEYS[1], ARGV[3])
+		return tonumber(uid)
+	`
+	result, err := RedisClient.Eval(ctx, script, []string{sessionKey(sessionID)},
+		HashSensitive(parts[1]), HashSensitive(secret), int64(refreshExpiration().Seconds()), time.Now().Unix(), ip).Int64()
+	if err != nil {
+		return 0, "", "", fmt.Errorf("刷新登录会话失败: %w", err)
+	}
+	switch {
+	case result < 0:
+		return 0, "", "", ErrRefreshTokenInvalid
+	case result == 0:
+		return 0, "", "", ErrRefreshTokenReused
+	}
+
+	userID := int(result)
+	RedisClient.Expire(ctx, userSessionsKey(userID), refreshExpiration())
+	return userID, sessionID, sessionID + "." + secret, nil
+}
+
+// SessionActive 检查会话是否仍然有效且属于该用户，并按间隔刷新最近活跃时间和IP
+func SessionActive(sessionID string, userID int, ip string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+	values, err := RedisClient.HMGet(ctx, sessionKey(sessionID), "user_id", "last_seen_at").Result()
+	if err != nil {
+		return false, err
+	}
+	if values[0] == nil {
+		return false, nil
+	}
+	uid, _ := strconv.Atoi(fmt.Sprint(values[0]))
+	if uid != userID {
+		return false, nil
+	}
+
+	lastSeen, _ := strconv.ParseInt(fmt.Sprint(values[1]), 10, 64)
+	if now := time.Now(); now.Sub(time.Unix(lastSeen, 0)) >= sessionTouchInterval {
+		RedisClient.HSet(ctx, sessionKey(sessionID), "last_seen_at", now.Unix(), "ip", ip)
+	}
+	return true, nil
+}
+
+// ListUserSessions 列出用户的全部有效会话，按最近活跃时间倒序
+func ListUserSessions(userID int) ([]*Session, error) {
+	sessionIDs, err := RedisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := RedisClient.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(sessionIDs))
+	for i, sessionID := range sessionIDs {
+		cmds[i] = pipe.HGetAll(ctx, sessionKey(sessionID))
+	}
+	if len(sessionIDs) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, err
+		}
+	}
+
+	sessions := make([]*Session, 0, len(sessionIDs))
+	var expired []interface{}
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if fields["user_id"] != strconv.Itoa(userID) {
+			// 已过期或已注销的会话顺便从索引中移除
+			expired = append(expired, sessionIDs[i])
+			continue
+		}
+		createdAt, _ := strconv.ParseInt(fields["created_at"], 10, 64)
+		lastSeenAt, _ := strconv.ParseInt(fields["last_seen_at"], 10, 64)
+		sessions = append(sessions, &Session{
+			ID:         sessionIDs[i],
+			DeviceName: fields["device_name"],
+			IP:         fields["ip"],
+			UserAgent:  fields["user_agent"],
+			CreatedAt:  time.Unix(createdAt, 0),
+			LastSeenAt: time.Unix(lastSeenAt, 0),
+		})
+	}
+	if len(expired) > 0 {
+		RedisClient.SRem(ctx, userSessionsKey(userID), expired...)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// DeleteUserSession 注销用户的指定会话，会话不存在或不属于该用户时返回 false
+func DeleteUserSession(userID int, sessionID string) (bool, error) {
+	uid, err := RedisClient.HGet(ctx, sessionKey(sessionID), "user_id").Int()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+		return false, err
+	}
+	if uid != userID {
+		return false, nil
+	}
+
+	pipe := RedisClient.TxPipeline()
+	pipe.Del(ctx, sessionKey(sessionID))
+	pipe.SRem(ctx, userSessionsKey(userID), sessionID)
+	_, err = pipe.Exec(ctx)
+	return err == nil, err
+}
+
+// DeleteOtherSessions 注销用户除当前会话以外的全部会话
+func DeleteOtherSessions(userID int, currentSessionID string) error {
+	sessionIDs, err := RedisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	var members []interface{}
+	for _, sessionID := range sessionIDs {
+		if sessionID == currentSessionID {
+			continue
+		}
+		keys = append(keys, sessionKey(sessionID))
+		members = append(members, sessionID)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	pipe := RedisClient.TxPipeline()
+	pipe.Del(ctx, keys...)
+	pipe.SRem(ctx, userSessionsKey(userID), members...)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// RememberDevice 记录用户登录过的设备，返回是否为新设备以及是否为该用户的首个设备
+func RememberDevice(userID int, userAgent string) (bool, bool, error) {
+	pipe := RedisClient.TxPipeline()
+	count := pipe.SCard(ctx, userDevicesKey(userID))
+	added := pipe.SAdd(ctx, userDevicesKey(userID), HashSensitive(userAgent))
+	pipe.Expire(ctx, userDevicesKey(userID), knownDeviceExpiration)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, false, err
+	}
+	return added.Val() > 0, count.Val() == 0, nil
+}
+
+// RevokeRefreshToken 注销刷新令牌所属的会话，令牌不匹配时不做任何修改
+func RevokeRefreshToken(refreshToken string) error {
+	parts := strings.SplitN(refreshToken, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ErrRefreshTokenInvalid
+	}
+
+	script := `
+		if redis.call("hget", KEYS[1], "refresh_hash") == ARGV[1] then
+			return redis.call("del", KEYS[1])
+		else
+			return 0
+		end
+	`
+	result, err := RedisClient.Eval(ctx, script, []string{sessionKey(parts[0])}, HashSensitive(parts[1])).Int64()
+	if err != nil {
+		return fmt.Errorf("注销登录会话失败: %w", err)
+	}
+	if result == 0 {
+		return ErrRefreshTokenInvalid
+	}
+	return nil
+}
+
+// DeleteSession 注销单个会话
+func DeleteSession(sessionID string) error {
+	return RedisClient.Del(ctx, sessionKey(sessionID)).Err()
+}
+
+// DeleteUserSessions 注销用户的全部会话，用于修改密码、禁用或删除账号
+func DeleteUserSessions(userID int) error {
+	sessionIDs, err := RedisClient.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{userSessionsKey(userID)}
+	for _, sessionID := range sessionIDs {
+		keys = append(keys, sessionKey(sessionID))
+	}
+	return RedisClient.Del(ctx, keys...).Err()
+}
+
+// DeviceNameFromUserAgent 根据 User-Agent 生成便于识别的设备名称，如 "Chrome（Windows）"
+func DeviceNameFromUserAgent(userAgent string) string {
+	var system string
+	switch {
+	case strings.Contains(userAgent, "iPhone"):
+		system = "iPhone"
+	case strings.Contains(userAgent, "iPad"):
+		system = "iPad"
+	case strings.Contains(userAgent, "Android"):
+		system = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		system = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"), strings.Contains(userAgent, "Macintosh"):
+		system = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		system = "Linux"
+	}
+
+	var browser string
+	switch {
+	case strings.Contains(userAgent, "MicroMessenger"):
+		browser = "微信"
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"), strings.Contains(userAgent, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + "（" + system + "）"
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "未知设备"
+	}
+}
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 签名令牌格式：base64url(字段1.字段2....过期时间).签名，签名为 HMAC-SHA256(purpose:载荷)。
+// purpose 区分令牌用途，一种用途签发的令牌不能用于另一种；字段中不能包含点号。
+func signedTokenSignature(purpose, payload string) string {
+	mac := hmac.New(sha256.New, dataKey())
+	mac.Write([]byte(purpose + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issueSignedToken 签发在 expiresAt 之前有效的令牌
+func issueSignedToken(purpose string, expiresAt time.Time, fields ...string) string {
+	payload := strings.Join(append(fields, strconv.FormatInt(expiresAt.Unix(), 10)), ".")
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + signedTokenSignature(purpose, payload)
+}
+
+// parseSignedToken 校验签名和有效期，返回签发时的 n 个字段
+func parseSignedToken(purpose, token string, n int) ([]string, bool) {
+	fields, payload, signature, ok := decodeSignedToken(token, n)
+	if !ok || !hmac.Equal([]byte(signature), []byte(signedTokenSignature(purpose, payload))) {
+		return nil, false
+	}
+	return fields, true
+}
+
+// peekSignedToken 只检查格式和有效期，不校验签名。用于签名用途取决于令牌内容的情况，
+// 调用方须在取得 purpose 后再用 parseSignedToken 校验
+func peekSignedToken(token string, n int) ([]string, bool) {
+	fields, _, _, ok := decodeSignedToken(token, n)
+	return fields, ok
+}
+
+func decodeSignedToken(token string, n int) ([]string, string, string, bool) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return nil, "", "", false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, "", "", false
+	}
+	payload := string(raw)
+	fields := strings.Split(payload, ".")
+	if len(fields) != n+1 {
+		return nil, "", "", false
+	}
+	expiresAt, err := strconv.ParseInt(fields[n], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, "", "", false
+	}
+	return fields[:n], payload, parts[1], true
+}
@@ -0,0 +1,149 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TOTP 参数（RFC 6238）：HMAC-SHA1，30 秒步长，6 位数字，与主流验证器 App 的默认值一致
+const (
+	TOTPPeriod = 30
+	TOTPDigits = 6
+	totpSkew   = 1 // 允许前后各一个步长的时钟误差
+
+	twoFactorMaxFailures = 5
+	twoFactorFailureTTL  = 15 * time.Minute
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret 生成 160 位随机密钥，以无填充的 Base32 编码返回
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPProvisioningURI 生成验证器 App 扫码使用的 otpauth:// 地址
+func TOTPProvisioningURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(TOTPPeriod))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPCode 计算指定时间步的动态码
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod), nil
+}
+
+// ValidateTOTP 校验动态码，返回匹配的时间步，调用方应再用 MarkTOTPStepUsed 防止同一动态码被重放
+func ValidateTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+	current := now.Unix() / TOTPPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// MarkTOTPStepUsed 记录管理员已使用的时间步，同一时间步的动态码只能使用一次
+func MarkTOTPStepUsed(adminID int, step int64) (bool, error) {
+	key := fmt.Sprintf("admin:totp_used:%d:%d", adminID, step)
+	return RedisClient.SetNX(ctx, key, 1, time.Duration(TOTPPeriod*(2*totpSkew+2))*time.Second).Result()
+}
+
+func twoFactorFailureKey(adminID int) string {
+	return fmt.Sprintf("admin:2fa_failures:%d", adminID)
+}
+
+// TwoFactorLocked 连续输错动态码过多时暂停验证，防止暴力猜测
+func TwoFactorLocked(adminID int) (bool, error) {
+	count, err := RedisClient.Get(ctx, twoFactorFailureKey(adminID)).Int()
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+	return count >= twoFactorMaxFailures, nil
+}
+
+// RecordTwoFactorFailure 记录一次动态码校验失败
+func RecordTwoFactorFailure(adminID int) error {
+	key := twoFactorFailureKey(adminID)
+	pipe := RedisClient.TxPipeline()
+	pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, twoFactorFailureTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ClearTwoFactorFailures 验证通过后清除失败计数
+func ClearTwoFactorFailures(adminID int) error {
+	return RedisClient.Del(ctx, twoFactorFailureKey(adminID)).Err()
+}
+
+// GenerateRecoveryCode 生成形如 XXXXX-XXXXX 的恢复码，排除易混淆的字符
+func GenerateRecoveryCode() (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := make([]byte, 0, 11)
+	for i, b := range buf {
+		if i == 5 {
+			code = append(code, '-')
+		}
+		code = append(code, alphabet[int(b)%len(alphabet)])
+	}
+	return string(code), nil
+}
+
+// NormalizeRecoveryCode 统一恢复码格式，忽略大小写、空格和连字符
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
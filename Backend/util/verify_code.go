@@ -0,0 +1,125 @@
+package util
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var (
+	ErrVerifyCodeInvalid     = errors.New("验证码错误或已过期")
+	ErrVerifyCodeTooFrequent = errors.New("验证码发送过于频繁，请稍后再试")
+	ErrVerifyCodeLocked      = errors.New("验证码错误次数过多，请重新获取")
+)
+
+const (
+	VerifyCodeExpiration  = 5 * time.Minute
+	verifyCodeCooldown    = 60 * time.Second
+	verifyCodeMaxAttempts = 5
+	verifyCodeDailyLimit  = 10 // 同一手机号/邮箱 24 小时内最多发送次数
+	verifyCodeIPLimit     = 30 // 同一 IP 每小时最多发送次数
+)
+
+func verifyCodeKey(purpose, target string) string {
+	return "verify:code:" + purpose + ":" + target
+}
+
+func verifyCodeHash(purpose, target, code string) string {
+	return HashSensitive(purpose + ":" + target + ":" + code)
+}
+
+// GenerateVerifyCode 生成 6 位数字验证码
+func GenerateVerifyCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// IssueVerifyCode 生成并保存验证码，新验证码会使旧验证码失效；受重发冷却、
+// 每个目标每日上限和每个 IP 每小时上限限制
+func IssueVerifyCode(purpose, target, ip string) (string, error) {
+	code, err := GenerateVerifyCode()
+	if err != nil {
+		return "", err
+	}
+
+	script := `
+		if redis.call("exists", KEYS[2]) == 1 then
+			return 0
+		end
+		if tonumber(redis.call("get", KEYS[3]) or "0") >= tonumber(ARGV[4]) then
+			return 0
+		end
+		if tonumber(redis.call("get", KEYS[4]) or "0") >= tonumber(ARGV[5]) then
+			return 0
+		end
+		redis.call("del", KEYS[1])
+		redis.call("hset", KEYS[1], "code_hash", ARGV[1], "attempts", 0)
+		redis.call("expire", KEYS[1], ARGV[2])
+		redis.call("set", KEYS[2], 1, "EX", ARGV[3])
+		if redis.call("incr", KEYS[3]) == 1 then
+			redis.call("expire", KEYS[3], 86400)
+		end
+		if redis.call("incr", KEYS[4]) == 1 then
+			redis.call("expire", KEYS[4], 3600)
+		end
+		return 1
+	`
+	keys := []string{
+		verifyCodeKey(purpose, target),
+		"verify:cooldown:" + purpose + ":" + target,
+		"verify:daily:" + target,
+		"verify:ip:" + ip,
+	}
+	result, err := RedisClient.Eval(ctx, script, keys,
+		verifyCodeHash(purpose, target, code),
+		int64(VerifyCodeExpiration.Seconds()),
+		int64(verifyCodeCooldown.Seconds()),
+		verifyCodeDailyLimit,
+		verifyCodeIPLimit,
+	).Int64()
+	if err != nil {
+		return "", fmt.Errorf("保存验证码失败: %w", err)
+	}
+	if result == 0 {
+		return "", ErrVerifyCodeTooFrequent
+	}
+	return code, nil
+}
+
+// CheckVerifyCode 校验验证码，成功后验证码立即失效；错误次数达到上限后验证码作废
+func CheckVerifyCode(purpose, target, code string) error {
+	script := `
+		local hash = redis.call("hget", KEYS[1], "code_hash")
+		if not hash then
+			return -1
+		end
+		local attempts = redis.call("hincrby", KEYS[1], "attempts", 1)
+		if hash == ARGV[1] then
+			redis.call("del", KEYS[1])
+			return 1
+		end
+		if attempts >= tonumber(ARGV[2]) then
+			redis.call("del", KEYS[1])
+			return -2
+		end
+		return 0
+	`
+	result, err := RedisClient.Eval(ctx, script, []string{verifyCodeKey(purpose, target)},
+		verifyCodeHash(purpose, target, code), verifyCodeMaxAttempts).Int64()
+	if err != nil {
+		return fmt.Errorf("校验验证码失败: %w", err)
+	}
+	switch result {
+	case 1:
+		return nil
+	case -2:
+		return ErrVerifyCodeLocked
+	default:
+		return ErrVerifyCodeInvalid
+	}
+}
@@ -0,0 +1,56 @@
+-- 隐藏票种与兑换码
+-- 执行顺序：Createdb.sql 之后执行
+
+SET NAMES utf8mb4;
+
+SET @col_exists = (SELECT COUNT(*) FROM information_schema.COLUMNS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'ticket_type' AND COLUMN_NAME = 'hidden');
+SET @sql = IF(@col_exists = 0,
+  'ALTER TABLE ticket_type ADD COLUMN hidden TINYINT NOT NULL DEFAULT 0 COMMENT ''1:隐藏票种，凭兑换码解锁''',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+CREATE TABLE IF NOT EXISTS `access_code` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `ticket_type_id` INT NOT NULL,
+  `code` VARCHAR(32) NOT NULL,
+  `batch_no` VARCHAR(32) NOT NULL COMMENT '生成批次号',
+  `max_uses` INT NOT NULL DEFAULT 1 COMMENT '可下单次数，1 为一次性兑换码',
+  `used_count` INT NOT NULL DEFAULT 0,
+  `status` TINYINT NOT NULL DEFAULT 1 COMMENT '0:已停用, 1:有效',
+  `expire_time` DATETIME DEFAULT NULL,
+  `created_by` INT NOT NULL COMMENT '生成的管理员ID',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uk_code (`code`),
+  INDEX idx_ticket_type_batch (`ticket_type_id`, `batch_no`),
+  CONSTRAINT fk_access_code_ticket_type FOREIGN KEY (`ticket_type_id`) REFERENCES `ticket_type` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `access_code_unlock` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `access_code_id` INT NOT NULL,
+  `user_id` INT NOT NULL,
+  `ticket_type_id` INT NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uk_code_user (`access_code_id`, `user_id`),
+  INDEX idx_user_ticket_type (`user_id`, `ticket_type_id`),
+  CONSTRAINT fk_unlock_access_code FOREIGN KEY (`access_code_id`) REFERENCES `access_code` (`id`),
+  CONSTRAINT fk_unlock_user FOREIGN KEY (`user_id`) REFERENCES `user` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `order_access_code` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `order_id` INT NOT NULL,
+  `access_code_id` INT NOT NULL,
+  `user_id` INT NOT NULL,
+  `ticket_type_id` INT NOT NULL,
+  `quantity` INT NOT NULL,
+  `released` TINYINT NOT NULL DEFAULT 0 COMMENT '1:订单关闭后已归还次数',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_order_id (`order_id`),
+  INDEX idx_access_code_id (`access_code_id`),
+  CONSTRAINT fk_order_access_code_order FOREIGN KEY (`order_id`) REFERENCES `order` (`id`),
+  CONSTRAINT fk_order_access_code_code FOREIGN KEY (`access_code_id`) REFERENCES `access_code` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
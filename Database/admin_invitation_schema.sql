@@ -0,0 +1,24 @@
+-- 管理员邀请
+-- 执行顺序：admin_rbac_schema.sql 之后执行，可重复执行。
+-- 邀请链接中的令牌经过签名并带有过期时间，nonce 须与记录一致；接受或撤销后链接失效。
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `admin_invitation` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `email` VARCHAR(100) NOT NULL,
+  `real_name` VARCHAR(50),
+  `role` VARCHAR(20) NOT NULL COMMENT '接受邀请后管理员的角色',
+  `nonce` VARCHAR(64) NOT NULL,
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT '0:待接受 1:已接受 2:已撤销',
+  `invited_by` INT NOT NULL,
+  `admin_id` INT NOT NULL DEFAULT 0 COMMENT '接受邀请后创建的管理员',
+  `expires_at` DATETIME NOT NULL,
+  `accepted_at` DATETIME NULL,
+  `revoked_by` INT NOT NULL DEFAULT 0,
+  `revoked_at` DATETIME NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX idx_email (`email`),
+  INDEX idx_status (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,26 @@
+-- 管理员角色与权限
+-- 执行顺序：admin_schema.sql 之后执行，可重复执行，已存在的角色不会被覆盖。
+-- 超级管理员 (super_admin) 固定拥有全部权限，其余角色的权限以逗号分隔保存在 permissions 字段。
+-- admin.security、admin.invite、role.manage 只属于超级管理员，不能分配给其他角色。
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `admin_role` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `name` VARCHAR(20) NOT NULL UNIQUE COMMENT '对应 admin.role',
+  `display_name` VARCHAR(50) NOT NULL,
+  `description` VARCHAR(255),
+  `permissions` TEXT,
+  `built_in` TINYINT NOT NULL DEFAULT 0 COMMENT '1:内置角色，不可删除',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT IGNORE INTO `admin_role` (`name`, `display_name`, `description`, `permissions`, `built_in`) VALUES
+('super_admin', '超级管理员', '拥有全部权限，可管理角色和管理员安全设置', '', 1),
+('admin', '管理员', '除角色管理和管理员安全设置以外的全部权限',
+ 'admin.read,admin.write,category.write,config.read,config.write,coupon.read,coupon.write,dashboard.read,fee_policy.read,fee_policy.write,invoice.read,log.read,order.export,order.read,order.refund,performance.read,performance.write,ticket.check_in,ticket_type.read,ticket_type.write,user.delete,user.read,user.write', 1),
+('content_admin', '内容管理员', '管理演出、分类和票种',
+ 'category.write,dashboard.read,performance.read,performance.write,ticket_type.read,ticket_type.write', 1),
+('ticket_admin', '票务管理员', '处理订单、退款、验票、发票和优惠券',
+ 'coupon.read,coupon.write,dashboard.read,invoice.read,order.export,order.read,order.refund,performance.read,ticket.check_in,ticket_type.read,user.read', 1);
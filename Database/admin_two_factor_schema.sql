@@ -0,0 +1,41 @@
+-- 管理员两步验证（TOTP）
+-- 执行顺序：admin_schema.sql 之后执行，可重复执行。已有管理员默认未启用两步验证。
+
+SET NAMES utf8mb4;
+
+SET @col_exists = (SELECT COUNT(*) FROM information_schema.COLUMNS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'admin' AND COLUMN_NAME = 'totp_secret');
+SET @sql = IF(@col_exists = 0,
+  'ALTER TABLE `admin`
+     ADD COLUMN totp_secret VARCHAR(255) NOT NULL DEFAULT '''' COMMENT ''验证器密钥（加密存储）'' AFTER last_login_ip,
+     ADD COLUMN totp_enabled TINYINT NOT NULL DEFAULT 0 COMMENT ''1:已启用两步验证'' AFTER totp_secret,
+     ADD COLUMN totp_enabled_at DATETIME NULL AFTER totp_enabled',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- 登录历史区分密码登录和两步验证事件
+SET @col_exists = (SELECT COUNT(*) FROM information_schema.COLUMNS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'admin_login_history' AND COLUMN_NAME = 'event');
+SET @sql = IF(@col_exists = 0,
+  'ALTER TABLE `admin_login_history`
+     ADD COLUMN event VARCHAR(30) NOT NULL DEFAULT ''password'' COMMENT ''password/2fa_verify/2fa_recovery_code/2fa_enable/2fa_disable/2fa_reset/2fa_recovery_renew'' AFTER status',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- 恢复码只保存哈希，每个只能使用一次
+CREATE TABLE IF NOT EXISTS `admin_recovery_code` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `admin_id` INT NOT NULL,
+  `code_hash` VARCHAR(64) NOT NULL,
+  `used_at` DATETIME NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_admin_code (`admin_id`, `code_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 超级管理员可开启：所有管理员必须启用两步验证才能登录
+INSERT IGNORE INTO system_config (`key`, `value`, `description`) VALUES
+('admin_require_2fa', 'false', '管理员必须启用两步验证');
@@ -0,0 +1,51 @@
+-- 抽签购票
+-- 执行顺序：Createdb.sql 之后执行
+
+SET NAMES utf8mb4;
+
+-- 票种销售方式与抽签登记时间
+SET @col_exists = (SELECT COUNT(*) FROM information_schema.COLUMNS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'ticket_type' AND COLUMN_NAME = 'sale_mode');
+SET @sql = IF(@col_exists = 0,
+  'ALTER TABLE ticket_type
+     ADD COLUMN sale_mode TINYINT NOT NULL DEFAULT 0 COMMENT ''0:先到先得, 1:抽签'',
+     ADD COLUMN ballot_start_time DATETIME DEFAULT NULL COMMENT ''抽签登记开始时间'',
+     ADD COLUMN ballot_end_time DATETIME DEFAULT NULL COMMENT ''抽签登记截止时间''',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+CREATE TABLE IF NOT EXISTS `ballot_entry` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `ticket_type_id` INT NOT NULL,
+  `user_id` INT NOT NULL,
+  `quantity` INT NOT NULL,
+  `attendee_ids` VARCHAR(255) DEFAULT NULL COMMENT '逗号分隔的观演人ID',
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT '0:待开奖, 1:已中签, 2:未中签, 3:逾期未支付',
+  `draw_round` INT NOT NULL DEFAULT 0 COMMENT '中签轮次',
+  `order_id` INT NOT NULL DEFAULT 0,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uk_ticket_type_user (`ticket_type_id`, `user_id`),
+  INDEX idx_user_id (`user_id`),
+  CONSTRAINT fk_ballot_entry_ticket_type FOREIGN KEY (`ticket_type_id`) REFERENCES `ticket_type` (`id`),
+  CONSTRAINT fk_ballot_entry_user FOREIGN KEY (`user_id`) REFERENCES `user` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `ballot_draw` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `ticket_type_id` INT NOT NULL,
+  `round` INT NOT NULL,
+  `seed` VARCHAR(64) NOT NULL COMMENT '抽签种子，用于复现结果',
+  `stock` INT NOT NULL COMMENT '抽签时的可分配库存',
+  `entry_count` INT NOT NULL,
+  `winner_count` INT NOT NULL,
+  `winner_quantity` INT NOT NULL,
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT '0:已抽签, 1:已公布',
+  `payment_deadline` DATETIME DEFAULT NULL,
+  `published_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uk_ticket_type_round (`ticket_type_id`, `round`),
+  CONSTRAINT fk_ballot_draw_ticket_type FOREIGN KEY (`ticket_type_id`) REFERENCES `ticket_type` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
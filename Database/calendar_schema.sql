@@ -0,0 +1,15 @@
+-- 用户私有日历订阅
+-- 只保存订阅令牌的 HMAC 哈希，重新生成令牌即吊销旧链接
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `calendar_feed` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `user_id` INT NOT NULL,
+  `token_hash` VARCHAR(64) NOT NULL,
+  `last_used_at` DATETIME DEFAULT NULL COMMENT '日历应用最近一次拉取时间',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uk_user_id (`user_id`),
+  UNIQUE KEY uk_token_hash (`token_hash`),
+  CONSTRAINT fk_calendar_feed_user FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
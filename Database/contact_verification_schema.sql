@@ -0,0 +1,25 @@
+-- 手机号、邮箱验证状态
+-- 执行顺序：Createdb.sql 之后执行，可重复执行。已有用户的联系方式均为未验证。
+
+SET NAMES utf8mb4;
+
+SET @col_exists = (SELECT COUNT(*) FROM information_schema.COLUMNS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'user' AND COLUMN_NAME = 'email_verified');
+SET @sql = IF(@col_exists = 0,
+  'ALTER TABLE `user`
+     ADD COLUMN email_verified TINYINT NOT NULL DEFAULT 0 COMMENT ''1:邮箱已验证'' AFTER status,
+     ADD COLUMN phone_verified TINYINT NOT NULL DEFAULT 0 COMMENT ''1:手机号已验证'' AFTER email_verified',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- 验证码登录、找回密码按手机号和邮箱查找用户
+SET @idx_exists = (SELECT COUNT(*) FROM information_schema.STATISTICS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'user' AND INDEX_NAME = 'idx_phone');
+SET @sql = IF(@idx_exists = 0,
+  'ALTER TABLE `user` ADD INDEX idx_phone (`phone`), ADD INDEX idx_email (`email`)',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
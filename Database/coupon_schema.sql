@@ -0,0 +1,139 @@
+-- 优惠券与优惠码
+-- 执行顺序：ticket_schema.sql、access_code_schema.sql 之后执行
+
+SET NAMES utf8mb4;
+
+SET @col_exists = (SELECT COUNT(*) FROM information_schema.COLUMNS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'order' AND COLUMN_NAME = 'discount_amount');
+SET @sql = IF(@col_exists = 0,
+  'ALTER TABLE `order` ADD COLUMN discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT ''优惠金额，amount 为优惠后的应付金额'' AFTER amount',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+CREATE TABLE IF NOT EXISTS `coupon` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `name` VARCHAR(100) NOT NULL,
+  `type` TINYINT NOT NULL COMMENT '1:固定金额, 2:按比例折扣',
+  `value` DECIMAL(10,2) NOT NULL COMMENT '优惠金额或折扣百分比',
+  `max_discount` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT '折扣券优惠上限，0 为不限',
+  `min_spend` DECIMAL(10,2) NOT NULL DEFAULT 0 COMMENT '适用票款的最低消费',
+  `start_time` DATETIME NOT NULL,
+  `end_time` DATETIME NOT NULL,
+  `total_limit` INT NOT NULL DEFAULT 0 COMMENT '总使用次数，0 为不限',
+  `per_user_limit` INT NOT NULL DEFAULT 0 COMMENT '每人使用次数，0 为不限',
+  `used_count` INT NOT NULL DEFAULT 0,
+  `scope_type` TINYINT NOT NULL DEFAULT 0 COMMENT '0:全场, 1:分类, 2:演出, 3:票种',
+  `scope_ids` VARCHAR(500) DEFAULT NULL COMMENT '逗号分隔的适用ID',
+  `status` TINYINT NOT NULL DEFAULT 1 COMMENT '0:停用, 1:启用',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `coupon_code` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `coupon_id` INT NOT NULL,
+  `code` VARCHAR(32) NOT NULL,
+  `max_uses` INT NOT NULL DEFAULT 0 COMMENT '可用次数，0 为不限',
+  `used_count` INT NOT NULL DEFAULT 0,
+  `status` TINYINT NOT NULL DEFAULT 1 COMMENT '0:停用, 1:有效',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uk_code (`code`),
+  INDEX idx_coupon_id (`coupon_id`),
+  CONSTRAINT fk_coupon_code_coupon FOREIGN KEY (`coupon_id`) REFERENCES `coupon` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `coupon_usage` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `coupon_id` INT NOT NULL,
+  `coupon_code_id` INT NOT NULL,
+  `user_id` INT NOT NULL,
+  `order_id` INT NOT NULL,
+  `discount` DECIMAL(10,2) NOT NULL,
+  `status` TINYINT NOT NULL DEFAULT 1 COMMENT '1:已使用, 0:订单关闭后已释放',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX idx_order_id (`order_id`),
+  INDEX idx_coupon_user (`coupon_id`, `user_id`, `status`),
+  CONSTRAINT fk_coupon_usage_coupon FOREIGN KEY (`coupon_id`) REFERENCES `coupon` (`id`),
+  CONSTRAINT fk_coupon_usage_order FOREIGN KEY (`order_id`) REFERENCES `order` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `order_discount` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `order_id` INT NOT NULL,
+  `coupon_id` INT NOT NULL,
+  `coupon_code_id` INT NOT NULL,
+  `code` VARCHAR(32) DEFAULT NULL,
+  `description` VARCHAR(100) DEFAULT NULL,
+  `amount` DECIMAL(10,2) NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_order_id (`order_id`),
+  CONSTRAINT fk_order_discount_order FOREIGN KEY (`order_id`) REFERENCES `order` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 关闭过期订单时同时释放兑换码和优惠券的使用次数
+
+DELIMITER //
+
+DROP PROCEDURE IF EXISTS p_close_expired_orders//
+CREATE PROCEDURE p_close_expired_orders()
+BEGIN
+  START TRANSACTION;
+  DROP TEMPORARY TABLE IF EXISTS tmp_expired_order;
+  CREATE TEMPORARY TABLE tmp_expired_order (id INT PRIMARY KEY)
+    SELECT id FROM `order` WHERE status = 0 AND expire_time < NOW() FOR UPDATE;
+
+  UPDATE ticket_type tt
+  JOIN (
+    SELECT oi.ticket_type_id, SUM(oi.quantity) AS qty
+    FROM order_item oi JOIN tmp_expired_order t ON oi.order_id = t.id
+    GROUP BY oi.ticket_type_id
+  ) s ON tt.id = s.ticket_type_id
+  SET tt.stock = tt.stock + s.qty;
+
+  UPDATE ticket tk JOIN tmp_expired_order t ON tk.order_id = t.id
+  SET tk.status = 3, tk.claim = NULL, tk.updated_at = NOW();
+
+  UPDATE access_code ac
+  JOIN (
+    SELECT oac.access_code_id, COUNT(*) AS cnt
+    FROM order_access_code oac JOIN tmp_expired_order t ON oac.order_id = t.id
+    WHERE oac.released = 0
+    GROUP BY oac.access_code_id
+  ) s ON ac.id = s.access_code_id
+  SET ac.used_count = GREATEST(ac.used_count - s.cnt, 0);
+
+  UPDATE order_access_code oac JOIN tmp_expired_order t ON oac.order_id = t.id
+  SET oac.released = 1;
+
+  UPDATE coupon c
+  JOIN (
+    SELECT cu.coupon_id, COUNT(*) AS cnt
+    FROM coupon_usage cu JOIN tmp_expired_order t ON cu.order_id = t.id
+    WHERE cu.status = 1
+    GROUP BY cu.coupon_id
+  ) s ON c.id = s.coupon_id
+  SET c.used_count = GREATEST(c.used_count - s.cnt, 0);
+
+  UPDATE coupon_code cc
+  JOIN (
+    SELECT cu.coupon_code_id, COUNT(*) AS cnt
+    FROM coupon_usage cu JOIN tmp_expired_order t ON cu.order_id = t.id
+    WHERE cu.status = 1
+    GROUP BY cu.coupon_code_id
+  ) s ON cc.id = s.coupon_code_id
+  SET cc.used_count = GREATEST(cc.used_count - s.cnt, 0);
+
+  UPDATE coupon_usage cu JOIN tmp_expired_order t ON cu.order_id = t.id
+  SET cu.status = 0, cu.updated_at = NOW()
+  WHERE cu.status = 1;
+
+  UPDATE `order` o JOIN tmp_expired_order t ON o.id = t.id SET o.status = 2, o.updated_at = NOW();
+
+  DROP TEMPORARY TABLE tmp_expired_order;
+  COMMIT;
+END//
+
+DELIMITER ;
@@ -0,0 +1,44 @@
+-- 服务费、配送费与税费策略及订单金额明细
+-- 执行顺序：money_schema.sql 之后执行，金额以分为单位，费率以万分比为单位
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `fee_policy` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `name` VARCHAR(100) NOT NULL,
+  `scope_type` TINYINT NOT NULL COMMENT '0:全局, 1:分类, 2:演出',
+  `scope_id` INT NOT NULL DEFAULT 0 COMMENT '分类或演出ID，全局为 0',
+  `service_fee` BIGINT NOT NULL DEFAULT 0 COMMENT '每张票服务费，单位分',
+  `service_fee_rate` BIGINT NOT NULL DEFAULT 0 COMMENT '按票款收取的服务费比例，万分比',
+  `delivery_fee` BIGINT NOT NULL DEFAULT 0 COMMENT '每单配送费，单位分',
+  `tax_rate` BIGINT NOT NULL DEFAULT 0 COMMENT '税率，万分比',
+  `tax_inclusive` TINYINT NOT NULL DEFAULT 0 COMMENT '1:价格已含税',
+  `status` TINYINT NOT NULL DEFAULT 1 COMMENT '0:停用, 1:启用',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uk_scope (`scope_type`, `scope_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `order_charge` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `order_id` INT NOT NULL,
+  `type` VARCHAR(20) NOT NULL COMMENT 'ticket/service_fee/delivery_fee/discount/tax',
+  `description` VARCHAR(100) DEFAULT NULL,
+  `amount` BIGINT NOT NULL COMMENT '单位分，优惠为负数',
+  `included` TINYINT NOT NULL DEFAULT 0 COMMENT '1:已含在其他金额中，不计入合计（如含税价的税额）',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_order_id (`order_id`),
+  CONSTRAINT fk_order_charge_order FOREIGN KEY (`order_id`) REFERENCES `order` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 为已有订单补充票款与优惠明细
+INSERT INTO order_charge (order_id, type, description, amount, included, created_at)
+SELECT o.id, 'ticket', '票款', o.amount + o.discount_amount, 0, o.created_at
+FROM `order` o
+WHERE NOT EXISTS (SELECT 1 FROM order_charge c WHERE c.order_id = o.id);
+
+INSERT INTO order_charge (order_id, type, description, amount, included, created_at)
+SELECT o.id, 'discount', '优惠', -o.discount_amount, 0, o.created_at
+FROM `order` o
+WHERE o.discount_amount > 0
+  AND NOT EXISTS (SELECT 1 FROM order_charge c WHERE c.order_id = o.id AND c.type = 'discount');
@@ -0,0 +1,95 @@
+-- 订单电子发票
+-- 执行顺序：money_schema.sql、coupon_schema.sql 之后执行，金额以分为单位
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `invoice` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `invoice_no` VARCHAR(32) NOT NULL COMMENT '前缀 + 年份 + 8 位序号',
+  `order_id` INT NOT NULL,
+  `user_id` INT NOT NULL,
+  `title` VARCHAR(100) NOT NULL COMMENT '发票抬头',
+  `tax_id` VARCHAR(32) DEFAULT NULL COMMENT '购买方纳税人识别号，个人抬头为空',
+  `email` VARCHAR(100) NOT NULL COMMENT '接收电子发票的邮箱',
+  `amount` BIGINT NOT NULL COMMENT '价税合计，单位分',
+  `tax_amount` BIGINT NOT NULL DEFAULT 0 COMMENT '其中税额，单位分',
+  `currency` CHAR(3) NOT NULL,
+  `status` TINYINT NOT NULL DEFAULT 1 COMMENT '1:已开具, 2:已作废',
+  `issued_at` DATETIME NOT NULL,
+  `voided_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uk_invoice_no (`invoice_no`),
+  UNIQUE KEY uk_order_id (`order_id`),
+  INDEX idx_user_id (`user_id`),
+  INDEX idx_issued_at (`issued_at`),
+  CONSTRAINT fk_invoice_order FOREIGN KEY (`order_id`) REFERENCES `order` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 关闭过期订单时同时作废已开具的发票，其余步骤与 coupon_schema.sql 相同
+
+DELIMITER //
+
+DROP PROCEDURE IF EXISTS p_close_expired_orders//
+CREATE PROCEDURE p_close_expired_orders()
+BEGIN
+  START TRANSACTION;
+  DROP TEMPORARY TABLE IF EXISTS tmp_expired_order;
+  CREATE TEMPORARY TABLE tmp_expired_order (id INT PRIMARY KEY)
+    SELECT id FROM `order` WHERE status = 0 AND expire_time < NOW() FOR UPDATE;
+
+  UPDATE ticket_type tt
+  JOIN (
+    SELECT oi.ticket_type_id, SUM(oi.quantity) AS qty
+    FROM order_item oi JOIN tmp_expired_order t ON oi.order_id = t.id
+    GROUP BY oi.ticket_type_id
+  ) s ON tt.id = s.ticket_type_id
+  SET tt.stock = tt.stock + s.qty;
+
+  UPDATE ticket tk JOIN tmp_expired_order t ON tk.order_id = t.id
+  SET tk.status = 3, tk.claim = NULL, tk.updated_at = NOW();
+
+  UPDATE access_code ac
+  JOIN (
+    SELECT oac.access_code_id, COUNT(*) AS cnt
+    FROM order_access_code oac JOIN tmp_expired_order t ON oac.order_id = t.id
+    WHERE oac.released = 0
+    GROUP BY oac.access_code_id
+  ) s ON ac.id = s.access_code_id
+  SET ac.used_count = GREATEST(ac.used_count - s.cnt, 0);
+
+  UPDATE order_access_code oac JOIN tmp_expired_order t ON oac.order_id = t.id
+  SET oac.released = 1;
+
+  UPDATE coupon c
+  JOIN (
+    SELECT cu.coupon_id, COUNT(*) AS cnt
+    FROM coupon_usage cu JOIN tmp_expired_order t ON cu.order_id = t.id
+    WHERE cu.status = 1
+    GROUP BY cu.coupon_id
+  ) s ON c.id = s.coupon_id
+  SET c.used_count = GREATEST(c.used_count - s.cnt, 0);
+
+  UPDATE coupon_code cc
+  JOIN (
+    SELECT cu.coupon_code_id, COUNT(*) AS cnt
+    FROM coupon_usage cu JOIN tmp_expired_order t ON cu.order_id = t.id
+    WHERE cu.status = 1
+    GROUP BY cu.coupon_code_id
+  ) s ON cc.id = s.coupon_code_id
+  SET cc.used_count = GREATEST(cc.used_count - s.cnt, 0);
+
+  UPDATE coupon_usage cu JOIN tmp_expired_order t ON cu.order_id = t.id
+  SET cu.status = 0, cu.updated_at = NOW()
+  WHERE cu.status = 1;
+
+  UPDATE invoice i JOIN tmp_expired_order t ON i.order_id = t.id
+  SET i.status = 2, i.voided_at = NOW()
+  WHERE i.status = 1;
+
+  UPDATE `order` o JOIN tmp_expired_order t ON o.id = t.id SET o.status = 2, o.updated_at = NOW();
+
+  DROP TEMPORARY TABLE tmp_expired_order;
+  COMMIT;
+END//
+
+DELIMITER ;
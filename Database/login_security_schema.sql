@@ -0,0 +1,18 @@
+-- 用户登录历史（与 admin_login_history 结构一致）
+-- 执行顺序：Createdb.sql 之后执行，可重复执行。登录失败计数和锁定状态保存在 Redis 中。
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_login_history` (
+  `id` BIGINT PRIMARY KEY AUTO_INCREMENT,
+  `user_id` INT NOT NULL COMMENT '用户名不存在时为 0',
+  `ip` VARCHAR(50),
+  `user_agent` VARCHAR(255),
+  `status` TINYINT NOT NULL DEFAULT 1 COMMENT '1:成功 0:失败',
+  `event` VARCHAR(30) NOT NULL DEFAULT 'password' COMMENT 'password/code/locked',
+  `fail_reason` VARCHAR(100),
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_user_id (`user_id`),
+  INDEX idx_ip (`ip`),
+  INDEX idx_created_at (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,60 @@
+-- 金额改为以分为单位的整数存储
+-- 执行顺序：在其他建表脚本之后执行，可重复执行；已是 BIGINT 的列会跳过。
+-- 迁移后 admin_schema.sql 中的统计视图同样以分为单位，测试数据需在迁移前导入。
+
+SET NAMES utf8mb4;
+
+DELIMITER //
+
+DROP PROCEDURE IF EXISTS p_migrate_money_column//
+CREATE PROCEDURE p_migrate_money_column(IN p_table VARCHAR(64), IN p_column VARCHAR(64))
+BEGIN
+  DECLARE v_type VARCHAR(64);
+
+  SELECT DATA_TYPE INTO v_type FROM information_schema.COLUMNS
+    WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = p_table AND COLUMN_NAME = p_column;
+
+  IF v_type IN ('decimal', 'float', 'double') THEN
+    -- 先扩大精度，避免乘以 100 后溢出 DECIMAL(10,2)；再按四舍五入换算为分
+    SET @sql = CONCAT('ALTER TABLE `', p_table, '` MODIFY `', p_column, '` DECIMAL(20,2) NOT NULL DEFAULT 0');
+    PREPARE stmt FROM @sql;
+    EXECUTE stmt;
+    DEALLOCATE PREPARE stmt;
+
+    SET @sql = CONCAT('UPDATE `', p_table, '` SET `', p_column, '` = ROUND(`', p_column, '` * 100)');
+    PREPARE stmt FROM @sql;
+    EXECUTE stmt;
+    DEALLOCATE PREPARE stmt;
+
+    SET @sql = CONCAT('ALTER TABLE `', p_table, '` MODIFY `', p_column, '` BIGINT NOT NULL DEFAULT 0 COMMENT ''单位：分''');
+    PREPARE stmt FROM @sql;
+    EXECUTE stmt;
+    DEALLOCATE PREPARE stmt;
+  END IF;
+END//
+
+DELIMITER ;
+
+CALL p_migrate_money_column('ticket_type', 'price');
+CALL p_migrate_money_column('order', 'amount');
+CALL p_migrate_money_column('order', 'discount_amount');
+CALL p_migrate_money_column('order_item', 'unit_price');
+CALL p_migrate_money_column('order_item', 'amount');
+CALL p_migrate_money_column('price_rule', 'price');
+-- 折扣券的 value 为百分比，换算后即为万分比
+CALL p_migrate_money_column('coupon', 'value');
+CALL p_migrate_money_column('coupon', 'max_discount');
+CALL p_migrate_money_column('coupon', 'min_spend');
+CALL p_migrate_money_column('coupon_usage', 'discount');
+CALL p_migrate_money_column('order_discount', 'amount');
+
+DROP PROCEDURE IF EXISTS p_migrate_money_column;
+
+SET @col_exists = (SELECT COUNT(*) FROM information_schema.COLUMNS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'order' AND COLUMN_NAME = 'currency');
+SET @sql = IF(@col_exists = 0,
+  'ALTER TABLE `order` ADD COLUMN currency CHAR(3) NOT NULL DEFAULT ''CNY'' COMMENT ''结算币种'' AFTER discount_amount',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
@@ -0,0 +1,65 @@
+-- 购物车与多票种订单
+-- 执行顺序：Createdb.sql、admin_schema.sql 之后执行
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `cart_item` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `user_id` INT NOT NULL,
+  `ticket_type_id` INT NOT NULL,
+  `quantity` INT NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uk_user_ticket_type (`user_id`, `ticket_type_id`),
+  CONSTRAINT fk_cart_user FOREIGN KEY (`user_id`) REFERENCES `user` (`id`),
+  CONSTRAINT fk_cart_ticket_type FOREIGN KEY (`ticket_type_id`) REFERENCES `ticket_type` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `order_item` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `order_id` INT NOT NULL,
+  `performance_id` INT NOT NULL,
+  `ticket_type_id` INT NOT NULL,
+  `quantity` INT NOT NULL,
+  `unit_price` DECIMAL(10,2) NOT NULL,
+  `amount` DECIMAL(10,2) NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_order_id (`order_id`),
+  INDEX idx_ticket_type_id (`ticket_type_id`),
+  CONSTRAINT fk_order_item_order FOREIGN KEY (`order_id`) REFERENCES `order` (`id`),
+  CONSTRAINT fk_order_item_ticket_type FOREIGN KEY (`ticket_type_id`) REFERENCES `ticket_type` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 为已有的单票种订单补齐明细
+INSERT INTO order_item (order_id, performance_id, ticket_type_id, quantity, unit_price, amount, created_at)
+SELECT o.id, o.performance_id, o.ticket_type_id, o.quantity, ROUND(o.amount / o.quantity, 2), o.amount, o.created_at
+FROM `order` o
+WHERE NOT EXISTS (SELECT 1 FROM order_item oi WHERE oi.order_id = o.id);
+
+-- 关闭过期订单时按明细归还库存
+
+DELIMITER //
+
+DROP PROCEDURE IF EXISTS p_close_expired_orders//
+CREATE PROCEDURE p_close_expired_orders()
+BEGIN
+  START TRANSACTION;
+  DROP TEMPORARY TABLE IF EXISTS tmp_expired_order;
+  CREATE TEMPORARY TABLE tmp_expired_order (id INT PRIMARY KEY)
+    SELECT id FROM `order` WHERE status = 0 AND expire_time < NOW() FOR UPDATE;
+
+  UPDATE ticket_type tt
+  JOIN (
+    SELECT oi.ticket_type_id, SUM(oi.quantity) AS qty
+    FROM order_item oi JOIN tmp_expired_order t ON oi.order_id = t.id
+    GROUP BY oi.ticket_type_id
+  ) s ON tt.id = s.ticket_type_id
+  SET tt.stock = tt.stock + s.qty;
+
+  UPDATE `order` o JOIN tmp_expired_order t ON o.id = t.id SET o.status = 2, o.updated_at = NOW();
+
+  DROP TEMPORARY TABLE tmp_expired_order;
+  COMMIT;
+END//
+
+DELIMITER ;
@@ -0,0 +1,30 @@
+-- 票种动态定价规则
+-- 执行顺序：order_item_schema.sql 之后执行
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `price_rule` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `ticket_type_id` INT NOT NULL,
+  `name` VARCHAR(100) NOT NULL COMMENT '如 早鸟价、第二档、临近开演价',
+  `price` DECIMAL(10,2) NOT NULL,
+  `start_time` DATETIME DEFAULT NULL COMMENT '生效开始时间，为空表示不限',
+  `end_time` DATETIME DEFAULT NULL COMMENT '生效结束时间，为空表示不限',
+  `sold_threshold` INT NOT NULL DEFAULT 0 COMMENT '已售达到该数量后生效',
+  `priority` INT NOT NULL DEFAULT 0 COMMENT '多条规则同时生效时取优先级最高的',
+  `status` TINYINT NOT NULL DEFAULT 1 COMMENT '0:停用, 1:启用',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX idx_ticket_type_id (`ticket_type_id`),
+  CONSTRAINT fk_price_rule_ticket_type FOREIGN KEY (`ticket_type_id`) REFERENCES `ticket_type` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 订单明细记录下单时生效的价格规则，unit_price 即为当时的成交价
+SET @col_exists = (SELECT COUNT(*) FROM information_schema.COLUMNS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'order_item' AND COLUMN_NAME = 'price_rule_id');
+SET @sql = IF(@col_exists = 0,
+  'ALTER TABLE order_item ADD COLUMN price_rule_id INT NOT NULL DEFAULT 0 COMMENT ''0 为票面价'' AFTER amount, ADD COLUMN price_rule_name VARCHAR(100) DEFAULT NULL AFTER price_rule_id',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
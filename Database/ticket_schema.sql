@@ -0,0 +1,82 @@
+-- 实名观演人与电子票
+-- 执行顺序：order_item_schema.sql 之后执行
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `attendee` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `user_id` INT NOT NULL,
+  `name` VARCHAR(50) NOT NULL,
+  `id_number_enc` VARCHAR(255) NOT NULL COMMENT '证件号密文',
+  `id_number_hash` CHAR(64) NOT NULL COMMENT '证件号 HMAC，用于查重',
+  `id_number_masked` VARCHAR(30) NOT NULL COMMENT '脱敏证件号',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uk_user_id_number (`user_id`, `id_number_hash`),
+  CONSTRAINT fk_attendee_user FOREIGN KEY (`user_id`) REFERENCES `user` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `ticket` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `ticket_no` VARCHAR(50) NOT NULL,
+  `order_id` INT NOT NULL,
+  `user_id` INT NOT NULL,
+  `performance_id` INT NOT NULL,
+  `ticket_type_id` INT NOT NULL,
+  `attendee_id` INT NOT NULL DEFAULT 0,
+  `attendee_name` VARCHAR(50) DEFAULT NULL,
+  `id_number_hash` CHAR(64) DEFAULT NULL,
+  `id_number_masked` VARCHAR(30) DEFAULT NULL,
+  `claim` TINYINT DEFAULT NULL COMMENT '1:占用实名名额, NULL:未占用或已释放',
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT '0:待支付, 1:有效, 2:已检票, 3:已作废',
+  `checked_in_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uk_ticket_no (`ticket_no`),
+  -- 同一证件号在同一场演出只能持有一张有效票，claim 为 NULL 时不参与唯一约束
+  UNIQUE KEY uk_performance_id_number (`performance_id`, `id_number_hash`, `claim`),
+  INDEX idx_order_id (`order_id`),
+  CONSTRAINT fk_ticket_order FOREIGN KEY (`order_id`) REFERENCES `order` (`id`),
+  CONSTRAINT fk_ticket_ticket_type FOREIGN KEY (`ticket_type_id`) REFERENCES `ticket_type` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 演出实名制开关
+SET @col_exists = (SELECT COUNT(*) FROM information_schema.COLUMNS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'performance' AND COLUMN_NAME = 'require_real_name');
+SET @sql = IF(@col_exists = 0,
+  'ALTER TABLE performance ADD COLUMN require_real_name TINYINT NOT NULL DEFAULT 0 COMMENT ''1:实名购票''',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- 关闭过期订单时同时作废电子票并释放实名名额
+
+DELIMITER //
+
+DROP PROCEDURE IF EXISTS p_close_expired_orders//
+CREATE PROCEDURE p_close_expired_orders()
+BEGIN
+  START TRANSACTION;
+  DROP TEMPORARY TABLE IF EXISTS tmp_expired_order;
+  CREATE TEMPORARY TABLE tmp_expired_order (id INT PRIMARY KEY)
+    SELECT id FROM `order` WHERE status = 0 AND expire_time < NOW() FOR UPDATE;
+
+  UPDATE ticket_type tt
+  JOIN (
+    SELECT oi.ticket_type_id, SUM(oi.quantity) AS qty
+    FROM order_item oi JOIN tmp_expired_order t ON oi.order_id = t.id
+    GROUP BY oi.ticket_type_id
+  ) s ON tt.id = s.ticket_type_id
+  SET tt.stock = tt.stock + s.qty;
+
+  UPDATE ticket tk JOIN tmp_expired_order t ON tk.order_id = t.id
+  SET tk.status = 3, tk.claim = NULL, tk.updated_at = NOW();
+
+  UPDATE `order` o JOIN tmp_expired_order t ON o.id = t.id SET o.status = 2, o.updated_at = NOW();
+
+  DROP TEMPORARY TABLE tmp_expired_order;
+  COMMIT;
+END//
+
+DELIMITER ;
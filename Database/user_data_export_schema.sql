@@ -0,0 +1,20 @@
+-- 用户个人数据导出
+-- 可重复执行。导出文件由后台任务生成到 export.dir 目录，下载有效期过后删除文件并将状态置为已过期。
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_data_export` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `user_id` INT NOT NULL,
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT '0:排队中 1:生成中 2:可下载 3:失败 4:已过期',
+  `file_path` VARCHAR(255),
+  `file_size` BIGINT NOT NULL DEFAULT 0,
+  `error` VARCHAR(255),
+  `started_at` DATETIME NULL,
+  `completed_at` DATETIME NULL,
+  `expires_at` DATETIME NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX idx_user_id (`user_id`),
+  INDEX idx_status (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
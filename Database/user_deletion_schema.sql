@@ -0,0 +1,46 @@
+-- 用户账号注销
+-- 可重复执行。申请注销后进入冷静期 (account.deletion_grace_days)，期满后后台任务匿名化账号：
+-- user.status 置为 3 (已注销)，清除用户名、密码、联系方式和头像，订单、电子票和发票以匿名形式保留。
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_deletion_request` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `user_id` INT NOT NULL,
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT '0:冷静期中 1:已撤销 2:已注销',
+  `pending` TINYINT DEFAULT NULL COMMENT '1:冷静期中, NULL:已撤销或已注销',
+  `scheduled_at` DATETIME NOT NULL COMMENT '冷静期结束时间',
+  `cancelled_at` DATETIME NULL,
+  `completed_at` DATETIME NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  -- 每个用户同时只能有一条冷静期中的申请，pending 为 NULL 时不参与唯一约束
+  UNIQUE KEY uk_user_pending (`user_id`, `pending`),
+  INDEX idx_status_scheduled (`status`, `scheduled_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 早先创建的表补充 pending 列和唯一约束，重复的冷静期申请只保留最早的一条
+SET @col_exists = (SELECT COUNT(*) FROM information_schema.COLUMNS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'user_deletion_request' AND COLUMN_NAME = 'pending');
+SET @sql = IF(@col_exists = 0,
+  'ALTER TABLE user_deletion_request ADD COLUMN pending TINYINT DEFAULT NULL COMMENT ''1:冷静期中, NULL:已撤销或已注销'' AFTER status',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+UPDATE user_deletion_request r
+JOIN (SELECT user_id, MIN(id) AS id FROM user_deletion_request WHERE status = 0 GROUP BY user_id) k ON r.user_id = k.user_id
+SET r.status = 1, r.cancelled_at = NOW()
+WHERE r.status = 0 AND r.id <> k.id;
+
+UPDATE user_deletion_request SET pending = IF(status = 0, 1, NULL);
+
+SET @idx_exists = (SELECT COUNT(*) FROM information_schema.STATISTICS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'user_deletion_request' AND INDEX_NAME = 'uk_user_pending');
+SET @sql = IF(@idx_exists = 0,
+  'ALTER TABLE user_deletion_request ADD UNIQUE KEY uk_user_pending (`user_id`, `pending`), DROP INDEX idx_user_id',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
@@ -0,0 +1,17 @@
+-- 第三方登录 (OpenID Connect) 绑定关系
+-- 执行顺序：Createdb.sql 之后执行，可重复执行。授权过程中的 state、nonce 和 PKCE 校验码保存在 Redis 中。
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_identity` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `user_id` INT NOT NULL,
+  `provider` VARCHAR(50) NOT NULL COMMENT '配置中的服务商名称',
+  `subject` VARCHAR(255) NOT NULL COMMENT 'ID Token 中的 sub',
+  `email` VARCHAR(100),
+  `name` VARCHAR(100),
+  `last_login_at` DATETIME NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY idx_provider_subject (`provider`, `subject`),
+  INDEX idx_user_id (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
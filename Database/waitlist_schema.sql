@@ -0,0 +1,52 @@
+-- 售罄票种候补
+-- 执行顺序：Createdb.sql 之后执行，可重复执行
+
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `waitlist_entry` (
+  `id` INT PRIMARY KEY AUTO_INCREMENT,
+  `ticket_type_id` INT NOT NULL,
+  `user_id` INT NOT NULL,
+  `quantity` INT NOT NULL,
+  `attendee_ids` VARCHAR(255) DEFAULT NULL COMMENT '逗号分隔的观演人ID',
+  `status` TINYINT NOT NULL DEFAULT 0 COMMENT '0:排队中, 1:待支付, 2:已支付, 3:已失效, 4:已退出',
+  `active` TINYINT DEFAULT NULL COMMENT '1:排队中或待支付, NULL:已结束',
+  `order_id` INT NOT NULL DEFAULT 0 COMMENT '候补订单ID',
+  `offer_expire_time` DATETIME DEFAULT NULL COMMENT '候补订单支付截止时间',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX idx_ticket_type_status (`ticket_type_id`, `status`, `id`),
+  -- 每个用户在同一票种只能有一条未结束的候补，active 为 NULL 时不参与唯一约束
+  UNIQUE KEY uk_ticket_type_user_active (`ticket_type_id`, `user_id`, `active`),
+  INDEX idx_user_id (`user_id`),
+  INDEX idx_order_id (`order_id`),
+  CONSTRAINT fk_waitlist_ticket_type FOREIGN KEY (`ticket_type_id`) REFERENCES `ticket_type` (`id`),
+  CONSTRAINT fk_waitlist_user FOREIGN KEY (`user_id`) REFERENCES `user` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 早先创建的表补充 active 列和唯一约束，重复排队的候补只保留最早的一条
+SET @col_exists = (SELECT COUNT(*) FROM information_schema.COLUMNS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'waitlist_entry' AND COLUMN_NAME = 'active');
+SET @sql = IF(@col_exists = 0,
+  'ALTER TABLE waitlist_entry ADD COLUMN active TINYINT DEFAULT NULL COMMENT ''1:排队中或待支付, NULL:已结束'' AFTER status',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+UPDATE waitlist_entry w
+JOIN waitlist_entry e ON e.ticket_type_id = w.ticket_type_id AND e.user_id = w.user_id
+  AND e.status IN (0, 1) AND e.id < w.id
+SET w.status = 4
+WHERE w.status = 0;
+
+UPDATE waitlist_entry SET active = IF(status IN (0, 1), 1, NULL);
+
+SET @idx_exists = (SELECT COUNT(*) FROM information_schema.STATISTICS
+  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'waitlist_entry' AND INDEX_NAME = 'uk_ticket_type_user_active');
+SET @sql = IF(@idx_exists = 0,
+  'ALTER TABLE waitlist_entry ADD UNIQUE KEY uk_ticket_type_user_active (`ticket_type_id`, `user_id`, `active`)',
+  'SELECT 1');
+PREPARE stmt FROM @sql;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;